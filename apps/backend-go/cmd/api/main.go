@@ -8,11 +8,18 @@ import (
 	"syscall"
 	"time"
 
+	"backend-go/internal/cache"
 	"backend-go/internal/config"
 	"backend-go/internal/database"
 	"backend-go/internal/handlers"
 	"backend-go/internal/middleware"
 	"backend-go/internal/services/ai"
+	"backend-go/internal/services/catalog"
+	"backend-go/internal/services/chat"
+	"backend-go/internal/services/editor"
+	"backend-go/internal/services/ratelimit"
+	"backend-go/internal/services/render"
+	"backend-go/internal/services/revision"
 	"backend-go/internal/services/token"
 	"backend-go/internal/services/website"
 	"backend-go/internal/utils"
@@ -55,29 +62,82 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to run migrations")
 	}
 
+	// Initialize Redis (optional - features fall back to in-memory state)
+	redisCache, err := cache.New(cfg)
+	if err != nil {
+		logrus.WithError(err).Warn("Redis unavailable, using in-memory state")
+	}
+
 	// Initialize utilities
 	jwtUtil := utils.NewJWTUtil(&cfg.JWT)
 
 	// Initialize services
 	tokenMgr := token.NewManager(db)
 	kimiClient := ai.NewKimiClient(&cfg.Kimi)
-	websiteGen := website.NewGenerator(db, kimiClient, tokenMgr)
+	revisions := revision.NewService(db, &cfg.Revision)
+	templates, err := catalog.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load template catalog")
+	}
+	websiteGen := website.NewGenerator(db, kimiClient, tokenMgr, revisions, templates)
+	renderer, err := render.New()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load page templates")
+	}
+
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if redisCache != nil {
+		rateLimitStore = ratelimit.NewRedisStore(redisCache.Client)
+	}
+	limiter := ratelimit.NewManager(rateLimitStore, &cfg.RateLimit, ratelimit.NewDBTierResolver(db, time.Minute))
 
 	// Initialize WebSocket manager
-	wsManager := websocket.NewManager()
+	websocket.SetAllowedOrigins(cfg.Server.AllowOrigins)
+	wsManager := websocket.NewManager(&cfg.WebSocket)
+	siteEditor := editor.NewEditor(db, kimiClient, tokenMgr, revisions, wsManager, &cfg.Editor)
+	chatSvc := chat.NewService(db, kimiClient, tokenMgr, websiteGen, siteEditor, &cfg.Chat)
+	backplaneCtx, stopBackplane := context.WithCancel(context.Background())
+	defer stopBackplane()
+	if redisCache != nil {
+		backplane := websocket.NewRedisBackplane(redisCache.Client)
+		if err := wsManager.UseBackplane(backplaneCtx, backplane); err != nil {
+			logrus.WithError(err).Warn("WebSocket backplane unavailable, running single-node")
+		} else {
+			defer backplane.Close()
+			logrus.WithField("node", wsManager.NodeID()).Info("WebSocket backplane connected")
+		}
+	}
 	go wsManager.Run()
 
+	var wsTickets websocket.TicketStore = websocket.NewMemoryTicketStore()
+	if redisCache != nil {
+		wsTickets = websocket.NewRedisTicketStore(redisCache.Client)
+	}
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, jwtUtil, tokenMgr)
 	userHandler := handlers.NewUserHandler(db)
-	websiteHandler := handlers.NewWebsiteHandler(db, websiteGen)
-	aiHandler := handlers.NewAIHandler(db, kimiClient, websiteGen)
+	websiteHandler := handlers.NewWebsiteHandler(db, websiteGen, siteEditor, revisions, renderer, wsManager)
+	revisionHandler := handlers.NewRevisionHandler(revisions, wsManager)
+	themeHandler := handlers.NewThemeHandler(siteEditor)
+	pageHandler := handlers.NewPageHandler(db, siteEditor)
+	seoHandler := handlers.NewSEOHandler(db)
+	translationHandler := handlers.NewTranslationHandler(siteEditor)
+	accessibilityHandler := handlers.NewAccessibilityHandler(db, renderer, siteEditor)
+	templateHandler := handlers.NewTemplateHandler(templates, websiteGen)
+	aiHandler := handlers.NewAIHandler(db, kimiClient, websiteGen, chatSvc)
+	chatHandler := handlers.NewChatHandler(chatSvc)
 	tokenHandler := handlers.NewTokenHandler(db, tokenMgr)
-	deployHandler := handlers.NewDeployHandler(db)
-	wsHandler := handlers.NewWebSocketHandler(wsManager, jwtUtil, db, kimiClient)
+	deployHandler := handlers.NewDeployHandler(db, renderer, &cfg.Deploy)
+	wsHandler := handlers.NewWebSocketHandler(wsManager, wsTickets, db, chatSvc, limiter)
 
 	// Setup router
 	r := gin.New()
+	// Rate limits key anonymous callers by client IP, so only the proxies
+	// in front of the API may set it
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logrus.WithError(err).Fatal("Invalid trusted proxies")
+	}
 	r.Use(middleware.ErrorMiddleware())
 	r.Use(middleware.LoggerMiddleware())
 	r.Use(middleware.CORSMiddleware(&cfg.Server))
@@ -102,6 +162,7 @@ func main() {
 
 	// Public preview endpoint (no auth required for preview)
 	r.GET("/preview/:id", websiteHandler.Preview)
+	r.GET("/preview/:id/*page", websiteHandler.Preview)
 
 	// API routes
 	api := r.Group("/api")
@@ -109,15 +170,21 @@ func main() {
 		// Auth routes (public)
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/refresh", authHandler.Refresh)
-			auth.GET("/me", middleware.AuthMiddleware(jwtUtil), authHandler.Me)
+			authLimit := middleware.RateLimitMiddleware(limiter, ratelimit.GroupAuth)
+			auth.POST("/register", authLimit, authHandler.Register)
+			auth.POST("/login", authLimit, authHandler.Login)
+			auth.POST("/refresh", authLimit, authHandler.Refresh)
+			auth.GET("/me", middleware.AuthMiddleware(jwtUtil), middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault), authHandler.Me)
 		}
 
+		// Template catalog (public; signed-in users see what their plan includes)
+		api.GET("/templates", middleware.OptionalAuthMiddleware(jwtUtil), middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault), templateHandler.List)
+		api.GET("/templates/:id/thumbnail", templateHandler.Thumbnail)
+
 		// User routes (protected)
 		user := api.Group("/user")
 		user.Use(middleware.AuthMiddleware(jwtUtil))
+		user.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault))
 		{
 			user.GET("/profile", userHandler.GetProfile)
 			user.PUT("/profile", userHandler.UpdateProfile)
@@ -126,26 +193,74 @@ func main() {
 		// Website routes (protected)
 		websites := api.Group("/websites")
 		websites.Use(middleware.AuthMiddleware(jwtUtil))
+		websites.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault))
 		{
 			websites.GET("", websiteHandler.List)
 			websites.GET("/:id", websiteHandler.Get)
 			websites.POST("", websiteHandler.Create)
 			websites.PUT("/:id", websiteHandler.Update)
 			websites.DELETE("/:id", websiteHandler.Delete)
+			websites.POST("/:id/sections/:index/regenerate", middleware.RateLimitMiddleware(limiter, ratelimit.GroupAI), websiteHandler.RegenerateSection)
+			websites.POST("/:id/theme", themeHandler.Apply)
+			websites.GET("/:id/pages", pageHandler.List)
+			websites.POST("/:id/pages", pageHandler.Create)
+			websites.PUT("/:id/pages", pageHandler.Reorder)
+			websites.GET("/:id/pages/*slug", pageHandler.Get)
+			websites.PUT("/:id/pages/*slug", pageHandler.Update)
+			websites.DELETE("/:id/pages/*slug", pageHandler.Delete)
+			websites.GET("/:id/seo", seoHandler.Audit)
+			websites.POST("/:id/translate", middleware.RateLimitMiddleware(limiter, ratelimit.GroupAI), translationHandler.Translate)
+			websites.PUT("/:id/locale", translationHandler.SetLocale)
+			websites.DELETE("/:id/translations/:locale", translationHandler.Delete)
+			websites.GET("/:id/accessibility", accessibilityHandler.Check)
+			websites.POST("/:id/accessibility/fix", middleware.RateLimitMiddleware(limiter, ratelimit.GroupAI), accessibilityHandler.Fix)
+			websites.GET("/:id/revisions", revisionHandler.List)
+			websites.GET("/:id/revisions/compare", revisionHandler.Compare)
+			websites.GET("/:id/revisions/:version", revisionHandler.Get)
+			websites.POST("/:id/revisions/:version/restore", revisionHandler.Restore)
+			websites.PUT("/:id/revisions/:version/name", revisionHandler.Name)
 		}
 
 		// AI routes (protected)
 		ai := api.Group("/ai")
 		ai.Use(middleware.AuthMiddleware(jwtUtil))
+		ai.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupAI))
 		{
 			ai.POST("/generate", aiHandler.Generate)
 		}
 		// Chat can be optionally authenticated
-		api.POST("/ai/chat", middleware.OptionalAuthMiddleware(jwtUtil), aiHandler.Chat)
+		api.POST("/ai/chat", middleware.OptionalAuthMiddleware(jwtUtil), middleware.RateLimitMiddleware(limiter, ratelimit.GroupAI), aiHandler.Chat)
+		// SSE fallback for WebSocket chat; shares the WebSocket chat quota
+		api.POST("/ai/chat/stream", middleware.AuthMiddleware(jwtUtil), middleware.RateLimitMiddleware(limiter, ratelimit.GroupWSChat), aiHandler.ChatStream)
+		api.POST("/ai/chat/stream/cancel", middleware.AuthMiddleware(jwtUtil), middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault), aiHandler.CancelStream)
+
+		// Chat thread routes (protected)
+		chatRoutes := api.Group("/chat")
+		chatRoutes.Use(middleware.AuthMiddleware(jwtUtil))
+		chatRoutes.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault))
+		{
+			chatRoutes.GET("/threads", chatHandler.ListThreads)
+			chatRoutes.POST("/threads", chatHandler.CreateThread)
+			chatRoutes.GET("/threads/:id", chatHandler.GetThread)
+			chatRoutes.GET("/threads/:id/messages", chatHandler.ListMessages)
+			chatRoutes.PATCH("/threads/:id", chatHandler.RenameThread)
+			chatRoutes.DELETE("/threads/:id", chatHandler.DeleteThread)
+			chatRoutes.GET("/threads/:id/guide", chatHandler.GetGuide)
+			chatRoutes.POST("/threads/:id/guide/back", chatHandler.GuideBack)
+		}
+
+		// WebSocket ticket routes (protected)
+		ws := api.Group("/ws")
+		ws.Use(middleware.AuthMiddleware(jwtUtil))
+		ws.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault))
+		{
+			ws.POST("/ticket", wsHandler.IssueTicket)
+		}
 
 		// Token routes (protected)
 		tokens := api.Group("/tokens")
 		tokens.Use(middleware.AuthMiddleware(jwtUtil))
+		tokens.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault))
 		{
 			tokens.GET("/balance", tokenHandler.GetBalance)
 			tokens.GET("/transactions", tokenHandler.GetTransactions)
@@ -155,6 +270,7 @@ func main() {
 		// Deploy routes (protected)
 		deploy := api.Group("/deploy")
 		deploy.Use(middleware.AuthMiddleware(jwtUtil))
+		deploy.Use(middleware.RateLimitMiddleware(limiter, ratelimit.GroupDefault))
 		{
 			deploy.POST("", deployHandler.Deploy)
 			deploy.GET("/list", deployHandler.GetDeployments)
@@ -194,5 +310,11 @@ func main() {
 		logrus.WithError(err).Error("Failed to close database connection")
 	}
 
+	if redisCache != nil {
+		if err := redisCache.Close(); err != nil {
+			logrus.WithError(err).Error("Failed to close redis connection")
+		}
+	}
+
 	logrus.Info("Server exited")
 }
\ No newline at end of file
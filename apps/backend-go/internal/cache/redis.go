@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend-go/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Redis struct {
+	Client *redis.Client
+	config *config.RedisConfig
+}
+
+func New(cfg *config.Config) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Host + ":" + cfg.Redis.Port,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &Redis{
+		Client: client,
+		config: &cfg.Redis,
+	}, nil
+}
+
+func (r *Redis) Health() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return r.Client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.Client.Close()
+}
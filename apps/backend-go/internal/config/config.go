@@ -1,6 +1,8 @@
 package config
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -8,17 +10,26 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Kimi     KimiConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Kimi      KimiConfig
+	Chat      ChatConfig
+	Editor    EditorConfig
+	Deploy    DeployConfig
+	Revision  RevisionConfig
+	RateLimit RateLimitConfig
+	WebSocket WebSocketConfig
 }
 
 type ServerConfig struct {
 	Port         string
 	Environment  string
 	AllowOrigins []string
+	// TrustedProxies may set X-Forwarded-For; the client IP of other
+	// requests is their peer address
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -45,6 +56,72 @@ type JWTConfig struct {
 type KimiConfig struct {
 	APIKey string
 	BaseURL string
+	Model   string
+}
+
+// ChatConfig holds AI chat limits and pricing
+type ChatConfig struct {
+	// ResponseCost is the tokens charged per assistant response (0 = free)
+	ResponseCost int
+	// ResponseTimeout bounds how long one response may stream
+	ResponseTimeout time.Duration
+	// HistorySize is how many recent messages of a thread are kept verbatim;
+	// older ones are folded into the thread's running summary
+	HistorySize int
+	// ContextBudgets maps a model name to its context window in tokens, with
+	// "default" used for models that are not listed
+	ContextBudgets map[string]int
+}
+
+// EditorConfig holds website editing prices
+type EditorConfig struct {
+	// SectionRegenerateCost is the tokens charged to rewrite one section with
+	// AI (0 = free)
+	SectionRegenerateCost int
+	// TranslateCost is the tokens charged to translate a website into one
+	// locale with AI (0 = free)
+	TranslateCost int
+	// AccessibilityFixCost is the tokens charged per page to fix its
+	// accessibility findings with AI (0 = free)
+	AccessibilityFixCost int
+}
+
+// DeployConfig holds publishing checks
+type DeployConfig struct {
+	// RequireAccessible refuses to publish websites whose pages have
+	// accessibility errors, unless a deploy request says otherwise
+	RequireAccessible bool
+}
+
+// RevisionConfig holds the retention of website revisions. Named snapshots
+// and each website's latest revision are always kept.
+type RevisionConfig struct {
+	// Keep is how many recent revisions of a website are kept (0 = all)
+	Keep int
+	// MaxAge is how long older revisions are kept (0 = forever)
+	MaxAge time.Duration
+}
+
+// WebSocketConfig holds WebSocket connection limits and clustering settings
+type WebSocketConfig struct {
+	// MaxConnectionsPerUser caps concurrent connections per user (0 = unlimited)
+	MaxConnectionsPerUser int
+	// NodeID identifies this replica on the backplane (random when empty)
+	NodeID string
+	// PresenceTTL is how long presence entries live without a refresh
+	PresenceTTL time.Duration
+	// ReplayBufferSize is how many recent messages are kept per user for resume
+	ReplayBufferSize int
+	// ReplayRetention is how long a disconnected user's messages stay resumable
+	ReplayRetention time.Duration
+}
+
+// RateLimitConfig holds request quotas per route group and subscription tier.
+// Groups maps a group name (e.g. "ai") to tier -> requests allowed per Window.
+type RateLimitConfig struct {
+	Enabled bool
+	Window  time.Duration
+	Groups  map[string]map[string]int
 }
 
 func Load() (*Config, error) {
@@ -58,6 +135,7 @@ func Load() (*Config, error) {
 	viper.SetDefault("SERVER_PORT", "3001")
 	viper.SetDefault("SERVER_ENV", "development")
 	viper.SetDefault("SERVER_ALLOW_ORIGINS", "*")
+	viper.SetDefault("SERVER_TRUSTED_PROXIES", "")
 
 	viper.SetDefault("DB_HOST", "localhost")
 	viper.SetDefault("DB_PORT", "5432")
@@ -76,6 +154,34 @@ func Load() (*Config, error) {
 
 	viper.SetDefault("KIMI_API_KEY", "")
 	viper.SetDefault("KIMI_BASE_URL", "https://api.openai.com/v1")
+	viper.SetDefault("KIMI_MODEL", "gpt-4o")
+
+	viper.SetDefault("CHAT_RESPONSE_COST", 0)
+	viper.SetDefault("CHAT_RESPONSE_TIMEOUT", "120s")
+	viper.SetDefault("CHAT_HISTORY_SIZE", 20)
+	viper.SetDefault("CHAT_CONTEXT_BUDGETS", "default:8000,gpt-4o:128000,moonshot-v1-8k:8000,moonshot-v1-32k:32000,moonshot-v1-128k:128000")
+
+	viper.SetDefault("SECTION_REGENERATE_COST", 10)
+	viper.SetDefault("TRANSLATE_COST", 20)
+	viper.SetDefault("ACCESSIBILITY_FIX_COST", 10)
+
+	viper.SetDefault("DEPLOY_REQUIRE_ACCESSIBLE", false)
+
+	viper.SetDefault("REVISION_KEEP", 50)
+	viper.SetDefault("REVISION_MAX_AGE", "2160h")
+
+	viper.SetDefault("WS_MAX_CONNECTIONS_PER_USER", 5)
+	viper.SetDefault("WS_NODE_ID", "")
+	viper.SetDefault("WS_PRESENCE_TTL", "60s")
+	viper.SetDefault("WS_REPLAY_BUFFER_SIZE", 256)
+	viper.SetDefault("WS_REPLAY_RETENTION", "5m")
+
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_WINDOW", "1m")
+	viper.SetDefault("RATE_LIMIT_DEFAULT", "anonymous:60,free:120,pro:600,business:1200")
+	viper.SetDefault("RATE_LIMIT_AUTH", "anonymous:10,free:10,pro:10,business:10")
+	viper.SetDefault("RATE_LIMIT_AI", "anonymous:5,free:10,pro:60,business:120")
+	viper.SetDefault("RATE_LIMIT_WS_CHAT", "free:10,pro:60,business:120")
 
 	viper.AutomaticEnv()
 
@@ -84,11 +190,37 @@ func Load() (*Config, error) {
 		expiresIn = 24 * time.Hour
 	}
 
+	chatTimeout, err := time.ParseDuration(viper.GetString("CHAT_RESPONSE_TIMEOUT"))
+	if err != nil {
+		chatTimeout = 120 * time.Second
+	}
+
+	presenceTTL, err := time.ParseDuration(viper.GetString("WS_PRESENCE_TTL"))
+	if err != nil {
+		presenceTTL = time.Minute
+	}
+
+	replayRetention, err := time.ParseDuration(viper.GetString("WS_REPLAY_RETENTION"))
+	if err != nil {
+		replayRetention = 5 * time.Minute
+	}
+
+	revisionMaxAge, err := time.ParseDuration(viper.GetString("REVISION_MAX_AGE"))
+	if err != nil {
+		revisionMaxAge = 90 * 24 * time.Hour
+	}
+
+	rateLimitWindow, err := time.ParseDuration(viper.GetString("RATE_LIMIT_WINDOW"))
+	if err != nil {
+		rateLimitWindow = time.Minute
+	}
+
 	return &Config{
 		Server: ServerConfig{
-			Port:         viper.GetString("SERVER_PORT"),
-			Environment:  viper.GetString("SERVER_ENV"),
-			AllowOrigins: viper.GetStringSlice("SERVER_ALLOW_ORIGINS"),
+			Port:           viper.GetString("SERVER_PORT"),
+			Environment:    viper.GetString("SERVER_ENV"),
+			AllowOrigins:   parseList(viper.GetString("SERVER_ALLOW_ORIGINS")),
+			TrustedProxies: parseList(viper.GetString("SERVER_TRUSTED_PROXIES")),
 		},
 		Database: DatabaseConfig{
 			Host:     viper.GetString("DB_HOST"),
@@ -111,10 +243,76 @@ func Load() (*Config, error) {
 		Kimi: KimiConfig{
 			APIKey:  viper.GetString("KIMI_API_KEY"),
 			BaseURL: viper.GetString("KIMI_BASE_URL"),
+			Model:   viper.GetString("KIMI_MODEL"),
+		},
+		Chat: ChatConfig{
+			ResponseCost:    viper.GetInt("CHAT_RESPONSE_COST"),
+			ResponseTimeout: chatTimeout,
+			HistorySize:     viper.GetInt("CHAT_HISTORY_SIZE"),
+			ContextBudgets:  parseIntPairs(viper.GetString("CHAT_CONTEXT_BUDGETS")),
+		},
+		Editor: EditorConfig{
+			SectionRegenerateCost: viper.GetInt("SECTION_REGENERATE_COST"),
+			TranslateCost:         viper.GetInt("TRANSLATE_COST"),
+			AccessibilityFixCost:  viper.GetInt("ACCESSIBILITY_FIX_COST"),
+		},
+		Deploy: DeployConfig{
+			RequireAccessible: viper.GetBool("DEPLOY_REQUIRE_ACCESSIBLE"),
+		},
+		Revision: RevisionConfig{
+			Keep:   viper.GetInt("REVISION_KEEP"),
+			MaxAge: revisionMaxAge,
+		},
+		WebSocket: WebSocketConfig{
+			MaxConnectionsPerUser: viper.GetInt("WS_MAX_CONNECTIONS_PER_USER"),
+			NodeID:                viper.GetString("WS_NODE_ID"),
+			PresenceTTL:           presenceTTL,
+			ReplayBufferSize:      viper.GetInt("WS_REPLAY_BUFFER_SIZE"),
+			ReplayRetention:       replayRetention,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: viper.GetBool("RATE_LIMIT_ENABLED"),
+			Window:  rateLimitWindow,
+			Groups: map[string]map[string]int{
+				"default": parseIntPairs(viper.GetString("RATE_LIMIT_DEFAULT")),
+				"auth":    parseIntPairs(viper.GetString("RATE_LIMIT_AUTH")),
+				"ai":      parseIntPairs(viper.GetString("RATE_LIMIT_AI")),
+				"ws_chat": parseIntPairs(viper.GetString("RATE_LIMIT_WS_CHAT")),
+			},
 		},
 	}, nil
 }
 
+// parseList splits a comma-separated value, dropping empty entries
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseIntPairs parses "key:int" pairs separated by commas,
+// e.g. "anonymous:5,free:10,pro:60". Malformed pairs and negative values are
+// skipped.
+func parseIntPairs(value string) map[string]int {
+	pairs := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 {
+			continue
+		}
+		pairs[strings.TrimSpace(parts[0])] = n
+	}
+	return pairs
+}
+
 func (c *Config) GetDSN() string {
 	return "host=" + c.Database.Host +
 		" user=" + c.Database.User +
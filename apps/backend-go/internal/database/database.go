@@ -53,7 +53,10 @@ func (d *Database) Migrate() error {
 	err := d.DB.AutoMigrate(
 		&models.User{},
 		&models.Website{},
+		&models.WebsiteRevision{},
 		&models.TokenTransaction{},
+		&models.ChatThread{},
+		&models.ChatMessage{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend-go/internal/database"
+	"backend-go/internal/models"
+	"backend-go/internal/services/a11y"
+	"backend-go/internal/services/editor"
+	"backend-go/internal/services/render"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// fixableRules are the accessibility findings the content can fix; the
+// others come from the templates or the design tokens
+var fixableRules = map[string]bool{
+	"missing_alt":   true,
+	"empty_alt":     true,
+	"empty_link":    true,
+	"vague_link":    true,
+	"empty_heading": true,
+	"empty_button":  true,
+}
+
+type AccessibilityHandler struct {
+	db       *database.Database
+	renderer *render.Renderer
+	editor   *editor.Editor
+}
+
+func NewAccessibilityHandler(db *database.Database, renderer *render.Renderer, siteEditor *editor.Editor) *AccessibilityHandler {
+	return &AccessibilityHandler{db: db, renderer: renderer, editor: siteEditor}
+}
+
+// Check renders the website as it would be published and lists its
+// accessibility findings
+func (h *AccessibilityHandler) Check(c *gin.Context) {
+	site, content, report, ok := h.check(c)
+	if !ok {
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"errors":         report.Errors,
+		"warnings":       report.Warnings,
+		"findings":       report.Findings,
+		"fixable":        len(fixableFindings(content, report)) > 0,
+		"contentVersion": site.ContentVersion,
+	})
+}
+
+// Fix has AI rewrite the content of the pages with fixable findings
+func (h *AccessibilityHandler) Fix(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	_, content, report, ok := h.check(c)
+	if !ok {
+		return
+	}
+
+	findings := fixableFindings(content, report)
+	if len(findings) == 0 {
+		utils.ValidationError(c, "There are no accessibility issues AI can fix")
+		return
+	}
+
+	result, err := h.editor.FixAccessibility(c.Request.Context(), userID, websiteID, findings)
+	if err != nil {
+		accessibilityError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"pages":          result.Pages,
+		"contentVersion": result.Version,
+		"tokensUsed":     result.TokensUsed,
+	})
+}
+
+// check loads and renders the website and runs the accessibility checks
+func (h *AccessibilityHandler) check(c *gin.Context) (*models.Website, *models.SiteContent, *a11y.Report, bool) {
+	site, content, ok := loadContent(c, h.db)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	rendered, err := h.renderer.Render(site, siteURL(*site))
+	if err != nil {
+		logrus.WithError(err).WithField("website_id", site.ID).Error("Failed to render website")
+		utils.InternalError(c)
+		return nil, nil, nil, false
+	}
+	return site, content, a11y.Check(rendered, content, renderedTokens(site)), true
+}
+
+// checkAccessibility runs the accessibility checks on a rendered website
+func checkAccessibility(website *models.Website, site *render.Site) (*a11y.Report, error) {
+	content, err := models.ParseContent(website.GeneratedContent)
+	if err != nil {
+		return nil, err
+	}
+	return a11y.Check(site, content, renderedTokens(website)), nil
+}
+
+// renderedTokens are the design tokens the website renders with: the
+// default design, nil, when its own cannot be read
+func renderedTokens(website *models.Website) *models.DesignTokens {
+	tokens, err := models.ParseDesignTokens(website.DesignTokens)
+	if err != nil {
+		logrus.WithError(err).WithField("website_id", website.ID).Warn("Checking website contrast with the default design")
+		return nil
+	}
+	return tokens
+}
+
+// fixableFindings groups the findings the content can fix by page slug,
+// for the default locale's pages only
+func fixableFindings(content *models.SiteContent, report *a11y.Report) map[string][]string {
+	slugs := map[string]string{}
+	for _, page := range content.Pages {
+		slugs["/"+page.Slug+"/"] = page.Slug
+	}
+	slugs["/"] = ""
+
+	findings := map[string][]string{}
+	for _, f := range report.Findings {
+		slug, ok := slugs[f.Page]
+		if !ok || !fixableRules[f.Rule] {
+			continue
+		}
+		problem := f.Message
+		if f.Element != "" {
+			problem += ": " + f.Element
+		}
+		findings[slug] = append(findings[slug], problem)
+	}
+	return findings
+}
+
+// accessibilityError writes the response for a failed accessibility fix
+func accessibilityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, editor.ErrWebsiteNotFound):
+		utils.NotFound(c, "Website not found")
+	case errors.Is(err, editor.ErrPageNotFound):
+		utils.NotFound(c, "Page not found")
+	case errors.Is(err, editor.ErrInvalidEdit):
+		utils.ValidationError(c, err.Error())
+	case errors.Is(err, editor.ErrInsufficientTokens):
+		utils.InsufficientTokens(c)
+	case errors.Is(err, editor.ErrConflict):
+		utils.Conflict(c, err.Error())
+	case errors.Is(err, editor.ErrGenerationFailed):
+		utils.JSONError(c, http.StatusBadGateway, "GENERATION_FAILED", err.Error())
+	default:
+		logrus.WithError(err).Error("Accessibility fix failed")
+		utils.InternalError(c)
+	}
+}
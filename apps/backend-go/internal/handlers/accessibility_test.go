@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/render"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestCheckAccessibility_UnreadableTokens(t *testing.T) {
+	renderer, err := render.New()
+	require.NoError(t, err)
+
+	// The site renders with the default design, so that is what is checked
+	website := &models.Website{
+		Title:            "Sweet Bites",
+		GeneratedContent: datatypes.JSON(`{"title":"Sweet Bites","locale":"en","sections":[{"type":"hero","content":{"title":"Fresh bread daily"}}]}`),
+		DesignTokens:     datatypes.JSON(`"not tokens"`),
+	}
+	site, err := renderer.Render(website, "")
+	require.NoError(t, err)
+
+	report, err := checkAccessibility(website, site)
+	require.NoError(t, err)
+	assert.False(t, report.HasErrors())
+}
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"backend-go/internal/database"
+	"backend-go/internal/models"
 	"backend-go/internal/services/ai"
+	"backend-go/internal/services/chat"
 	"backend-go/internal/services/website"
 	"backend-go/internal/utils"
+	"backend-go/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -19,14 +27,18 @@ type AIHandler struct {
 	db        *database.Database
 	kimi      *ai.KimiClient
 	generator *website.Generator
+	chat      *chat.Service
+	events    *chat.EventLog
 	validate  *validator.Validate
 }
 
-func NewAIHandler(db *database.Database, kimi *ai.KimiClient, generator *website.Generator) *AIHandler {
+func NewAIHandler(db *database.Database, kimi *ai.KimiClient, generator *website.Generator, chatSvc *chat.Service) *AIHandler {
 	return &AIHandler{
 		db:        db,
 		kimi:      kimi,
 		generator: generator,
+		chat:      chatSvc,
+		events:    chat.NewEventLog(sseEventRetention),
 		validate:  validator.New(),
 	}
 }
@@ -41,6 +53,23 @@ type ChatRequest struct {
 	Messages []ai.Message `json:"messages" validate:"required,min=1"`
 }
 
+type ChatStreamRequest struct {
+	Content    string `json:"content" validate:"required_without=Regenerate,max=4000"`
+	WebsiteID  string `json:"websiteId" validate:"omitempty,uuid"`
+	ThreadID   string `json:"threadId" validate:"omitempty,uuid"`
+	Regenerate bool   `json:"regenerate"`
+}
+
+const (
+	// sseHeartbeatInterval keeps proxies from closing idle streams
+	sseHeartbeatInterval = 15 * time.Second
+	// sseEventRetention is how long a finished response can be resumed
+	sseEventRetention = 5 * time.Minute
+	// sseResumeGrace is how long a response keeps being generated with no
+	// client following it, waiting for one to reconnect
+	sseResumeGrace = 30 * time.Second
+)
+
 func (h *AIHandler) Generate(c *gin.Context) {
 	userID, exists := c.Get("userId")
 	if !exists {
@@ -62,6 +91,10 @@ func (h *AIHandler) Generate(c *gin.Context) {
 	// Normalize subdomain
 	subdomain := strings.ToLower(strings.TrimSpace(req.Subdomain))
 	subdomain = strings.ReplaceAll(subdomain, " ", "-")
+	if !models.ValidSubdomain(subdomain) {
+		utils.ValidationError(c, "Subdomain must be 1 to 63 lowercase letters, digits or hyphens, and cannot start or end with a hyphen")
+		return
+	}
 
 	result, err := h.generator.Generate(c.Request.Context(), website.GenerateRequest{
 		UserID:     userID.(uuid.UUID),
@@ -71,8 +104,11 @@ func (h *AIHandler) Generate(c *gin.Context) {
 	})
 
 	if err != nil {
+		if templateError(c, err) {
+			return
+		}
 		logrus.WithError(err).Error("Website generation failed")
-		if err.Error() == "insufficient tokens" || strings.Contains(err.Error(), "insufficient") {
+		if errors.Is(err, website.ErrInsufficientTokens) {
 			utils.InsufficientTokens(c)
 			return
 		}
@@ -107,4 +143,185 @@ func (h *AIHandler) Chat(c *gin.Context) {
 	utils.JSONSuccess(c, http.StatusOK, gin.H{
 		"message": response,
 	})
-}
\ No newline at end of file
+}
+
+// ChatStream streams an AI chat response as Server-Sent Events, for clients
+// whose network blocks WebSocket upgrades. Events mirror the WebSocket
+// messages: chat:message (the prompt), chat:stream (chunks), chat:message
+// (the final answer) and error. A client that lost the stream reconnects
+// with Last-Event-ID and receives the events it missed. Generation does not
+// depend on the request: it runs until done, CHAT_RESPONSE_TIMEOUT, a
+// cancel request, or sseResumeGrace passing without any client following.
+func (h *AIHandler) ChatStream(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+	uid := userID.(uuid.UUID)
+
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		responseID, after, err := h.events.Resolve(uid, lastEventID)
+		if err != nil {
+			utils.NotFound(c, "Stream not found or expired")
+			return
+		}
+		h.writeEvents(c, responseID, after)
+		return
+	}
+
+	var req ChatStreamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if req.Regenerate {
+		if current := h.chat.Active(uid); current != nil {
+			current.Stop(chat.TruncatedRegenerated)
+			<-current.Done()
+		}
+	}
+
+	// The response outlives the request so a client that reconnects can
+	// resume it; Supervise stops it if none does
+	stream, err := h.chat.Begin(context.Background(), uid)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	thread, err := h.chat.Thread(uid, parseUUID(req.ThreadID), parseUUID(req.WebsiteID))
+	if err == nil {
+		if req.Regenerate {
+			err = h.chat.PrepareRegenerate(thread)
+		} else {
+			err = h.chat.AddUserMessage(thread, req.Content)
+		}
+	}
+	if err != nil {
+		h.chat.End(uid, stream)
+		chatError(c, err)
+		return
+	}
+
+	h.events.Start(stream.ID, uid)
+	go h.events.Supervise(stream, sseResumeGrace)
+	if !req.Regenerate {
+		h.events.Append(stream.ID, string(websocket.MessageTypeChatMessage), websocket.Message{
+			Type:      websocket.MessageTypeChatMessage,
+			ID:        uuid.New().String(),
+			Role:      "user",
+			Content:   req.Content,
+			ThreadID:  thread.ID.String(),
+			Timestamp: time.Now(),
+		})
+	}
+
+	go func() {
+		defer h.events.Finish(stream.ID)
+
+		var reply *chat.Reply
+		var err error
+		if chat.Guiding(thread) {
+			reply, err = h.chat.Guide(stream, thread, req.Content)
+		} else {
+			reply, err = h.chat.Respond(stream, thread, func(chunk string) {
+				h.events.Append(stream.ID, string(websocket.MessageTypeChatStream), websocket.Message{
+					Type:      websocket.MessageTypeChatStream,
+					ID:        stream.ID,
+					Chunk:     chunk,
+					Timestamp: time.Now(),
+				})
+			})
+		}
+		if err != nil {
+			code, message := chatErrorCode(err)
+			h.events.Append(stream.ID, string(websocket.MessageTypeError), websocket.ErrorMessage(stream.ID, code, message))
+			return
+		}
+		h.events.Append(stream.ID, string(websocket.MessageTypeChatMessage), replyMessage(reply))
+	}()
+
+	h.writeEvents(c, stream.ID, 0)
+}
+
+// CancelStream stops the response being generated for the user
+func (h *AIHandler) CancelStream(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	stream := h.chat.Active(userID.(uuid.UUID))
+	if stream == nil {
+		utils.NotFound(c, "No response is being generated")
+		return
+	}
+	stream.Stop(chat.TruncatedCancelled)
+	<-stream.Done()
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"id": stream.ID})
+}
+
+// writeEvents streams a response's events after the first `after` ones,
+// with heartbeat comments while the model is quiet
+func (h *AIHandler) writeEvents(c *gin.Context, responseID string, after int) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	emit := func(event chat.Event) error {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Name, data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	heartbeat := func() error {
+		if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if err := h.events.Follow(c.Request.Context(), responseID, after, sseHeartbeatInterval, emit, heartbeat); err != nil {
+		logrus.WithError(err).WithField("responseId", responseID).Debug("SSE stream ended early")
+	}
+}
+
+// chatError maps chat service errors to HTTP responses
+func chatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, chat.ErrBusy):
+		utils.Conflict(c, "A response is already being generated")
+	case errors.Is(err, chat.ErrInsufficientTokens):
+		utils.InsufficientTokens(c)
+	case errors.Is(err, chat.ErrNothingToRegenerate):
+		utils.NotFound(c, "Nothing to regenerate")
+	case errors.Is(err, chat.ErrThreadNotFound):
+		utils.NotFound(c, "Thread not found")
+	case errors.Is(err, chat.ErrWebsiteNotFound):
+		utils.NotFound(c, "Website not found")
+	case errors.Is(err, chat.ErrNotGuided):
+		utils.NotFound(c, "Thread is not guided")
+	case errors.Is(err, chat.ErrCannotGoBack):
+		utils.Conflict(c, "Cannot go back from this step")
+	default:
+		logrus.WithError(err).Error("Chat request failed")
+		utils.InternalError(c)
+	}
+}
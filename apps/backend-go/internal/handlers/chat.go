@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend-go/internal/services/chat"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// maxPageSize bounds the limit query parameter of thread listings
+const maxPageSize = 100
+
+type ChatHandler struct {
+	chat     *chat.Service
+	validate *validator.Validate
+}
+
+func NewChatHandler(chatSvc *chat.Service) *ChatHandler {
+	return &ChatHandler{
+		chat:     chatSvc,
+		validate: validator.New(),
+	}
+}
+
+type CreateThreadRequest struct {
+	Title     string `json:"title" validate:"max=200"`
+	WebsiteID string `json:"websiteId" validate:"omitempty,uuid,excluded_with=Guided"`
+	// Guided starts the step-by-step conversation that builds a new website
+	Guided bool `json:"guided"`
+}
+
+type RenameThreadRequest struct {
+	Title string `json:"title" validate:"required,max=200"`
+}
+
+// ListThreads returns the user's chat threads, most recently active first.
+// ?websiteId= restricts the list to one website.
+func (h *ChatHandler) ListThreads(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var websiteID *uuid.UUID
+	if w := c.Query("websiteId"); w != "" {
+		parsed, err := uuid.Parse(w)
+		if err != nil {
+			utils.BadRequest(c, "Invalid website ID")
+			return
+		}
+		websiteID = &parsed
+	}
+
+	limit, offset := pagination(c)
+	threads, total, err := h.chat.ListThreads(userID.(uuid.UUID), websiteID, limit, offset)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	responses := make([]map[string]interface{}, len(threads))
+	for i, t := range threads {
+		responses[i] = t.Response()
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"threads": responses,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// CreateThread starts an empty thread, optionally about a website
+func (h *ChatHandler) CreateThread(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req CreateThreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if req.Guided {
+		thread, guide, err := h.chat.StartGuide(userID.(uuid.UUID), req.Title)
+		if err != nil {
+			chatError(c, err)
+			return
+		}
+
+		response := thread.Response()
+		response["guide"] = guide.Response()
+		utils.JSONSuccess(c, http.StatusCreated, response)
+		return
+	}
+
+	thread, err := h.chat.CreateThread(userID.(uuid.UUID), parseUUID(req.WebsiteID), req.Title)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusCreated, thread.Response())
+}
+
+// GetThread returns one thread
+func (h *ChatHandler) GetThread(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	threadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid thread ID")
+		return
+	}
+
+	thread, err := h.chat.GetThread(userID.(uuid.UUID), threadID)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	response := thread.Response()
+	if guide, err := chat.LoadGuide(thread); err == nil && guide != nil {
+		response["guide"] = guide.Response()
+	}
+	utils.JSONSuccess(c, http.StatusOK, response)
+}
+
+// ListMessages returns a page of a thread's messages, newest first
+func (h *ChatHandler) ListMessages(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	threadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid thread ID")
+		return
+	}
+
+	limit, offset := pagination(c)
+	messages, total, err := h.chat.Messages(userID.(uuid.UUID), threadID, limit, offset)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	responses := make([]map[string]interface{}, len(messages))
+	for i, m := range messages {
+		responses[i] = m.Response()
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"messages": responses,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// RenameThread changes a thread's title
+func (h *ChatHandler) RenameThread(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	threadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid thread ID")
+		return
+	}
+
+	var req RenameThreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	thread, err := h.chat.RenameThread(userID.(uuid.UUID), threadID, req.Title)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, thread.Response())
+}
+
+// DeleteThread deletes a thread with its messages
+func (h *ChatHandler) DeleteThread(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	threadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid thread ID")
+		return
+	}
+
+	if err := h.chat.DeleteThread(userID.(uuid.UUID), threadID); err != nil {
+		chatError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"deleted": true})
+}
+
+// GetGuide returns the state of a guided thread: the current step, the
+// answers so far and the question being asked
+func (h *ChatHandler) GetGuide(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	threadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid thread ID")
+		return
+	}
+
+	_, guide, err := h.chat.GuideState(userID.(uuid.UUID), threadID)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, guide.Response())
+}
+
+// GuideBack returns a guided thread to the previous step so its answer can
+// be changed
+func (h *ChatHandler) GuideBack(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	threadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid thread ID")
+		return
+	}
+
+	guide, err := h.chat.GuideBack(userID.(uuid.UUID), threadID)
+	if err != nil {
+		chatError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, guide.Response())
+}
+
+// pagination reads the limit and offset query parameters
+func pagination(c *gin.Context) (int, int) {
+	limit := 20
+	offset := 0
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	return limit, offset
+}
@@ -7,8 +7,10 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"backend-go/internal/config"
 	"backend-go/internal/database"
 	"backend-go/internal/models"
+	"backend-go/internal/services/render"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,17 +19,22 @@ import (
 
 // DeployHandler handles website deployment
 type DeployHandler struct {
-	db *database.Database
+	db       *database.Database
+	renderer *render.Renderer
+	config   *config.DeployConfig
 }
 
 // NewDeployHandler creates new deploy handler
-func NewDeployHandler(db *database.Database) *DeployHandler {
-	return &DeployHandler{db: db}
+func NewDeployHandler(db *database.Database, renderer *render.Renderer, cfg *config.DeployConfig) *DeployHandler {
+	return &DeployHandler{db: db, renderer: renderer, config: cfg}
 }
 
 // DeployRequest represents deployment request
 type DeployRequest struct {
 	WebsiteID string `json:"websiteId" binding:"required,uuid"`
+	// RequireAccessible refuses to publish pages with accessibility errors;
+	// DEPLOY_REQUIRE_ACCESSIBLE decides when it is not set
+	RequireAccessible *bool `json:"requireAccessible"`
 }
 
 // DeployResponse represents deployment response
@@ -47,6 +54,7 @@ type DeployResponse struct {
 // @Success 200 {object} DeployResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /deploy [post]
 func (h *DeployHandler) Deploy(c *gin.Context) {
@@ -80,12 +88,50 @@ func (h *DeployHandler) Deploy(c *gin.Context) {
 	subdomain := website.Subdomain
 	if subdomain == "" {
 		subdomain = generateSubdomain(website.Title)
+		if !models.ValidSubdomain(subdomain) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Website needs a subdomain before it can be deployed"})
+			return
+		}
 		website.Subdomain = subdomain
 		h.db.DB.Save(&website)
 	}
+	// The subdomain names the directory the site replaces
+	if !models.ValidSubdomain(subdomain) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Website subdomain is invalid"})
+		return
+	}
+
+	// Render the same pages as the preview
+	site, err := h.renderer.Render(&website, siteURL(website))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to render website")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Deployment failed"})
+		return
+	}
+
+	requireAccessible := h.config.RequireAccessible
+	if req.RequireAccessible != nil {
+		requireAccessible = *req.RequireAccessible
+	}
+	if requireAccessible {
+		report, err := checkAccessibility(&website, site)
+		if err != nil {
+			// The content rendered, so this is stored data the checks cannot read
+			logrus.WithError(err).WithField("website_id", website.ID).Error("Failed to check website accessibility")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Deployment failed"})
+			return
+		}
+		if report.HasErrors() {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":         fmt.Sprintf("The website has %d accessibility errors", report.Errors),
+				"accessibility": report,
+			})
+			return
+		}
+	}
 
 	// Trigger deployment
-	deployURL, err := h.deployWebsite(website)
+	deployURL, err := h.deployWebsite(website, site)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to deploy website")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Deployment failed"})
@@ -103,35 +149,17 @@ func (h *DeployHandler) Deploy(c *gin.Context) {
 	})
 }
 
-// deployWebsite triggers the deployment script
-func (h *DeployHandler) deployWebsite(website models.Website) (string, error) {
-	// Get base domain from environment
-	baseDomain := os.Getenv("BASE_DOMAIN")
-	if baseDomain == "" {
-		baseDomain = "sitespark.id" // default
-	}
-
-	// Get generated HTML content
-	htmlContent, err := h.generateHTML(website)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate HTML: %w", err)
-	}
-
+// deployWebsite writes the rendered site and triggers the deployment
+// script
+func (h *DeployHandler) deployWebsite(website models.Website, site *render.Site) (string, error) {
 	// Create website directory
 	websitesDir := os.Getenv("WEBSITES_DIR")
 	if websitesDir == "" {
 		websitesDir = "./websites"
 	}
 
-	websiteDir := filepath.Join(websitesDir, website.Subdomain)
-	if err := os.MkdirAll(websiteDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	// Write index.html
-	indexPath := filepath.Join(websiteDir, "index.html")
-	if err := os.WriteFile(indexPath, []byte(htmlContent), 0644); err != nil {
-		return "", fmt.Errorf("failed to write HTML: %w", err)
+	if err := writeSite(websitesDir, website.Subdomain, site); err != nil {
+		return "", err
 	}
 
 	// Run deployment script (optional - for Dokploy deployment)
@@ -156,75 +184,91 @@ func (h *DeployHandler) deployWebsite(website models.Website) (string, error) {
 		logrus.Info("No deployment script found, skipping script execution")
 	}
 
-	// Return deployed URL
-	deployURL := fmt.Sprintf("https://%s.%s", website.Subdomain, baseDomain)
-	return deployURL, nil
+	return siteURL(website), nil
 }
 
-// generateHTML generates HTML content from website config
-func (h *DeployHandler) generateHTML(website models.Website) (string, error) {
-	// TODO: Use template engine to generate HTML from website.Config
-	// For now, return a basic template
-	
-	title := website.Title
-	if title == "" {
-		title = "My Website"
-	}
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="id">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>%s</title>
-    <style>
-        * { margin: 0; padding: 0; box-sizing: border-box; }
-        body { 
-            font-family: 'Plus Jakarta Sans', -apple-system, BlinkMacSystemFont, sans-serif;
-            line-height: 1.6;
-            color: #1e293b;
-        }
-        .container { max-width: 1200px; margin: 0 auto; padding: 0 20px; }
-        header { 
-            background: linear-gradient(135deg, #2563eb 0%%, #7c3aed 100%%);
-            color: white;
-            padding: 4rem 0;
-            text-align: center;
-        }
-        h1 { font-size: 3rem; margin-bottom: 1rem; font-weight: 700; }
-        .subtitle { font-size: 1.25rem; opacity: 0.9; }
-        main { padding: 4rem 0; text-align: center; }
-        footer {
-            background: #1e293b;
-            color: white;
-            padding: 2rem 0;
-            text-align: center;
-            margin-top: 4rem;
-        }
-    </style>
-    <link href="https://fonts.googleapis.com/css2?family=Plus+Jakarta+Sans:wght@400;500;600;700&display=swap" rel="stylesheet">
-</head>
-<body>
-    <header>
-        <div class="container">
-            <h1>%s</h1>
-            <p class="subtitle">%s</p>
-        </div>
-    </header>
-    <main>
-        <div class="container">
-            <p>Website generated by SiteSpark AI</p>
-        </div>
-    </main>
-    <footer>
-        <div class="container">
-            <p>Made with ❤️ by SiteSpark</p>
-        </div>
-    </footer>
-</body>
-</html>`, title, title, website.Description)
-
-	return html, nil
+// baseDomain is the domain websites are published under
+func baseDomain() string {
+	if domain := os.Getenv("BASE_DOMAIN"); domain != "" {
+		return domain
+	}
+	return "sitespark.id" // default
+}
+
+// siteURL is where a website is published; empty before it has a subdomain
+func siteURL(website models.Website) string {
+	if website.Subdomain == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://%s.%s", website.Subdomain, baseDomain())
+}
+
+// siteDir is the directory a website is published to, the child of
+// websitesDir named after its subdomain. Anything else, such as an empty
+// subdomain naming websitesDir itself or a path leaving it, is refused,
+// since deploying replaces the whole directory.
+func siteDir(websitesDir, subdomain string) (string, error) {
+	if !models.ValidSubdomain(subdomain) {
+		return "", fmt.Errorf("invalid subdomain %q", subdomain)
+	}
+	root, err := filepath.Abs(websitesDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid websites directory: %w", err)
+	}
+	dir := filepath.Join(root, subdomain)
+	if filepath.Dir(dir) != root {
+		return "", fmt.Errorf("subdomain %q is outside the websites directory", subdomain)
+	}
+	return dir, nil
+}
+
+// writeSite replaces the directory of the website with the subdomain with
+// its rendered pages, sitemap.xml and robots.txt. The files are written to
+// a new directory first, so visitors never see a half-written site and
+// deleted pages disappear.
+func writeSite(websitesDir, subdomain string, site *render.Site) error {
+	websiteDir, err := siteDir(websitesDir, subdomain)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(websiteDir), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(websiteDir), "."+filepath.Base(websiteDir)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	for _, page := range site.Pages {
+		// Page paths come from validated slugs and are always relative
+		path := filepath.Join(staging, filepath.FromSlash(page.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(path, page.HTML, 0644); err != nil {
+			return fmt.Errorf("failed to write HTML: %w", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(staging, "sitemap.xml"), site.Sitemap, 0644); err != nil {
+		return fmt.Errorf("failed to write sitemap: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(staging, "robots.txt"), site.Robots, 0644); err != nil {
+		return fmt.Errorf("failed to write robots.txt: %w", err)
+	}
+
+	previous := staging + ".old"
+	if err := os.Rename(websiteDir, previous); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace website: %w", err)
+	}
+	if err := os.Rename(staging, websiteDir); err != nil {
+		os.Rename(previous, websiteDir)
+		return fmt.Errorf("failed to replace website: %w", err)
+	}
+	return os.RemoveAll(previous)
 }
 
 // generateSubdomain creates a URL-friendly subdomain
@@ -266,16 +310,11 @@ func (h *DeployHandler) GetDeployments(c *gin.Context) {
 		return
 	}
 
-	baseDomain := os.Getenv("BASE_DOMAIN")
-	if baseDomain == "" {
-		baseDomain = "sitespark.id"
-	}
-
 	deployments := make([]DeployResponse, len(websites))
 	for i, website := range websites {
 		deployments[i] = DeployResponse{
 			Subdomain: website.Subdomain,
-			URL:       fmt.Sprintf("https://%s.%s", website.Subdomain, baseDomain),
+			URL:       siteURL(website),
 			Status:    website.Status,
 		}
 	}
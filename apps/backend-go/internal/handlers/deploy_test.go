@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend-go/internal/services/render"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSite is a rendered site with only a home page
+func testSite() *render.Site {
+	return &render.Site{
+		Pages:   []*render.Page{{Path: "index.html", HTML: []byte("<h1>New</h1>")}},
+		Sitemap: []byte("<urlset></urlset>"),
+		Robots:  []byte("User-agent: *"),
+	}
+}
+
+// publish creates a published website directory with an index page
+func publish(t *testing.T, dir string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Old</h1>"), 0644))
+}
+
+func TestWriteSite(t *testing.T) {
+	root := t.TempDir()
+	websitesDir := filepath.Join(root, "websites")
+	publish(t, filepath.Join(websitesDir, "bakery"))
+	publish(t, filepath.Join(root, "scripts"))
+
+	require.NoError(t, writeSite(websitesDir, "bakery", testSite()))
+	html, err := os.ReadFile(filepath.Join(websitesDir, "bakery", "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>New</h1>", string(html))
+	assert.FileExists(t, filepath.Join(websitesDir, "bakery", "sitemap.xml"))
+
+	for _, subdomain := range []string{"", "../scripts", "bakery/..", ".", "Bakery", "-bakery"} {
+		assert.Error(t, writeSite(websitesDir, subdomain, testSite()), "subdomain %q", subdomain)
+	}
+	// Neither the other sites nor anything outside the websites directory
+	// were replaced
+	assert.FileExists(t, filepath.Join(websitesDir, "bakery", "index.html"))
+	assert.FileExists(t, filepath.Join(root, "scripts", "index.html"))
+	entries, err := os.ReadDir(websitesDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
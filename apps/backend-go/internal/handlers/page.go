@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"backend-go/internal/database"
+	"backend-go/internal/models"
+	"backend-go/internal/services/editor"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type PageHandler struct {
+	db     *database.Database
+	editor *editor.Editor
+}
+
+func NewPageHandler(db *database.Database, siteEditor *editor.Editor) *PageHandler {
+	return &PageHandler{db: db, editor: siteEditor}
+}
+
+type CreatePageRequest struct {
+	Slug     string           `json:"slug" binding:"required"`
+	Title    string           `json:"title" binding:"required"`
+	NavLabel string           `json:"navLabel"`
+	SEO      *models.SEO      `json:"seo"`
+	Sections []models.Section `json:"sections"`
+	Position *int             `json:"position"`
+}
+
+// UpdatePageRequest changes the fields that are set; a new slug moves the
+// page and the pages below it
+type UpdatePageRequest struct {
+	Slug     *string          `json:"slug"`
+	Title    *string          `json:"title"`
+	NavLabel *string          `json:"navLabel"`
+	SEO      *models.SEO      `json:"seo"`
+	Sections []models.Section `json:"sections"`
+}
+
+type ReorderPagesRequest struct {
+	Order []string `json:"order" binding:"required"`
+}
+
+// pageSummary describes a page in the list; the home page has the empty slug
+func pageSummary(page *models.Page) gin.H {
+	path := "/"
+	if page.Slug != "" {
+		path = "/" + page.Slug + "/"
+	}
+	return gin.H{
+		"slug":     page.Slug,
+		"title":    page.Title,
+		"navLabel": page.NavLabel,
+		"path":     path,
+		"sections": len(page.Sections),
+		"seo":      page.SEO,
+	}
+}
+
+// List returns the website's pages, the home page first, and the locales
+// they are published in
+func (h *PageHandler) List(c *gin.Context) {
+	site, content, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	pages := []gin.H{pageSummary(&models.Page{Title: site.Title, Sections: content.Sections, SEO: content.SEO})}
+	for i := range content.Pages {
+		pages = append(pages, pageSummary(&content.Pages[i]))
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"pages":          pages,
+		"locales":        content.Locales(),
+		"contentVersion": site.ContentVersion,
+	})
+}
+
+// Get returns one page with its sections
+func (h *PageHandler) Get(c *gin.Context) {
+	site, content, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	page := content.Page(pageSlug(c))
+	if page == nil {
+		utils.NotFound(c, "Page not found")
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"page":           page,
+		"contentVersion": site.ContentVersion,
+	})
+}
+
+func (h *PageHandler) Create(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	var req CreatePageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	version, err := h.editor.AddPage(userID, websiteID, editor.PageFields{
+		Slug:     req.Slug,
+		Title:    req.Title,
+		NavLabel: req.NavLabel,
+		SEO:      req.SEO,
+		Sections: req.Sections,
+		Position: req.Position,
+	})
+	if err != nil {
+		pageError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, gin.H{"contentVersion": version})
+}
+
+func (h *PageHandler) Update(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	version, err := h.editor.UpdatePage(userID, websiteID, pageSlug(c), editor.PageUpdate{
+		Slug:     req.Slug,
+		Title:    req.Title,
+		NavLabel: req.NavLabel,
+		SEO:      req.SEO,
+		Sections: req.Sections,
+	})
+	if err != nil {
+		pageError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"contentVersion": version})
+}
+
+func (h *PageHandler) Delete(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.editor.DeletePage(userID, websiteID, pageSlug(c))
+	if err != nil {
+		pageError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"contentVersion": version})
+}
+
+// Reorder changes the order of the pages in the navigation menu
+func (h *PageHandler) Reorder(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	var req ReorderPagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	version, err := h.editor.ReorderPages(userID, websiteID, req.Order)
+	if err != nil {
+		pageError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"contentVersion": version})
+}
+
+// pageParams reads the user and the website ID, writing the error response
+// when either is missing
+func pageParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return uuid.Nil, uuid.Nil, false
+	}
+	websiteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid website ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID.(uuid.UUID), websiteID, true
+}
+
+// pageSlug is the slug in the path, e.g. blog/opening-day
+func pageSlug(c *gin.Context) string {
+	return strings.Trim(c.Param("slug"), "/")
+}
+
+// load reads one of the user's websites and its content
+func (h *PageHandler) load(c *gin.Context) (*models.Website, *models.SiteContent, bool) {
+	return loadContent(c, h.db)
+}
+
+// loadContent reads the website in the path, if it is the user's, and its
+// content, writing the error response when it cannot
+func loadContent(c *gin.Context, db *database.Database) (*models.Website, *models.SiteContent, bool) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	var site models.Website
+	if err := db.DB.Where("id = ? AND user_id = ?", websiteID, userID).First(&site).Error; err != nil {
+		utils.NotFound(c, "Website not found")
+		return nil, nil, false
+	}
+	content, err := models.ParseContent(site.GeneratedContent)
+	if err != nil {
+		logrus.WithError(err).WithField("website_id", websiteID).Error("Failed to read website content")
+		utils.InternalError(c)
+		return nil, nil, false
+	}
+	return &site, content, true
+}
+
+// pageError writes the response for a failed page edit
+func pageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, editor.ErrWebsiteNotFound):
+		utils.NotFound(c, "Website not found")
+	case errors.Is(err, editor.ErrPageNotFound):
+		utils.NotFound(c, "Page not found")
+	case errors.Is(err, editor.ErrInvalidEdit):
+		utils.ValidationError(c, err.Error())
+	case errors.Is(err, editor.ErrConflict):
+		utils.Conflict(c, err.Error())
+	default:
+		logrus.WithError(err).Error("Page edit failed")
+		utils.InternalError(c)
+	}
+}
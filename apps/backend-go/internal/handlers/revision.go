@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend-go/internal/services/revision"
+	"backend-go/internal/utils"
+	"backend-go/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// restoredFields are the website fields a restore may change
+var restoredFields = []string{"config", "description", "designTokens", "generatedContent", "title"}
+
+type RevisionHandler struct {
+	revisions *revision.Service
+	wsManager *websocket.Manager
+	validate  *validator.Validate
+}
+
+func NewRevisionHandler(revisions *revision.Service, wsManager *websocket.Manager) *RevisionHandler {
+	return &RevisionHandler{
+		revisions: revisions,
+		wsManager: wsManager,
+		validate:  validator.New(),
+	}
+}
+
+type NameRevisionRequest struct {
+	// Name labels the revision as a snapshot; empty removes the label
+	Name string `json:"name" validate:"max=100"`
+}
+
+// List returns a website's revisions, newest first. ?named=true lists only
+// the named snapshots.
+func (h *RevisionHandler) List(c *gin.Context) {
+	userID, websiteID, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := pagination(c)
+	revisions, total, err := h.revisions.List(userID, websiteID, c.Query("named") == "true", limit, offset)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	responses := make([]map[string]interface{}, len(revisions))
+	for i, r := range revisions {
+		responses[i] = r.Response()
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"revisions": responses,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// Get returns one revision with its content
+func (h *RevisionHandler) Get(c *gin.Context) {
+	userID, websiteID, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	r, err := h.revisions.Get(userID, websiteID, version)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	response := r.Response()
+	response["description"] = r.Description
+	response["config"] = r.Config
+	response["designTokens"] = r.DesignTokens
+	response["generatedContent"] = r.GeneratedContent
+	utils.JSONSuccess(c, http.StatusOK, response)
+}
+
+// Compare returns the structural changes from revision ?from= to ?to=
+func (h *RevisionHandler) Compare(c *gin.Context) {
+	userID, websiteID, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	from, ok := parseVersion(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := parseVersion(c, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	changes, err := h.revisions.Compare(userID, websiteID, from, to)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"from":    from,
+		"to":      to,
+		"changes": changes,
+	})
+}
+
+// Restore brings the website back to a revision, as a new revision
+func (h *RevisionHandler) Restore(c *gin.Context) {
+	userID, websiteID, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	website, err := h.revisions.Restore(userID, websiteID, version)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	h.wsManager.NotifyContentChanged(websiteID, userID, restoredFields, website.ContentVersion)
+	utils.JSONSuccess(c, http.StatusOK, website.Response())
+}
+
+// Name labels a revision as a named snapshot, which retention never prunes
+func (h *RevisionHandler) Name(c *gin.Context) {
+	userID, websiteID, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	var req NameRevisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	r, err := h.revisions.Name(userID, websiteID, version, req.Name)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, r.Response())
+}
+
+// revisionParams reads the user and the website ID of a revision request,
+// replying with an error when they are missing or invalid
+func revisionParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	websiteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid website ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID.(uuid.UUID), websiteID, true
+}
+
+func parseVersion(c *gin.Context, value string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		utils.BadRequest(c, "Invalid revision version")
+		return 0, false
+	}
+	return version, true
+}
+
+// revisionError replies to a revision service error
+func revisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, revision.ErrWebsiteNotFound):
+		utils.NotFound(c, "Website not found")
+	case errors.Is(err, revision.ErrRevisionNotFound):
+		utils.NotFound(c, "Revision not found")
+	default:
+		logrus.WithError(err).Error("Revision request failed")
+		utils.InternalError(c)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend-go/internal/database"
+	"backend-go/internal/services/seo"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SEOHandler struct {
+	db *database.Database
+}
+
+func NewSEOHandler(db *database.Database) *SEOHandler {
+	return &SEOHandler{db: db}
+}
+
+// Audit scores the search metadata of a website and lists the issues found
+func (h *SEOHandler) Audit(c *gin.Context) {
+	site, content, ok := loadContent(c, h.db)
+	if !ok {
+		return
+	}
+
+	report := seo.Audit(site, content)
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"score":          report.Score,
+		"pages":          report.Pages,
+		"issues":         report.Issues,
+		"url":            siteURL(*site),
+		"contentVersion": site.ContentVersion,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend-go/internal/services/catalog"
+	"backend-go/internal/services/website"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TemplateHandler struct {
+	catalog   *catalog.Catalog
+	generator *website.Generator
+}
+
+func NewTemplateHandler(templates *catalog.Catalog, generator *website.Generator) *TemplateHandler {
+	return &TemplateHandler{catalog: templates, generator: generator}
+}
+
+// List returns the template catalog. For signed-in users each template says
+// whether their subscription includes it; anonymous visitors see the free
+// templates as available.
+func (h *TemplateHandler) List(c *gin.Context) {
+	tier := "free"
+	if userID, exists := c.Get("userId"); exists {
+		tier = h.generator.Tier(userID.(uuid.UUID))
+	}
+
+	templates := make([]gin.H, 0, len(h.catalog.List()))
+	for _, t := range h.catalog.List() {
+		templates = append(templates, gin.H{
+			"id":           t.ID,
+			"name":         t.Name,
+			"description":  t.Description,
+			"tier":         t.Tier,
+			"available":    t.Allows(tier),
+			"sections":     t.Sections,
+			"designTokens": t.DesignTokens,
+			"guidance":     t.Guidance,
+			"thumbnailUrl": "/api/templates/" + t.ID + "/thumbnail",
+		})
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"templates": templates,
+		"tier":      tier,
+	})
+}
+
+// Thumbnail serves a template's SVG preview
+func (h *TemplateHandler) Thumbnail(c *gin.Context) {
+	t, ok := h.catalog.Get(c.Param("id"))
+	if !ok {
+		utils.NotFound(c, "Template not found")
+		return
+	}
+
+	// The thumbnails are static SVGs; the policy keeps any script in one
+	// from running if the image is opened directly
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/svg+xml", t.Thumbnail())
+}
+
+// templateError writes the response for an error from catalog.Check and
+// reports whether err was one
+func templateError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, catalog.ErrUnknownTemplate):
+		utils.ValidationError(c, err.Error())
+	case errors.Is(err, catalog.ErrTemplateNotAllowed):
+		utils.Forbidden(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
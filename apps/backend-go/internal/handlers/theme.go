@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/editor"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// maxLogoSize bounds an uploaded logo, in bytes
+const maxLogoSize = 2 << 20
+
+type ThemeHandler struct {
+	editor *editor.Editor
+}
+
+func NewThemeHandler(siteEditor *editor.Editor) *ThemeHandler {
+	return &ThemeHandler{editor: siteEditor}
+}
+
+type ThemeRequest struct {
+	// Color is the seed color, e.g. #3B82F6
+	Color string `json:"color"`
+}
+
+// Apply derives an accessible color palette from a seed color or a logo and
+// saves it as the website's color tokens. It takes JSON with a color, or a
+// multipart form with a logo file (PNG, JPEG or GIF) and an optional color
+// that takes precedence over the logo's own.
+func (h *ThemeHandler) Apply(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	websiteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid website ID")
+		return
+	}
+
+	var seeds []design.RGB
+	var color, source string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxLogoSize+1<<16)
+		if err := c.Request.ParseMultipartForm(maxLogoSize); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				utils.JSONError(c, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "The logo may be at most 2 MB")
+			} else {
+				utils.ValidationError(c, "Invalid multipart form")
+			}
+			return
+		}
+		color = c.PostForm("color")
+		if len(c.Request.MultipartForm.File["logo"]) > 0 {
+			logoColors, ok := h.logoColors(c)
+			if !ok {
+				return
+			}
+			seeds, source = logoColors, "logo"
+		}
+	} else {
+		var req ThemeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, "Invalid request body")
+			return
+		}
+		color = req.Color
+	}
+
+	if color = strings.TrimSpace(color); color != "" {
+		seed, err := design.ParseHex(color)
+		if err != nil {
+			utils.ValidationError(c, "color must be a hex color such as #3B82F6")
+			return
+		}
+		seeds, source = append([]design.RGB{seed}, seeds...), "color "+seed.Hex()
+	}
+	if len(seeds) == 0 {
+		utils.ValidationError(c, "Send a color or a logo")
+		return
+	}
+
+	var second *design.RGB
+	if len(seeds) > 1 {
+		second = &seeds[1]
+	}
+	palette := design.NewPalette(seeds[0], second)
+
+	version, err := h.editor.SetColors(userID.(uuid.UUID), websiteID, palette.Colors, "Generated theme from "+source)
+	if err != nil {
+		switch {
+		case errors.Is(err, editor.ErrWebsiteNotFound):
+			utils.NotFound(c, "Website not found")
+		case errors.Is(err, editor.ErrConflict):
+			utils.Conflict(c, err.Error())
+		default:
+			logrus.WithError(err).Error("Failed to save theme")
+			utils.InternalError(c)
+		}
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"seed":           seeds[0].Hex(),
+		"colors":         palette.Colors,
+		"checks":         palette.Checks,
+		"contentVersion": version,
+	})
+}
+
+// logoColors reads the uploaded logo and extracts its brand colors. It
+// writes the error response itself and reports whether it succeeded.
+func (h *ThemeHandler) logoColors(c *gin.Context) ([]design.RGB, bool) {
+	header, err := c.FormFile("logo")
+	if err != nil {
+		utils.ValidationError(c, "Invalid logo upload")
+		return nil, false
+	}
+	if header.Size > maxLogoSize {
+		utils.JSONError(c, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "The logo may be at most 2 MB")
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		utils.ValidationError(c, "Invalid logo upload")
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxLogoSize))
+	if err != nil {
+		utils.ValidationError(c, "Invalid logo upload")
+		return nil, false
+	}
+
+	img, err := design.DecodeLogo(bytes.NewReader(data))
+	if err != nil {
+		utils.ValidationError(c, err.Error())
+		return nil, false
+	}
+	colors := design.ExtractColors(img, 2)
+	if len(colors) == 0 {
+		utils.ValidationError(c, "The logo has no visible colors")
+		return nil, false
+	}
+	return colors, true
+}
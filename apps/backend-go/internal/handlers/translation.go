@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend-go/internal/services/editor"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type TranslationHandler struct {
+	editor *editor.Editor
+}
+
+func NewTranslationHandler(siteEditor *editor.Editor) *TranslationHandler {
+	return &TranslationHandler{editor: siteEditor}
+}
+
+type TranslateRequest struct {
+	Locales []string `json:"locales" binding:"required,min=1"`
+}
+
+type SetLocaleRequest struct {
+	Locale string `json:"locale" binding:"required"`
+}
+
+// Translate translates the website into the requested locales with AI
+func (h *TranslationHandler) Translate(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	var req TranslateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	result, err := h.editor.Translate(c.Request.Context(), userID, websiteID, req.Locales)
+	if err != nil {
+		translationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"locales":        result.Locales,
+		"contentVersion": result.Version,
+		"tokensUsed":     result.TokensUsed,
+	})
+}
+
+// SetLocale declares the language the website's content is written in
+func (h *TranslationHandler) SetLocale(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	var req SetLocaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	version, err := h.editor.SetDefaultLocale(userID, websiteID, req.Locale)
+	if err != nil {
+		translationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"contentVersion": version})
+}
+
+func (h *TranslationHandler) Delete(c *gin.Context) {
+	userID, websiteID, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.editor.DeleteTranslation(userID, websiteID, c.Param("locale"))
+	if err != nil {
+		translationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"contentVersion": version})
+}
+
+// translationError writes the response for a failed translation or locale
+// edit
+func translationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, editor.ErrWebsiteNotFound):
+		utils.NotFound(c, "Website not found")
+	case errors.Is(err, editor.ErrTranslationNotFound):
+		utils.NotFound(c, "Translation not found")
+	case errors.Is(err, editor.ErrInvalidEdit):
+		utils.ValidationError(c, err.Error())
+	case errors.Is(err, editor.ErrInsufficientTokens):
+		utils.InsufficientTokens(c)
+	case errors.Is(err, editor.ErrConflict):
+		utils.Conflict(c, err.Error())
+	case errors.Is(err, editor.ErrGenerationFailed):
+		utils.JSONError(c, http.StatusBadGateway, "GENERATION_FAILED", err.Error())
+	default:
+		logrus.WithError(err).Error("Translation failed")
+		utils.InternalError(c)
+	}
+}
@@ -1,33 +1,50 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"backend-go/internal/database"
 	"backend-go/internal/models"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/editor"
+	"backend-go/internal/services/render"
+	"backend-go/internal/services/revision"
 	"backend-go/internal/services/website"
 	"backend-go/internal/utils"
+	"backend-go/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WebsiteHandler struct {
 	db        *database.Database
 	generator *website.Generator
+	editor    *editor.Editor
+	revisions *revision.Service
+	renderer  *render.Renderer
+	wsManager *websocket.Manager
 	validate  *validator.Validate
 }
 
-func NewWebsiteHandler(db *database.Database, generator *website.Generator) *WebsiteHandler {
+func NewWebsiteHandler(db *database.Database, generator *website.Generator, siteEditor *editor.Editor, revisions *revision.Service, renderer *render.Renderer, wsManager *websocket.Manager) *WebsiteHandler {
 	return &WebsiteHandler{
 		db:        db,
 		generator: generator,
+		editor:    siteEditor,
+		revisions: revisions,
+		renderer:  renderer,
+		wsManager: wsManager,
 		validate:  validator.New(),
 	}
 }
@@ -40,6 +57,17 @@ type CreateWebsiteRequest struct {
 	Config      datatypes.JSON  `json:"config"`
 }
 
+// websiteFieldNames maps updatable columns to their JSON field names
+var websiteFieldNames = map[string]string{
+	"title":         "title",
+	"description":   "description",
+	"custom_domain": "customDomain",
+	"status":        "status",
+	"published_at":  "publishedAt",
+	"config":        "config",
+	"design_tokens": "designTokens",
+}
+
 type UpdateWebsiteRequest struct {
 	Title       string         `json:"title"`
 	Description string         `json:"description"`
@@ -115,9 +143,19 @@ func (h *WebsiteHandler) Create(c *gin.Context) {
 		return
 	}
 
+	template, err := h.generator.Template(userID.(uuid.UUID), req.TemplateID)
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+
 	// Normalize subdomain
 	subdomain := strings.ToLower(strings.TrimSpace(req.Subdomain))
 	subdomain = strings.ReplaceAll(subdomain, " ", "-")
+	if !models.ValidSubdomain(subdomain) {
+		utils.ValidationError(c, "Subdomain must be 1 to 63 lowercase letters, digits or hyphens, and cannot start or end with a hyphen")
+		return
+	}
 
 	// Check subdomain availability
 	var existing models.Website
@@ -140,7 +178,23 @@ func (h *WebsiteHandler) Create(c *gin.Context) {
 		website.Config = datatypes.JSON(`{}`)
 	}
 
-	if err := h.db.DB.Create(website).Error; err != nil {
+	// The site starts with the template's sections and design
+	if website.DesignTokens, err = template.Tokens().JSON(); err != nil {
+		utils.InternalError(c)
+		return
+	}
+	if website.GeneratedContent, err = template.Content(req.Title, req.Description).JSON(); err != nil {
+		utils.InternalError(c)
+		return
+	}
+
+	err = h.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(website).Error; err != nil {
+			return err
+		}
+		return h.revisions.Record(tx, nil, website, revision.ByUser(website.UserID, "Created website"))
+	})
+	if err != nil {
 		utils.InternalError(c)
 		return
 	}
@@ -194,18 +248,131 @@ func (h *WebsiteHandler) Update(c *gin.Context) {
 		updates["config"] = req.Config
 	}
 	if req.DesignTokens != nil {
-		updates["design_tokens"] = req.DesignTokens
+		tokens, err := models.ParseDesignTokens(req.DesignTokens)
+		if err != nil {
+			utils.ValidationError(c, "designTokens must be an object of colors, typography, spacing and borderRadius")
+			return
+		}
+		if err := design.Validate(tokens); err != nil {
+			utils.ValidationError(c, "designTokens: "+err.Error())
+			return
+		}
+		data, err := tokens.JSON()
+		if err != nil {
+			utils.InternalError(c)
+			return
+		}
+		updates["design_tokens"] = data
+	}
+
+	fields := make([]string, 0, len(updates))
+	for column := range updates {
+		fields = append(fields, websiteFieldNames[column])
+	}
+	sort.Strings(fields)
+	if len(updates) > 0 {
+		updates["content_version"] = gorm.Expr("content_version + 1")
+
+		// Update, reload and record the revision together. The revision
+		// starts from the row as locked here, so a concurrent save is not
+		// counted as part of this one.
+		err := h.db.DB.Transaction(func(tx *gorm.DB) error {
+			var before models.Website
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND user_id = ?", websiteID, userID.(uuid.UUID)).
+				First(&before).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&website).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := tx.First(&website, "id = ?", websiteID).Error; err != nil {
+				return err
+			}
+			reason := "Updated " + strings.Join(fields, ", ")
+			return h.revisions.Record(tx, &before, &website, revision.ByUser(userID.(uuid.UUID), reason))
+		})
+		if err != nil {
+			utils.InternalError(c)
+			return
+		}
 	}
 
-	if err := h.db.DB.Model(&website).Updates(updates).Error; err != nil {
-		utils.InternalError(c)
+	if len(fields) > 0 {
+		h.wsManager.NotifyContentChanged(websiteID, userID.(uuid.UUID), fields, website.ContentVersion)
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, website.Response())
+}
+
+type RegenerateSectionRequest struct {
+	Instructions string `json:"instructions" validate:"max=1000"`
+}
+
+// RegenerateSection rewrites one section of the website with AI, optionally
+// following instructions, without touching the rest of the site
+func (h *WebsiteHandler) RegenerateSection(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	websiteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid website ID")
 		return
 	}
 
-	// Reload
-	h.db.DB.First(&website, "id = ?", websiteID)
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		utils.BadRequest(c, "Invalid section index")
+		return
+	}
 
-	utils.JSONSuccess(c, http.StatusOK, website.Response())
+	// The body is optional
+	var req RegenerateSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ValidationError(c, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	// The section is on the home page unless ?page= names another
+	result, err := h.editor.RegenerateSection(c.Request.Context(), userID.(uuid.UUID), websiteID, c.Query("page"), index, req.Instructions)
+	if err != nil {
+		switch {
+		case errors.Is(err, editor.ErrWebsiteNotFound):
+			utils.NotFound(c, "Website not found")
+		case errors.Is(err, editor.ErrPageNotFound):
+			utils.NotFound(c, "Page not found")
+		case errors.Is(err, editor.ErrSectionNotFound):
+			utils.NotFound(c, "Section not found")
+		case errors.Is(err, editor.ErrInsufficientTokens):
+			utils.InsufficientTokens(c)
+		case errors.Is(err, editor.ErrInvalidEdit):
+			utils.ValidationError(c, err.Error())
+		case errors.Is(err, editor.ErrConflict):
+			utils.Conflict(c, err.Error())
+		case errors.Is(err, editor.ErrGenerationFailed):
+			utils.JSONError(c, http.StatusBadGateway, "GENERATION_FAILED", err.Error())
+		default:
+			logrus.WithError(err).Error("Section regeneration failed")
+			utils.InternalError(c)
+		}
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"index":          index,
+		"section":        result.Section,
+		"contentVersion": result.Version,
+		"tokensUsed":     result.TokensUsed,
+	})
 }
 
 func (h *WebsiteHandler) Delete(c *gin.Context) {
@@ -228,7 +395,13 @@ func (h *WebsiteHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.DB.Delete(&website).Error; err != nil {
+	err = h.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := h.revisions.DeleteAll(tx, website.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&website).Error
+	})
+	if err != nil {
 		utils.InternalError(c)
 		return
 	}
@@ -236,7 +409,10 @@ func (h *WebsiteHandler) Delete(c *gin.Context) {
 	utils.JSONSuccess(c, http.StatusOK, gin.H{"deleted": true})
 }
 
-// Preview serves the generated website as HTML for preview
+// Preview serves a page of the generated website as HTML for preview:
+// /preview/:id/ is the home page, /preview/:id/about/ the about page and
+// /preview/:id/en/about/ its English translation. Pages link to each other
+// relatively, so paths end in a slash.
 func (h *WebsiteHandler) Preview(c *gin.Context) {
 	id := c.Param("id")
 	websiteID, err := uuid.Parse(id)
@@ -245,145 +421,33 @@ func (h *WebsiteHandler) Preview(c *gin.Context) {
 		return
 	}
 
+	path := c.Param("page")
+	if !strings.HasSuffix(path, "/") && !strings.HasSuffix(path, "/index.html") {
+		c.Redirect(http.StatusFound, c.Request.URL.Path+"/")
+		return
+	}
+	path = strings.Trim(strings.TrimSuffix(path, "index.html"), "/")
+
 	var website models.Website
 	if err := h.db.DB.First(&website, "id = ?", websiteID).Error; err != nil {
 		c.String(http.StatusNotFound, "Website not found")
 		return
 	}
 
-	// Generate HTML from the website content
-	html := h.generatePreviewHTML(&website)
-	c.Header("Content-Type", "text/html; charset=utf-8")
-	c.String(http.StatusOK, html)
-}
-
-func (h *WebsiteHandler) generatePreviewHTML(website *models.Website) string {
-	title := website.Title
-	if title == "" {
-		title = "My Website"
-	}
-
-	// Parse generated content if available
-	var content map[string]interface{}
-	if website.GeneratedContent != nil {
-		if err := json.Unmarshal(website.GeneratedContent, &content); err != nil {
-			content = map[string]interface{}{}
-		}
+	page, err := h.renderer.RenderPreview(&website, siteURL(website), path)
+	if errors.Is(err, render.ErrPageNotFound) {
+		c.String(http.StatusNotFound, "Page not found")
+		return
 	}
-
-	description, _ := content["description"].(string)
-	sections, _ := content["sections"].([]interface{})
-
-	// Build sections HTML
-	sectionsHTML := ""
-	for _, section := range sections {
-		if secMap, ok := section.(map[string]interface{}); ok {
-			secType, _ := secMap["type"].(string)
-			secContent, _ := secMap["content"].(map[string]interface{})
-			sectionsHTML += h.renderSection(secType, secContent)
-		}
+	if err != nil {
+		logrus.WithError(err).WithField("website_id", websiteID).Error("Failed to render preview")
+		c.String(http.StatusInternalServerError, "Failed to render website")
+		return
 	}
 
-	// Default content if no sections
-	if sectionsHTML == "" {
-		sectionsHTML = fmt.Sprintf(`
-			<section style="padding: 4rem 2rem; text-align: center; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%); color: white;">
-				<h1 style="font-size: 3rem; margin-bottom: 1rem;">%s</h1>
-				<p style="font-size: 1.25rem; opacity: 0.9;">%s</p>
-			</section>
-			<section style="padding: 4rem 2rem; max-width: 1200px; margin: 0 auto;">
-				<h2 style="text-align: center; margin-bottom: 2rem;">Welcome</h2>
-				<p style="text-align: center; color: #666;">Your website is being generated. Check back soon!</p>
-			</section>
-		`, title, description)
-	}
-
-	return fmt.Sprintf(`<!DOCTYPE html>
-<html lang="en">
-<head>
-	<meta charset="UTF-8">
-	<meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<title>%s</title>
-	<style>
-		* { margin: 0; padding: 0; box-sizing: border-box; }
-		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; }
-		.container { max-width: 1200px; margin: 0 auto; padding: 0 2rem; }
-		section { padding: 4rem 0; }
-	</style>
-</head>
-<body>
-	%s
-	<footer style="background: #1a1a1a; color: white; padding: 2rem; text-align: center;">
-		<p>&copy; 2026 %s. All rights reserved.</p>
-	</footer>
-</body>
-</html>`, title, sectionsHTML, title)
+	// The page carries no scripts; the policy keeps it that way even if
+	// generated content slips something past the templates
+	c.Header("Content-Security-Policy", page.Policy)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.HTML)
 }
-
-func (h *WebsiteHandler) renderSection(secType string, content map[string]interface{}) string {
-	switch secType {
-	case "hero":
-		title, _ := content["title"].(string)
-		subtitle, _ := content["subtitle"].(string)
-		return fmt.Sprintf(`
-			<section style="padding: 6rem 2rem; text-align: center; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%); color: white;">
-				<div class="container">
-					<h1 style="font-size: 3.5rem; margin-bottom: 1rem; font-weight: 700;">%s</h1>
-					<p style="font-size: 1.5rem; opacity: 0.9; max-width: 600px; margin: 0 auto;">%s</p>
-				</div>
-			</section>
-		`, title, subtitle)
-	case "about":
-		title, _ := content["title"].(string)
-		text, _ := content["text"].(string)
-		return fmt.Sprintf(`
-			<section style="padding: 4rem 2rem; background: #f8f9fa;">
-				<div class="container">
-					<h2 style="text-align: center; margin-bottom: 2rem; font-size: 2.5rem;">%s</h2>
-					<p style="max-width: 800px; margin: 0 auto; text-align: center; font-size: 1.1rem; color: #555;">%s</p>
-				</div>
-			</section>
-		`, title, text)
-	case "services":
-		title, _ := content["title"].(string)
-		items, _ := content["items"].([]interface{})
-		itemsHTML := ""
-		for _, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				itemTitle, _ := itemMap["title"].(string)
-				itemDesc, _ := itemMap["description"].(string)
-				itemsHTML += fmt.Sprintf(`
-					<div style="padding: 2rem; background: white; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1);">
-						<h3 style="margin-bottom: 1rem; color: #667eea;">%s</h3>
-						<p style="color: #666;">%s</p>
-					</div>
-				`, itemTitle, itemDesc)
-			}
-		}
-		return fmt.Sprintf(`
-			<section style="padding: 4rem 2rem;">
-				<div class="container">
-					<h2 style="text-align: center; margin-bottom: 3rem; font-size: 2.5rem;">%s</h2>
-					<div style="display: grid; grid-template-columns: repeat(auto-fit, minmax(300px, 1fr)); gap: 2rem;">
-						%s
-					</div>
-				</div>
-			</section>
-		`, title, itemsHTML)
-	case "contact":
-		title, _ := content["title"].(string)
-		email, _ := content["email"].(string)
-		phone, _ := content["phone"].(string)
-		return fmt.Sprintf(`
-			<section style="padding: 4rem 2rem; background: #f8f9fa;">
-				<div class="container" style="text-align: center;">
-					<h2 style="margin-bottom: 2rem; font-size: 2.5rem;">%s</h2>
-					<p style="margin-bottom: 1rem;">Email: %s</p>
-					<p>Phone: %s</p>
-				</div>
-			</section>
-		`, title, email, phone)
-	default:
-		return ""
-	}
-}
\ No newline at end of file
@@ -2,14 +2,16 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"backend-go/internal/database"
 	"backend-go/internal/models"
-	"backend-go/internal/services/ai"
+	"backend-go/internal/services/catalog"
+	"backend-go/internal/services/chat"
+	"backend-go/internal/services/ratelimit"
 	"backend-go/internal/utils"
 	"backend-go/internal/websocket"
 
@@ -20,42 +22,66 @@ import (
 
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
-	manager   *websocket.Manager
-	jwtUtil   *utils.JWTUtil
-	db        *database.Database
-	kimi      *ai.KimiClient
-	chatHistory map[string][]ai.Message // In-memory chat history per user (can be moved to Redis)
+	manager *websocket.Manager
+	tickets websocket.TicketStore
+	db      *database.Database
+	chat    *chat.Service
+	limiter *ratelimit.Manager
 }
 
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(manager *websocket.Manager, jwtUtil *utils.JWTUtil, db *database.Database, kimi *ai.KimiClient) *WebSocketHandler {
+func NewWebSocketHandler(manager *websocket.Manager, tickets websocket.TicketStore, db *database.Database, chatSvc *chat.Service, limiter *ratelimit.Manager) *WebSocketHandler {
 	return &WebSocketHandler{
-		manager:     manager,
-		jwtUtil:     jwtUtil,
-		db:          db,
-		kimi:        kimi,
-		chatHistory: make(map[string][]ai.Message),
+		manager: manager,
+		tickets: tickets,
+		db:      db,
+		chat:    chatSvc,
+		limiter: limiter,
 	}
 }
 
+// IssueTicket issues a short-lived, single-use ticket for opening a WebSocket.
+// The ticket is bound to the authenticated user and the request Origin.
+func (h *WebSocketHandler) IssueTicket(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	ticket, expiresAt, err := h.tickets.Issue(c.Request.Context(), userID.(uuid.UUID), c.GetHeader("Origin"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to issue WebSocket ticket")
+		utils.InternalError(c)
+		return
+	}
+
+	utils.JSONSuccess(c, http.StatusCreated, gin.H{
+		"ticket":    ticket,
+		"expiresAt": expiresAt,
+		"expiresIn": int(websocket.TicketTTL.Seconds()),
+	})
+}
+
 // HandleWebSocket handles WebSocket upgrade requests
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	// Get token from query parameter
-	token := c.Query("token")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
+	// Get ticket from query parameter
+	ticket := c.Query("ticket")
+	if ticket == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Ticket required"})
 		return
 	}
 
-	// Validate JWT token
-	claims, err := h.jwtUtil.ValidateToken(token)
+	// Redeem the single-use ticket
+	userID, err := h.tickets.Redeem(c.Request.Context(), ticket, c.GetHeader("Origin"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		logrus.WithError(err).Warn("WebSocket ticket rejected")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ticket"})
 		return
 	}
 
 	// Upgrade connection
-	h.manager.HandleWebSocket(c, claims.UserID, h.handleMessage)
+	h.manager.HandleWebSocket(c, userID, h.handleMessage)
 }
 
 // handleMessage processes incoming WebSocket messages
@@ -68,160 +94,301 @@ func (h *WebSocketHandler) handleMessage(client *websocket.Client, msg websocket
 
 	switch msg.Type {
 	case websocket.MessageTypeChatMessage:
-		h.handleChatMessage(client, msg)
+		go h.handleChatMessage(client, msg)
+	case websocket.MessageTypeChatCancel:
+		h.handleChatCancel(client, msg)
+	case websocket.MessageTypeChatRegenerate:
+		go h.handleChatRegenerate(client, msg)
 	case websocket.MessageTypeChatTyping:
 		h.handleTypingIndicator(client, msg)
 	case websocket.MessageTypeWebsiteJoin:
 		h.handleWebsiteJoin(client, msg)
 	case websocket.MessageTypeWebsiteLeave:
 		h.handleWebsiteLeave(client, msg)
-	case "ping":
-		// Handle ping - no response needed, the connection stays alive
-		logrus.Debug("Received ping from client")
 	default:
-		// Unknown message type - just log it, don't send error
-		logrus.WithField("type", msg.Type).Warn("Unknown message type received")
+		// The protocol knows the type but this handler does not serve it
+		h.sendError(client, msg, websocket.ErrorCodeUnknownType, fmt.Sprintf("Message type %q is not supported", msg.Type))
 	}
 }
 
-// handleChatMessage processes chat messages and streams AI responses
+// handleChatMessage processes chat messages and streams AI responses. It
+// runs in its own goroutine so the connection keeps reading while the
+// response streams, which is what lets chat:cancel arrive mid-stream.
 func (h *WebSocketHandler) handleChatMessage(client *websocket.Client, msg websocket.Message) {
 	logrus.WithField("content", msg.Content).Info("Handling chat message")
 
-	if msg.Content == "" {
-		h.sendError(client, "Message content is required")
+	if !h.allowChat(client, msg) {
 		return
 	}
 
-	userID := client.UserID.String()
-
-	// Initialize chat history for user if not exists
-	if _, ok := h.chatHistory[userID]; !ok {
-		h.chatHistory[userID] = []ai.Message{
-			{Role: "system", Content: "You are a helpful AI assistant for SiteSpark, an AI-powered website builder. Help users create and improve their websites."},
-		}
+	// The stream's context ends with the connection, so the upstream
+	// request is aborted when the client disconnects
+	stream, err := h.chat.Begin(client.Context(), client.UserID)
+	if err != nil {
+		h.sendChatError(client, msg, err)
+		return
 	}
 
-	// Add user message to history
-	h.chatHistory[userID] = append(h.chatHistory[userID], ai.Message{
-		Role:    "user",
-		Content: msg.Content,
-	})
-
-	// Limit history to last 20 messages to prevent context overflow
-	if len(h.chatHistory[userID]) > 20 {
-		h.chatHistory[userID] = h.chatHistory[userID][len(h.chatHistory[userID])-20:]
+	thread, err := h.chat.Thread(client.UserID, parseUUID(msg.ThreadID), parseUUID(msg.WebsiteID))
+	if err == nil {
+		err = h.chat.AddUserMessage(thread, msg.Content)
+	}
+	if err != nil {
+		h.chat.End(client.UserID, stream)
+		h.sendChatError(client, msg, err)
+		return
 	}
 
-	// Send acknowledgment that message was received
+	// Send acknowledgment that message was received, under the request's ID
+	// when the client set one. It names the thread so a client that did not
+	// pick one learns where the conversation continues.
+	ackID := msg.ID
+	if ackID == "" {
+		ackID = uuid.New().String()
+	}
 	ackMsg := websocket.Message{
 		Type:      websocket.MessageTypeChatMessage,
-		ID:        uuid.New().String(),
+		ID:        ackID,
 		Role:      "user",
 		Content:   msg.Content,
+		ThreadID:  thread.ID.String(),
 		Timestamp: time.Now(),
 	}
 	h.sendToClient(client, ackMsg)
 
-	// Stream AI response
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-	defer cancel()
+	if chat.Guiding(thread) {
+		h.guideResponse(client, msg, stream, thread)
+		return
+	}
+	h.streamResponse(client, msg, stream, thread)
+}
 
-	var fullResponse string
-	messageID := uuid.New().String()
+// handleChatCancel stops the response being generated for the user. A
+// response ID, when given, must match the ID of its chat:stream messages.
+func (h *WebSocketHandler) handleChatCancel(client *websocket.Client, msg websocket.Message) {
+	var responseID string
+	if payload, ok := msg.Payload.(*websocket.ChatCancelPayload); ok {
+		responseID = payload.ResponseID
+	}
 
-	err := h.kimi.ChatCompletionStream(ctx, h.chatHistory[userID], func(chunk string) {
-		fullResponse += chunk
+	stream := h.chat.Active(client.UserID)
+	if stream == nil || (responseID != "" && responseID != stream.ID) {
+		h.sendError(client, msg, websocket.ErrorCodeNotFound, "No response in progress")
+		return
+	}
+
+	stream.Stop(chat.TruncatedCancelled)
+}
+
+// handleChatRegenerate discards the last assistant response and streams a
+// new one for the same prompt, cancelling the current response if needed
+func (h *WebSocketHandler) handleChatRegenerate(client *websocket.Client, msg websocket.Message) {
+	if current := h.chat.Active(client.UserID); current != nil {
+		current.Stop(chat.TruncatedRegenerated)
+		<-current.Done()
+	}
+
+	if !h.allowChat(client, msg) {
+		return
+	}
+
+	stream, err := h.chat.Begin(client.Context(), client.UserID)
+	if err != nil {
+		h.sendChatError(client, msg, err)
+		return
+	}
+
+	thread, err := h.chat.Thread(client.UserID, parseUUID(msg.ThreadID), parseUUID(msg.WebsiteID))
+	if err == nil {
+		err = h.chat.PrepareRegenerate(thread)
+	}
+	if err != nil {
+		h.chat.End(client.UserID, stream)
+		h.sendChatError(client, msg, err)
+		return
+	}
+
+	h.streamResponse(client, msg, stream, thread)
+}
+
+// allowChat enforces the per-user chat quota before spending any AI budget
+func (h *WebSocketHandler) allowChat(client *websocket.Client, msg websocket.Message) bool {
+	result, limited := h.limiter.Check(context.Background(), ratelimit.GroupWSChat, h.limiter.TierFor(&client.UserID), "user:"+client.UserID.String())
+	if limited && !result.Allowed {
+		h.sendError(client, msg, websocket.ErrorCodeRateLimited, fmt.Sprintf("Rate limit exceeded, retry in %d seconds", int(result.ResetAfter.Seconds())+1))
+		return false
+	}
+	return true
+}
+
+// streamResponse streams the assistant's reply as chat:stream chunks and
+// finishes with a chat:message carrying the full (possibly truncated) text
+func (h *WebSocketHandler) streamResponse(client *websocket.Client, msg websocket.Message, stream *chat.Stream, thread *models.ChatThread) {
+	reply, err := h.chat.Respond(stream, thread, func(chunk string) {
 		streamMsg := websocket.Message{
 			Type:      websocket.MessageTypeChatStream,
-			ID:        messageID,
+			ID:        stream.ID,
 			Chunk:     chunk,
 			Timestamp: time.Now(),
 		}
 		h.sendToClient(client, streamMsg)
 	})
+	if err != nil {
+		h.sendChatError(client, msg, err)
+		return
+	}
+
+	h.sendToClient(client, replyMessage(reply))
+}
 
+// guideResponse answers a message in a guided thread with the next
+// question, or the generated website once the brief is complete
+func (h *WebSocketHandler) guideResponse(client *websocket.Client, msg websocket.Message, stream *chat.Stream, thread *models.ChatThread) {
+	reply, err := h.chat.Guide(stream, thread, msg.Content)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get AI streaming response")
-		// Send more detailed error to help debugging
-		h.sendError(client, fmt.Sprintf("Failed to get AI response: %v", err))
+		h.sendChatError(client, msg, err)
 		return
 	}
 
-	// Add assistant response to history
-	h.chatHistory[userID] = append(h.chatHistory[userID], ai.Message{
-		Role:    "assistant",
-		Content: fullResponse,
-	})
+	h.sendToClient(client, replyMessage(reply))
+}
 
-	// Send final message confirmation
+// replyMessage is the final chat:message of a response
+func replyMessage(reply *chat.Reply) websocket.Message {
 	finalMsg := websocket.Message{
 		Type:      websocket.MessageTypeChatMessage,
-		ID:        messageID,
+		ID:        reply.ID,
 		Role:      "assistant",
-		Content:   fullResponse,
+		Content:   reply.Content,
+		ThreadID:  reply.ThreadID.String(),
 		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"tokensUsed": reply.TokensUsed,
+		},
 	}
-	h.sendToClient(client, finalMsg)
+	if reply.Truncated != "" {
+		finalMsg.Metadata["truncated"] = true
+		finalMsg.Metadata["reason"] = reply.Truncated
+	}
+	if reply.Guide != nil {
+		finalMsg.Metadata["guide"] = reply.Guide.Response()
+	}
+	if reply.Website != nil {
+		finalMsg.WebsiteID = reply.Website.ID.String()
+		finalMsg.Metadata["website"] = reply.Website.Response()
+	}
+	if len(reply.Edits) > 0 {
+		finalMsg.Metadata["edits"] = reply.Edits
+	}
+	return finalMsg
+}
 
-	// Save chat message to database if user is authenticated
-	if msg.WebsiteID != "" {
-		websiteID, err := uuid.Parse(msg.WebsiteID)
-		if err == nil {
-			h.saveChatMessage(client.UserID, &websiteID, "user", msg.Content)
-			h.saveChatMessage(client.UserID, &websiteID, "assistant", fullResponse)
-		}
-	} else {
-		h.saveChatMessage(client.UserID, nil, "user", msg.Content)
-		h.saveChatMessage(client.UserID, nil, "assistant", fullResponse)
+// sendChatError reports a chat service error with a matching code
+func (h *WebSocketHandler) sendChatError(client *websocket.Client, msg websocket.Message, err error) {
+	code, message := chatErrorCode(err)
+	h.sendError(client, msg, code, message)
+}
+
+// chatErrorCode classifies a chat service error for the error event of a
+// WebSocket or SSE response. Unexpected errors are logged and reported
+// without their text, which may come from the AI provider.
+func chatErrorCode(err error) (websocket.ErrorCode, string) {
+	switch {
+	case errors.Is(err, chat.ErrBusy):
+		return websocket.ErrorCodeConflict, "A response is already being generated"
+	case errors.Is(err, chat.ErrInsufficientTokens):
+		return websocket.ErrorCodeInsufficientTokens, "Insufficient tokens"
+	case errors.Is(err, chat.ErrNothingToRegenerate):
+		return websocket.ErrorCodeNotFound, "Nothing to regenerate"
+	case errors.Is(err, chat.ErrThreadNotFound):
+		return websocket.ErrorCodeNotFound, "Thread not found"
+	case errors.Is(err, chat.ErrWebsiteNotFound):
+		return websocket.ErrorCodeNotFound, "Website not found or access denied"
+	case errors.Is(err, chat.ErrNotGuided), errors.Is(err, chat.ErrCannotGoBack):
+		return websocket.ErrorCodeConflict, err.Error()
+	case errors.Is(err, catalog.ErrUnknownTemplate):
+		return websocket.ErrorCodeNotFound, "Template not found"
+	case errors.Is(err, catalog.ErrTemplateNotAllowed):
+		return websocket.ErrorCodeConflict, "The chosen style needs a template your subscription does not include"
+	default:
+		logrus.WithError(err).Error("Chat request failed")
+		return websocket.ErrorCodeInternal, "Chat is unavailable, please retry"
+	}
+}
+
+// parseUUID returns the website or thread a chat message refers to, if any
+func parseUUID(value string) *uuid.UUID {
+	if value == "" {
+		return nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil
 	}
+	return &id
 }
 
-// handleTypingIndicator broadcasts typing status
+// handleTypingIndicator broadcasts typing status to the website rooms the
+// client has joined, or only to the given room when a website ID is set
 func (h *WebSocketHandler) handleTypingIndicator(client *websocket.Client, msg websocket.Message) {
-	// Broadcast typing indicator to all clients (or specific website room if implemented)
-	typingMsg := websocket.Message{
-		Type:      websocket.MessageTypeChatTyping,
-		UserID:    client.UserID.String(),
-		IsTyping:  msg.IsTyping,
-		Timestamp: time.Now(),
+	rooms := h.manager.ClientRooms(client)
+	if msg.WebsiteID != "" {
+		websiteID, err := uuid.Parse(msg.WebsiteID)
+		if err != nil || !h.manager.IsInRoom(client, websiteID) {
+			h.sendError(client, msg, websocket.ErrorCodeNotFound, "Join the website before sending typing indicators")
+			return
+		}
+		rooms = []uuid.UUID{websiteID}
 	}
 
-	// For now, just echo back to sender (in a multi-user scenario, broadcast to room)
-	h.sendToClient(client, typingMsg)
+	for _, websiteID := range rooms {
+		typingMsg := websocket.Message{
+			Type:      websocket.MessageTypeChatTyping,
+			UserID:    client.UserID.String(),
+			WebsiteID: websiteID.String(),
+			IsTyping:  msg.IsTyping,
+			Timestamp: time.Now(),
+		}
+		h.manager.BroadcastToRoomExcept(websiteID, client, typingMsg)
+	}
 }
 
 // handleWebsiteJoin handles joining a website chat room
 func (h *WebSocketHandler) handleWebsiteJoin(client *websocket.Client, msg websocket.Message) {
-	if msg.WebsiteID == "" {
-		h.sendError(client, "Website ID is required")
-		return
-	}
-
 	// Verify user has access to this website
 	websiteID, err := uuid.Parse(msg.WebsiteID)
 	if err != nil {
-		h.sendError(client, "Invalid website ID")
+		h.sendError(client, msg, websocket.ErrorCodeInvalidPayload, "Invalid website ID")
 		return
 	}
 
 	var website models.Website
 	if err := h.db.GetDB().Where("id = ? AND user_id = ?", websiteID, client.UserID).First(&website).Error; err != nil {
-		h.sendError(client, "Website not found or access denied")
+		h.sendError(client, msg, websocket.ErrorCodeNotFound, "Website not found or access denied")
 		return
 	}
 
-	// Send confirmation
+	// Send confirmation before the room's presence update
 	joinMsg := websocket.Message{
 		Type:      websocket.MessageTypeWebsiteJoin,
 		WebsiteID: msg.WebsiteID,
 		Timestamp: time.Now(),
 	}
 	h.sendToClient(client, joinMsg)
+
+	h.manager.JoinRoom(client, websiteID)
 }
 
 // handleWebsiteLeave handles leaving a website chat room
 func (h *WebSocketHandler) handleWebsiteLeave(client *websocket.Client, msg websocket.Message) {
+	websiteID, err := uuid.Parse(msg.WebsiteID)
+	if err != nil {
+		h.sendError(client, msg, websocket.ErrorCodeInvalidPayload, "Invalid website ID")
+		return
+	}
+
+	h.manager.LeaveRoom(client, websiteID)
+
 	leaveMsg := websocket.Message{
 		Type:      websocket.MessageTypeWebsiteLeave,
 		WebsiteID: msg.WebsiteID,
@@ -233,37 +400,12 @@ func (h *WebSocketHandler) handleWebsiteLeave(client *websocket.Client, msg webs
 // sendToClient sends a message to a specific client
 func (h *WebSocketHandler) sendToClient(client *websocket.Client, msg websocket.Message) {
 	logrus.WithField("type", msg.Type).Info("Sending message to client")
-	data, err := json.Marshal(msg)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal message")
-		return
-	}
-	client.SendMessage(data)
-}
-
-// sendError sends an error message to a client
-func (h *WebSocketHandler) sendError(client *websocket.Client, errorMsg string) {
-	msg := websocket.Message{
-		Type:      websocket.MessageTypeError,
-		Error:     errorMsg,
-		Timestamp: time.Now(),
+	if err := client.Deliver(msg); err != nil {
+		logrus.WithError(err).WithField("client", client.ID).Warn("Failed to send message")
 	}
-	h.sendToClient(client, msg)
 }
 
-// saveChatMessage saves a chat message to the database
-func (h *WebSocketHandler) saveChatMessage(userID uuid.UUID, websiteID *uuid.UUID, role, content string) {
-	// This can be made async to not block the WebSocket
-	go func() {
-		chatMsg := models.ChatMessage{
-			UserID:    userID,
-			WebsiteID: websiteID,
-			Role:      role,
-			Content:   content,
-		}
-
-		if err := h.db.GetDB().Create(&chatMsg).Error; err != nil {
-			logrus.WithError(err).Error("Failed to save chat message")
-		}
-	}()
+// sendError replies to a request with a coded error carrying its ID
+func (h *WebSocketHandler) sendError(client *websocket.Client, req websocket.Message, code websocket.ErrorCode, errorMsg string) {
+	h.sendToClient(client, websocket.ErrorMessage(req.ID, code, errorMsg))
 }
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+
+	"backend-go/internal/services/ratelimit"
+	"backend-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RateLimitMiddleware enforces the quota of a route group. It must run after
+// the auth middleware so authenticated requests are keyed by user and tier.
+func RateLimitMiddleware(limiter *ratelimit.Manager, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, userID := rateLimitSubject(c)
+		tier := limiter.TierFor(userID)
+
+		result, limited := limiter.Check(c.Request.Context(), group, tier, subject)
+		if !limited {
+			c.Next()
+			return
+		}
+
+		resetSeconds := int(math.Ceil(result.ResetAfter.Seconds()))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(resetSeconds))
+		c.Header("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+strconv.Itoa(int(limiter.Window().Seconds())))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(resetSeconds))
+			utils.TooManyRequests(c, "Rate limit exceeded, please retry later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitSubject identifies the caller by user ID or client IP. Headers
+// the caller controls, such as an API key or an untrusted X-Forwarded-For,
+// are ignored: a caller could send a new one with every request to get a
+// fresh quota.
+func rateLimitSubject(c *gin.Context) (string, *uuid.UUID) {
+	if value, exists := c.Get("userId"); exists {
+		if userID, ok := value.(uuid.UUID); ok {
+			return "user:" + userID.String(), &userID
+		}
+	}
+
+	return "ip:" + c.ClientIP(), nil
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend-go/internal/config"
+	"backend-go/internal/services/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitedRouter serves GET / with an anonymous quota of two requests
+func limitedRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	limiter := ratelimit.NewManager(ratelimit.NewMemoryStore(), &config.RateLimitConfig{
+		Enabled: true,
+		Window:  time.Minute,
+		Groups:  map[string]map[string]int{ratelimit.GroupDefault: {ratelimit.TierAnonymous: 2}},
+	}, nil)
+
+	r := gin.New()
+	require.NoError(t, r.SetTrustedProxies(trustedProxies))
+	r.GET("/", RateLimitMiddleware(limiter, ratelimit.GroupDefault), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+// get sends a request from peer claiming to forward forwardedFor
+func get(r *gin.Engine, peer, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = peer + ":40000"
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimitMiddleware_IgnoresUntrustedForwardedFor(t *testing.T) {
+	r := limitedRouter(t, nil)
+
+	// Each request claims another client, but all count against the peer
+	assert.Equal(t, http.StatusOK, get(r, "203.0.113.7", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, get(r, "203.0.113.7", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, get(r, "203.0.113.7", "198.51.100.3"))
+
+	assert.Equal(t, http.StatusOK, get(r, "203.0.113.8", "198.51.100.3"), "other peers have their own quota")
+}
+
+func TestRateLimitMiddleware_TrustedProxyForwardsClientIP(t *testing.T) {
+	r := limitedRouter(t, []string{"10.0.0.0/8"})
+
+	assert.Equal(t, http.StatusOK, get(r, "10.0.0.2", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, get(r, "10.0.0.2", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, get(r, "10.0.0.2", "198.51.100.1"))
+
+	assert.Equal(t, http.StatusOK, get(r, "10.0.0.2", "198.51.100.2"), "clients behind the proxy have their own quota")
+}
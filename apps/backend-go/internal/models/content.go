@@ -0,0 +1,194 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// SiteContent is the structure of Website.GeneratedContent. Keys it does
+// not model are kept as they are, so editing never loses generated data.
+// Sections and SEO belong to the home page; Pages are the site's other
+// pages, in navigation order. Locale is the language of the content, and
+// Translations hold the site in other locales.
+type SiteContent struct {
+	Title        string                  `json:"title"`
+	Description  string                  `json:"description"`
+	Sections     []Section               `json:"sections"`
+	SEO          *SEO                    `json:"seo,omitempty"`
+	Pages        []Page                  `json:"pages,omitempty"`
+	Locale       string                  `json:"locale,omitempty"`
+	Translations map[string]*Translation `json:"translations,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+// Page is a page of the site besides the home page. Its slug is its path,
+// e.g. about or blog/opening-day.
+type Page struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	// NavLabel names the page in the navigation menu instead of its title
+	NavLabel string    `json:"navLabel,omitempty"`
+	Sections []Section `json:"sections"`
+	SEO      *SEO      `json:"seo,omitempty"`
+}
+
+// slugPattern allows lower-case words joined by hyphens, in up to three
+// levels separated by slashes
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(/[a-z0-9]+(-[a-z0-9]+)*){0,2}$`)
+
+// ValidSlug reports whether slug can name a page
+func ValidSlug(slug string) bool {
+	return len(slug) <= 100 && slugPattern.MatchString(slug)
+}
+
+// Label is the page's name in the navigation menu
+func (p *Page) Label() string {
+	if p.NavLabel != "" {
+		return p.NavLabel
+	}
+	return p.Title
+}
+
+// Depth is how many directories below the home page the page is
+func (p *Page) Depth() int {
+	return strings.Count(p.Slug, "/") + 1
+}
+
+// Page returns the page with the slug, or nil
+func (c *SiteContent) Page(slug string) *Page {
+	for i := range c.Pages {
+		if c.Pages[i].Slug == slug {
+			return &c.Pages[i]
+		}
+	}
+	return nil
+}
+
+// SectionsOf returns the sections of a page for editing in place; the empty
+// slug is the home page
+func (c *SiteContent) SectionsOf(slug string) (*[]Section, bool) {
+	if slug == "" {
+		return &c.Sections, true
+	}
+	page := c.Page(slug)
+	if page == nil {
+		return nil, false
+	}
+	return &page.Sections, true
+}
+
+// Section is one block of a page, e.g. the hero or the contact details
+type Section struct {
+	Type    string                 `json:"type"`
+	Content map[string]interface{} `json:"content"`
+}
+
+// SEO holds the page's search metadata
+type SEO struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Keywords    []string `json:"keywords"`
+	// Image is the absolute URL of the image shown when the page is shared
+	Image string `json:"image,omitempty"`
+}
+
+// siteContentKeys are the keys SiteContent models
+var siteContentKeys = []string{"title", "description", "sections", "seo", "pages", "locale", "translations"}
+
+func (c *SiteContent) UnmarshalJSON(data []byte) error {
+	type plain SiteContent
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, key := range siteContentKeys {
+		delete(all, key)
+	}
+	c.extra = all
+	return nil
+}
+
+func (c SiteContent) MarshalJSON() ([]byte, error) {
+	type plain SiteContent
+	data, err := json.Marshal(plain(c))
+	if err != nil || len(c.extra) == 0 {
+		return data, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for key, value := range c.extra {
+		all[key] = value
+	}
+	return json.Marshal(all)
+}
+
+// ParseContent reads a website's generated content; empty content is an
+// empty site
+func ParseContent(data datatypes.JSON) (*SiteContent, error) {
+	content := &SiteContent{}
+	if len(data) == 0 || string(data) == "null" {
+		return content, nil
+	}
+	if err := json.Unmarshal(data, content); err != nil {
+		return nil, fmt.Errorf("invalid website content: %w", err)
+	}
+	return content, nil
+}
+
+// JSON encodes the content for storage
+func (c *SiteContent) JSON() (datatypes.JSON, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// DesignTokens is the structure of Website.DesignTokens
+type DesignTokens struct {
+	Colors       map[string]string `json:"colors"`
+	Typography   map[string]string `json:"typography"`
+	Spacing      map[string]string `json:"spacing"`
+	BorderRadius string            `json:"borderRadius"`
+}
+
+// ParseDesignTokens reads a website's design tokens
+func ParseDesignTokens(data datatypes.JSON) (*DesignTokens, error) {
+	tokens := &DesignTokens{}
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, tokens); err != nil {
+			return nil, fmt.Errorf("invalid design tokens: %w", err)
+		}
+	}
+	if tokens.Colors == nil {
+		tokens.Colors = map[string]string{}
+	}
+	if tokens.Typography == nil {
+		tokens.Typography = map[string]string{}
+	}
+	if tokens.Spacing == nil {
+		tokens.Spacing = map[string]string{}
+	}
+	return tokens, nil
+}
+
+// JSON encodes the tokens for storage
+func (t *DesignTokens) JSON() (datatypes.JSON, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
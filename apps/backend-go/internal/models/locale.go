@@ -0,0 +1,109 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FallbackLocale is the locale of content that does not declare one
+const FallbackLocale = "en"
+
+// Translation is the site's content in another locale. Its pages parallel
+// the default locale's: a page is translated by the page with its slug.
+type Translation struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Sections    []Section `json:"sections"`
+	SEO         *SEO      `json:"seo,omitempty"`
+	Pages       []Page    `json:"pages,omitempty"`
+}
+
+// localePattern allows a lower-case language code with an optional region,
+// e.g. id, en or pt-br
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z]{2})?$`)
+
+// ValidLocale reports whether locale can name a language of the site
+func ValidLocale(locale string) bool {
+	return localePattern.MatchString(locale)
+}
+
+// DefaultLocale is the locale of the content itself, FallbackLocale when it
+// declares none or an invalid one
+func (c *SiteContent) DefaultLocale() string {
+	if locale := strings.ToLower(c.Locale); ValidLocale(locale) {
+		return locale
+	}
+	return FallbackLocale
+}
+
+// Locales lists the default locale followed by the translated ones in
+// alphabetical order. Translations with an invalid locale, or whose pages
+// would collide with a page of the site, are left out.
+func (c *SiteContent) Locales() []string {
+	locales := []string{c.DefaultLocale()}
+	var translated []string
+	for locale, t := range c.Translations {
+		if t != nil && locale != locales[0] && ValidLocale(locale) && !c.HasPagesAt(locale) {
+			translated = append(translated, locale)
+		}
+	}
+	sort.Strings(translated)
+	return append(locales, translated...)
+}
+
+// Localized returns the site as it reads in the locale, or nil when it has
+// no such translation. Pages without a translation keep the default
+// locale's content, and translated pages the site does not have are left
+// out.
+func (c *SiteContent) Localized(locale string) *SiteContent {
+	if locale == c.DefaultLocale() {
+		return c
+	}
+	t := c.Translations[locale]
+	if t == nil {
+		return nil
+	}
+
+	localized := &SiteContent{
+		Title:       t.Title,
+		Description: t.Description,
+		Sections:    t.Sections,
+		SEO:         t.SEO,
+		Locale:      locale,
+	}
+	translated := make(map[string]*Page, len(t.Pages))
+	for i := range t.Pages {
+		translated[t.Pages[i].Slug] = &t.Pages[i]
+	}
+	for _, page := range c.Pages {
+		if tp, ok := translated[page.Slug]; ok {
+			page.Title, page.NavLabel, page.Sections, page.SEO = tp.Title, tp.NavLabel, tp.Sections, tp.SEO
+		}
+		localized.Pages = append(localized.Pages, page)
+	}
+	return localized
+}
+
+// Translation returns the content of the default locale in the shape of a
+// translation, to be translated into another locale
+func (c *SiteContent) Translation() *Translation {
+	return &Translation{
+		Title:       c.Title,
+		Description: c.Description,
+		Sections:    c.Sections,
+		SEO:         c.SEO,
+		Pages:       c.Pages,
+	}
+}
+
+// HasPagesAt reports whether a page lives at /segment/ or below it, where a
+// locale's pages would go
+func (c *SiteContent) HasPagesAt(segment string) bool {
+	for _, page := range c.Pages {
+		if page.Slug == segment || strings.HasPrefix(page.Slug, segment+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -37,6 +37,7 @@ type Website struct {
 	Config           datatypes.JSON `json:"config"`
 	DesignTokens     datatypes.JSON `json:"designTokens"`
 	GeneratedContent datatypes.JSON `json:"generatedContent"`
+	ContentVersion   int            `gorm:"not null;default:0" json:"contentVersion"` // bumped by every content or design edit
 	ViewCount        int            `gorm:"default:0" json:"viewCount"`
 	CreatedAt        time.Time      `json:"createdAt"`
 	UpdatedAt        time.Time      `json:"updatedAt"`
@@ -62,11 +63,50 @@ type ChatMessage struct {
 	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"userId"`
 	User      User       `json:"user,omitempty"`
 	WebsiteID *uuid.UUID `gorm:"type:uuid;index" json:"websiteId"`
+	ThreadID  *uuid.UUID `gorm:"type:uuid;index" json:"threadId"` // nil for messages from before threads
 	Role      string     `gorm:"not null" json:"role"` // 'user' | 'assistant' | 'system'
 	Content   string     `gorm:"type:text;not null" json:"content"`
+	Truncated bool       `gorm:"not null;default:false" json:"truncated"` // response was cut short by cancel or disconnect
 	CreatedAt time.Time  `json:"createdAt"`
 }
 
+// ChatThread is a conversation with the AI, optionally about one website
+type ChatThread struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"userId"`
+	WebsiteID *uuid.UUID `gorm:"type:uuid;index" json:"websiteId"`
+	Title     string     `json:"title"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"` // bumped by every message
+
+	// Summary condenses the messages up to SummarizedUntil, which are no
+	// longer sent to the model verbatim
+	Summary         string     `gorm:"type:text" json:"-"`
+	SummarizedUntil *time.Time `json:"-"`
+
+	// Guide is the state of the guided site-building conversation, null
+	// for free-form threads
+	Guide datatypes.JSON `json:"-"`
+}
+
+// WebsiteRevision is an immutable copy of a website's editable state, written
+// after every change
+type WebsiteRevision struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	WebsiteID        uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_website_revision" json:"websiteId"`
+	Version          int            `gorm:"not null;uniqueIndex:idx_website_revision" json:"version"` // the website's contentVersion
+	Author           string         `gorm:"not null" json:"author"`                                  // user, ai, tool or system
+	UserID           *uuid.UUID     `gorm:"type:uuid" json:"userId"`
+	Reason           string         `json:"reason"`
+	Name             string         `gorm:"index" json:"name"` // named snapshots are never pruned
+	Title            string         `json:"title"`
+	Description      string         `json:"description"`
+	Config           datatypes.JSON `json:"config"`
+	DesignTokens     datatypes.JSON `json:"designTokens"`
+	GeneratedContent datatypes.JSON `json:"generatedContent"`
+	CreatedAt        time.Time      `json:"createdAt"`
+}
+
 // BeforeCreate hook to generate UUID
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
@@ -96,6 +136,20 @@ func (c *ChatMessage) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+func (t *ChatThread) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *WebsiteRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
 // Response types for API
 
 // UserResponse is the public user data
@@ -126,6 +180,7 @@ func (w *Website) Response() map[string]interface{} {
 		"config":           w.Config,
 		"designTokens":     w.DesignTokens,
 		"generatedContent": w.GeneratedContent,
+		"contentVersion":   w.ContentVersion,
 		"viewCount":        w.ViewCount,
 		"createdAt":        w.CreatedAt,
 		"updatedAt":        w.UpdatedAt,
@@ -145,4 +200,45 @@ func (t *TokenTransaction) Response() map[string]interface{} {
 		"relatedWebsiteId": t.RelatedWebsiteID,
 		"createdAt":        t.CreatedAt,
 	}
-}
\ No newline at end of file
+}
+
+// ChatThreadResponse is the public thread data
+func (t *ChatThread) Response() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        t.ID,
+		"userId":    t.UserID,
+		"websiteId": t.WebsiteID,
+		"title":     t.Title,
+		"guided":    len(t.Guide) > 0 && string(t.Guide) != "null",
+		"createdAt": t.CreatedAt,
+		"updatedAt": t.UpdatedAt,
+	}
+}
+
+// ChatMessageResponse is the public chat message data
+func (c *ChatMessage) Response() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        c.ID,
+		"threadId":  c.ThreadID,
+		"websiteId": c.WebsiteID,
+		"role":      c.Role,
+		"content":   c.Content,
+		"truncated": c.Truncated,
+		"createdAt": c.CreatedAt,
+	}
+}
+
+// WebsiteRevisionResponse is the public revision data, without the content
+func (r *WebsiteRevision) Response() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        r.ID,
+		"websiteId": r.WebsiteID,
+		"version":   r.Version,
+		"author":    r.Author,
+		"userId":    r.UserID,
+		"reason":    r.Reason,
+		"name":      r.Name,
+		"title":     r.Title,
+		"createdAt": r.CreatedAt,
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "regexp"
+
+// subdomainPattern allows a DNS label: lower-case letters, digits and
+// hyphens, neither starting nor ending with a hyphen, at most 63 long
+var subdomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidSubdomain reports whether subdomain can name a website, both as a
+// host name and as the directory it is published to
+func ValidSubdomain(subdomain string) bool {
+	return subdomainPattern.MatchString(subdomain)
+}
@@ -0,0 +1,101 @@
+// Package a11y checks the accessibility of a website: the structure of its
+// rendered pages (language, title, headings, text alternatives, link and
+// button names, form labels) and the contrast of its design tokens.
+package a11y
+
+import (
+	"fmt"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/render"
+)
+
+// Severities of findings. Errors make a page unusable for some visitors and
+// can block publishing; warnings make it harder to use.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is an accessibility problem
+type Finding struct {
+	// Page is the path of the page, e.g. / or /en/about/; empty for
+	// findings about the whole site
+	Page     string `json:"page,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+	// Element is the start tag of the offending element, if any
+	Element string `json:"element,omitempty"`
+}
+
+// Report lists the findings of a check
+type Report struct {
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+	Findings []Finding `json:"findings"`
+}
+
+// HasErrors reports whether any finding is an error
+func (r *Report) HasErrors() bool {
+	return r.Errors > 0
+}
+
+func (r *Report) add(findings ...Finding) {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			r.Errors++
+		} else {
+			r.Warnings++
+		}
+		r.Findings = append(r.Findings, f)
+	}
+}
+
+// Check checks every rendered page of the site, the language its content
+// declares and the contrast of its design tokens
+func Check(site *render.Site, content *models.SiteContent, tokens *models.DesignTokens) *Report {
+	report := &Report{Findings: []Finding{}}
+
+	if !models.ValidLocale(strings.ToLower(content.Locale)) {
+		report.add(Finding{
+			Severity: SeverityWarning,
+			Rule:     "undeclared_language",
+			Message:  fmt.Sprintf("The content does not declare its language, so pages are marked as %q; set the website's locale", models.FallbackLocale),
+		})
+	}
+	report.add(CheckContrast(tokens)...)
+	for _, page := range site.Pages {
+		report.add(CheckPage(PagePath(page), page.HTML)...)
+	}
+	return report
+}
+
+// PagePath is where a rendered page is served, e.g. / or /en/about/
+func PagePath(page *render.Page) string {
+	return "/" + strings.TrimSuffix(page.Path, "index.html")
+}
+
+// CheckContrast checks the contrast of the text colors the templates use
+// with the tokens: below 3:1 is an error, below 4.5:1 a warning
+func CheckContrast(tokens *models.DesignTokens) []Finding {
+	var findings []Finding
+	for _, check := range design.ContrastChecks(tokens) {
+		if check.Passes {
+			continue
+		}
+		severity := SeverityWarning
+		if check.Ratio < design.MinLargeContrast {
+			severity = SeverityError
+		}
+		findings = append(findings, Finding{
+			Severity: severity,
+			Rule:     "contrast",
+			Message: fmt.Sprintf("%s text on %s has a contrast of %.2f:1; text needs %.1f:1",
+				check.Foreground, check.Background, check.Ratio, design.MinContrast),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,105 @@
+package a11y
+
+import (
+	"fmt"
+	"testing"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/render"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+// page wraps body in a document that passes the document-level checks
+func page(body string) []byte {
+	return []byte(fmt.Sprintf(`<!DOCTYPE html><html lang="en"><head><title>Sweet Bites</title></head><body>%s</body></html>`, body))
+}
+
+// rules lists the rules of the findings in order
+func rules(findings []Finding) []string {
+	var list []string
+	for _, f := range findings {
+		list = append(list, f.Rule)
+	}
+	return list
+}
+
+func TestCheckPage(t *testing.T) {
+	tests := []struct {
+		name string
+		html []byte
+		want []string
+	}{
+		{"clean", page(`<h1>Bakery</h1><h2>Menu</h2><img src="a.jpg" alt="Bread"><a href="/menu/">Our menu</a>`), nil},
+		{"document", []byte(`<html><body><h1>Bakery</h1></body></html>`), []string{"missing_lang", "missing_title"}},
+		{"no h1", page(`<h2>Menu</h2>`), []string{"missing_h1"}},
+		{"two h1", page(`<h1>Bakery</h1><h1>Menu</h1>`), []string{"multiple_h1"}},
+		{"skipped level", page(`<h1>Bakery</h1><h3>Menu</h3><h2>Hours</h2><h3>Weekdays</h3>`), []string{"heading_order"}},
+		{"empty heading", page(`<h1>Bakery</h1><h2> </h2>`), []string{"empty_heading"}},
+		{"images", page(`<h1>Bakery</h1><img src="a.jpg"><img src="b.jpg" alt=""><img src="c.jpg" aria-hidden="true">`), []string{"missing_alt", "empty_alt"}},
+		{"links", page(`<h1>Bakery</h1><a href="/"></a><a href="/menu/">Read more</a><a href="/"><img src="logo.png" alt="Home"></a><a name="top"></a>`), []string{"empty_link", "vague_link"}},
+		{"buttons", page(`<h1>Bakery</h1><button></button><button aria-label="Close">×</button>`), []string{"empty_button"}},
+		{"labels", page(`<h1>Bakery</h1><label for="email">Email</label><input id="email"><label>Name <input></label><input type="hidden"><input aria-label="Search"><textarea></textarea>`), []string{"missing_label"}},
+		{"hidden subtree", page(`<h1>Bakery</h1><div aria-hidden="true"><img src="a.jpg"><a href="/"></a><h3></h3><p id="top"></p></div><p id="top"></p>`), []string{"duplicate_id"}},
+		{"presentational", page(`<h1>Bakery</h1><table role="presentation"><tr><td><img src="a.jpg"></td></tr></table>`), []string{"missing_alt"}},
+		{"duplicate id", page(`<h1 id="top">Bakery</h1><p id="top">Hi</p>`), []string{"duplicate_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rules(CheckPage("/", tt.html)))
+		})
+	}
+
+	findings := CheckPage("/about/", page(`<h1>Bakery</h1><img src="a.jpg">`))
+	require.Len(t, findings, 1)
+	assert.Equal(t, Finding{
+		Page:     "/about/",
+		Severity: SeverityError,
+		Rule:     "missing_alt",
+		Message:  "The image has no text alternative (alt)",
+		Element:  `<img src="a.jpg">`,
+	}, findings[0])
+}
+
+func TestCheckContrast(t *testing.T) {
+	for _, f := range CheckContrast(nil) {
+		assert.Equal(t, SeverityWarning, f.Severity, "the default tokens are readable: %s", f.Message)
+	}
+
+	findings := CheckContrast(&models.DesignTokens{Colors: map[string]string{"text": "#777777", "background": "#888888"}})
+	require.NotEmpty(t, findings)
+	assert.Equal(t, SeverityError, findings[0].Severity)
+	assert.Equal(t, "contrast", findings[0].Rule)
+	assert.Contains(t, findings[0].Message, "text text on background")
+}
+
+func TestCheck(t *testing.T) {
+	renderer, err := render.New()
+	require.NoError(t, err)
+
+	site := &models.Website{
+		Title: "Sweet Bites",
+		GeneratedContent: datatypes.JSON(`{"title":"Sweet Bites","locale":"en","sections":[
+			{"type":"hero","content":{"title":"Fresh bread daily","ctaUrl":"https://sweetbites.test/menu"}}
+		]}`),
+	}
+	rendered, err := renderer.Render(site, "")
+	require.NoError(t, err)
+	content, err := models.ParseContent(site.GeneratedContent)
+	require.NoError(t, err)
+	readable := &models.DesignTokens{Colors: design.NewPalette(design.RGB{R: 29, G: 78, B: 216}, nil).Colors}
+
+	report := Check(rendered, content, readable)
+	assert.False(t, report.HasErrors())
+	assert.Equal(t, []string{"vague_link"}, rules(report.Findings), "the hero's default call to action says Learn more")
+	assert.Equal(t, "/", report.Findings[0].Page)
+
+	content.Locale = ""
+	report = Check(rendered, content, &models.DesignTokens{Colors: map[string]string{"text": "#777777", "background": "#888888"}})
+	assert.True(t, report.HasErrors())
+	assert.Equal(t, "undeclared_language", report.Findings[0].Rule)
+	assert.Equal(t, "contrast", report.Findings[1].Rule)
+}
@@ -0,0 +1,264 @@
+package a11y
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxElementLength bounds the start tags quoted in findings
+const maxElementLength = 160
+
+// vagueLinkTexts say nothing about where a link goes when read out of
+// context
+var vagueLinkTexts = map[string]bool{
+	"click here": true,
+	"details":    true,
+	"here":       true,
+	"learn more": true,
+	"link":       true,
+	"more":       true,
+	"read more":  true,
+	"this":       true,
+}
+
+// pageChecker collects the findings of one page while walking its document
+type pageChecker struct {
+	page     string
+	findings []Finding
+	// labelled are the IDs label elements point to
+	labelled map[string]bool
+	ids      map[string]bool
+	h1s      int
+	heading  int
+}
+
+// CheckPage checks the rendered HTML of the page at path
+func CheckPage(path string, page []byte) []Finding {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return []Finding{{Page: path, Severity: SeverityError, Rule: "invalid_html", Message: "The page cannot be parsed"}}
+	}
+
+	c := &pageChecker{page: path, labelled: map[string]bool{}, ids: map[string]bool{}}
+	walk(doc, func(n *html.Node) {
+		if n.DataAtom == atom.Label {
+			if target := attr(n, "for"); target != "" {
+				c.labelled[target] = true
+			}
+		}
+	})
+
+	c.document(doc)
+	c.walk(doc, false)
+	if c.h1s == 0 {
+		c.add(nil, SeverityWarning, "missing_h1", "The page has no main heading (h1)")
+	} else if c.h1s > 1 {
+		c.add(nil, SeverityWarning, "multiple_h1", fmt.Sprintf("The page has %d main headings (h1); keep one", c.h1s))
+	}
+	return c.findings
+}
+
+func (c *pageChecker) add(n *html.Node, severity, rule, message string) {
+	c.findings = append(c.findings, Finding{
+		Page:     c.page,
+		Severity: severity,
+		Rule:     rule,
+		Message:  message,
+		Element:  startTag(n),
+	})
+}
+
+// document checks the language and the title
+func (c *pageChecker) document(doc *html.Node) {
+	var root, title *html.Node
+	walk(doc, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.Html:
+			root = n
+		case atom.Title:
+			if title == nil {
+				title = n
+			}
+		}
+	})
+	if root == nil || strings.TrimSpace(attr(root, "lang")) == "" {
+		c.add(root, SeverityError, "missing_lang", "The page does not declare its language")
+	}
+	if title == nil || text(title) == "" {
+		c.add(title, SeverityError, "missing_title", "The page has no title")
+	}
+}
+
+// walk checks each element; inLabel is set inside label elements.
+// Assistive technology skips everything inside an aria-hidden element, so
+// only the ids there are checked.
+func (c *pageChecker) walk(n *html.Node, inLabel bool) {
+	if n.Type == html.ElementNode {
+		if attr(n, "aria-hidden") == "true" {
+			walk(n, c.id)
+			return
+		}
+		c.element(n, inLabel)
+		if n.DataAtom == atom.Label {
+			inLabel = true
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child, inLabel)
+	}
+}
+
+func (c *pageChecker) element(n *html.Node, inLabel bool) {
+	c.id(n)
+	if hidden(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		c.headingElement(n)
+	case atom.Img:
+		alt, ok := attrOK(n, "alt")
+		if !ok {
+			c.add(n, SeverityError, "missing_alt", "The image has no text alternative (alt)")
+		} else if strings.TrimSpace(alt) == "" {
+			c.add(n, SeverityWarning, "empty_alt", "The image has an empty alt, so screen readers skip it; describe it unless it is decorative")
+		}
+	case atom.A:
+		if attr(n, "href") == "" {
+			return
+		}
+		name := accessibleName(n)
+		if name == "" {
+			c.add(n, SeverityError, "empty_link", "The link has no text, so its purpose is unknown")
+		} else if vagueLinkTexts[strings.ToLower(strings.Trim(name, ".!…> "))] {
+			c.add(n, SeverityWarning, "vague_link", fmt.Sprintf("The link text %q does not say where the link goes", name))
+		}
+	case atom.Button:
+		if accessibleName(n) == "" {
+			c.add(n, SeverityError, "empty_button", "The button has no text")
+		}
+	case atom.Input, atom.Select, atom.Textarea:
+		switch strings.ToLower(attr(n, "type")) {
+		case "hidden", "submit", "reset", "button", "image":
+			return
+		}
+		if !inLabel && !c.labelled[attr(n, "id")] && attr(n, "aria-label") == "" && attr(n, "aria-labelledby") == "" && attr(n, "title") == "" {
+			c.add(n, SeverityError, "missing_label", "The form field has no label")
+		}
+	}
+}
+
+func (c *pageChecker) headingElement(n *html.Node) {
+	level := int(n.Data[1] - '0')
+	if level == 1 {
+		c.h1s++
+	}
+	if text(n) == "" {
+		c.add(n, SeverityError, "empty_heading", "The heading has no text")
+	}
+	if c.heading > 0 && level > c.heading+1 {
+		c.add(n, SeverityWarning, "heading_order", fmt.Sprintf("An h%d follows an h%d; do not skip heading levels", level, c.heading))
+	}
+	c.heading = level
+}
+
+// walk calls fn for every node below n, depth first
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attr(n *html.Node, key string) string {
+	value, _ := attrOK(n, key)
+	return value
+}
+
+// id records the element's id, warning when another element has it
+func (c *pageChecker) id(n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	if id := attr(n, "id"); id != "" {
+		if c.ids[id] {
+			c.add(n, SeverityWarning, "duplicate_id", fmt.Sprintf("The id %q is used more than once", id))
+		}
+		c.ids[id] = true
+	}
+}
+
+// hidden reports whether the element is hidden from assistive technology;
+// a presentational element's children are still announced
+func hidden(n *html.Node) bool {
+	role := attr(n, "role")
+	return attr(n, "aria-hidden") == "true" || role == "presentation" || role == "none"
+}
+
+// text is the element's text with whitespace collapsed, counting the text
+// alternatives of images inside it
+func text(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			b.WriteString(node.Data)
+			b.WriteString(" ")
+		case node.DataAtom == atom.Img:
+			b.WriteString(attr(node, "alt"))
+			b.WriteString(" ")
+		}
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// accessibleName is what assistive technology announces for a link or
+// button
+func accessibleName(n *html.Node) string {
+	if label := strings.TrimSpace(attr(n, "aria-label")); label != "" {
+		return label
+	}
+	if attr(n, "aria-labelledby") != "" {
+		return attr(n, "aria-labelledby")
+	}
+	if name := text(n); name != "" {
+		return name
+	}
+	return strings.TrimSpace(attr(n, "title"))
+}
+
+// startTag renders the start tag of an element for a finding
+func startTag(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		fmt.Fprintf(&b, " %s=%q", a.Key, a.Val)
+	}
+	b.WriteString(">")
+	tag := b.String()
+	if len(tag) > maxElementLength {
+		cut := maxElementLength
+		for cut > 0 && tag[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		tag = tag[:cut] + "…"
+	}
+	return tag
+}
@@ -16,30 +16,49 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ChatReplyTokens is the most tokens a streamed chat answer may use
+const ChatReplyTokens = 2048
+
 type KimiClient struct {
 	apiKey  string
 	baseURL string
+	model   string
 	client  *http.Client
 }
 
 func NewKimiClient(cfg *config.KimiConfig) *KimiClient {
+	model := cfg.Model
+	if model == "" {
+		model = "gpt-4o"
+	}
 	return &KimiClient{
 		apiKey:  cfg.APIKey,
 		baseURL: cfg.BaseURL,
+		model:   model,
 		client: &http.Client{
 			Timeout: 120 * time.Second,
 		},
 	}
 }
 
+// Model returns the name of the model requests are sent to
+func (k *KimiClient) Model() string {
+	return k.model
+}
+
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls are the tools an assistant message asks to run
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID ties a "tool" message to the call it answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 type ChatRequest struct {
 	Model       string    `json:"model"`
 	Messages    []Message `json:"messages"`
+	Tools       []Tool    `json:"tools,omitempty"`
 	Temperature float64   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
@@ -69,7 +88,7 @@ func (k *KimiClient) ChatCompletion(ctx context.Context, messages []Message, max
 			ID:      "demo-response",
 			Object:  "chat.completion",
 			Created: time.Now().Unix(),
-			Model:   "gpt-4o",
+			Model:   k.model,
 			Choices: []struct {
 				Index   int     `json:"index"`
 				Message Message `json:"message"`
@@ -101,7 +120,7 @@ func (k *KimiClient) ChatCompletion(ctx context.Context, messages []Message, max
 	}
 
 	reqBody := ChatRequest{
-		Model:       "gpt-4o",
+		Model:       k.model,
 		Messages:    messages,
 		Temperature: 0.7,
 		MaxTokens:   maxTokens,
@@ -152,14 +171,18 @@ func min(a, b int) int {
 	return b
 }
 
-func (k *KimiClient) GenerateWebsite(ctx context.Context, prompt, templateID string) (string, error) {
-	logrus.WithField("prompt", prompt).WithField("template", templateID).Info("Generating website")
+// GenerateWebsite generates a website's content. templateGuide describes the
+// chosen template; sectionGuide describes the section types the page may use
+// and the schema of their content.
+func (k *KimiClient) GenerateWebsite(ctx context.Context, prompt, templateGuide, sectionGuide string) (string, error) {
+	logrus.WithField("prompt", prompt).Info("Generating website")
 
 	systemPrompt := `You are a website generation assistant. Generate complete website content based on the user's requirements.
 Return ONLY valid JSON with this structure:
 {
   "title": "Site Title",
   "description": "Site description",
+  "locale": "en",
   "sections": [
     {"type": "hero", "content": {...}},
     {"type": "about", "content": {...}},
@@ -169,16 +192,30 @@ Return ONLY valid JSON with this structure:
     "title": "Page title",
     "description": "Meta description",
     "keywords": ["keyword1", "keyword2"]
-  }
+  },
+  "pages": [
+    {
+      "slug": "about",
+      "title": "About us",
+      "navLabel": "About",
+      "sections": [{"type": "about", "content": {...}}],
+      "seo": {"title": "...", "description": "...", "keywords": ["..."]}
+    }
+  ]
 }
-Be creative and professional. Ensure all content is in the same language as the user's prompt.`
+"sections" and "seo" are the home page. Add "pages" when the requirements ask for several pages, such as About, Services, Blog or Contact; leave them out for a one-page site. A slug is lower-case words joined by hyphens, and a page below another, such as a blog post, has a slug like blog/opening-day.
+Give every page its own seo: a title under 60 characters and a description of 50 to 160 characters. Include a contact section with the email, phone and address the requirements mention, so search engines can describe the business.
+Be creative and professional. Ensure all content is in the same language as the user's prompt, and set "locale" to that language's code, such as id or en.
+` + templateGuide + `
+Use only these section types, and give each section content that follows its schema:
+` + sectionGuide
 
 	messages := []Message{
 		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: fmt.Sprintf("Create a website with template '%s'. Requirements: %s", templateID, prompt)},
+		{Role: "user", Content: fmt.Sprintf("Create a website. Requirements: %s", prompt)},
 	}
 
-	resp, err := k.ChatCompletion(ctx, messages, 4096)
+	resp, err := k.ChatCompletion(ctx, messages, 8192)
 	if err != nil {
 		logrus.WithError(err).Error("ChatCompletion failed")
 		return "", err
@@ -220,12 +257,23 @@ type StreamResponse struct {
 
 // Delta represents the content delta in a streaming response
 type Delta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
 }
 
 // ChatCompletionStream performs a streaming chat completion
 func (k *KimiClient) ChatCompletionStream(ctx context.Context, messages []Message, onChunk func(chunk string)) error {
+	_, err := k.ChatCompletionStreamTools(ctx, messages, nil, onChunk)
+	return err
+}
+
+// ChatCompletionStreamTools performs a streaming chat completion in which
+// the model may call tools. Text is passed to onChunk as it arrives; the
+// tool calls, assembled from their streamed fragments, are returned once
+// the stream ends. The caller runs them and continues the conversation with
+// their results.
+func (k *KimiClient) ChatCompletionStreamTools(ctx context.Context, messages []Message, tools []Tool, onChunk func(chunk string)) ([]ToolCall, error) {
 	if k.apiKey == "" || k.apiKey == "demo_key" {
 		// Return mock response for demo/testing
 		mockResponse := "I'm a demo AI assistant. To get real AI responses, please configure a valid KIMI_API_KEY in your environment variables. You can get an API key from https://platform.moonshot.cn/"
@@ -235,26 +283,27 @@ func (k *KimiClient) ChatCompletionStream(ctx context.Context, messages []Messag
 		for _, word := range words {
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return nil, ctx.Err()
 			default:
 				onChunk(word + " ")
 				time.Sleep(50 * time.Millisecond) // Simulate typing delay
 			}
 		}
-		return nil
+		return nil, nil
 	}
 
 	reqBody := ChatRequest{
-		Model:       "gpt-4o",
+		Model:       k.model,
 		Messages:    messages,
+		Tools:       tools,
 		Temperature: 0.7,
-		MaxTokens:   2048,
+		MaxTokens:   ChatReplyTokens,
 		Stream:      true,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	url := k.baseURL + "/chat/completions"
@@ -262,7 +311,7 @@ func (k *KimiClient) ChatCompletionStream(ctx context.Context, messages []Messag
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -270,17 +319,18 @@ func (k *KimiClient) ChatCompletionStream(ctx context.Context, messages []Messag
 
 	resp, err := k.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		logrus.WithField("status", resp.StatusCode).WithField("url", url).WithField("body", string(body)).Error("Kimi API streaming error")
-		return fmt.Errorf("kimi API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("kimi API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Read the SSE stream
+	var calls toolCallBuilder
 	reader := bufio.NewReader(resp.Body)
 	for {
 		line, err := reader.ReadString('\n')
@@ -288,7 +338,7 @@ func (k *KimiClient) ChatCompletionStream(ctx context.Context, messages []Messag
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("error reading stream: %w", err)
+			return nil, fmt.Errorf("error reading stream: %w", err)
 		}
 
 		line = strings.TrimSpace(line)
@@ -319,8 +369,9 @@ func (k *KimiClient) ChatCompletionStream(ctx context.Context, messages []Messag
 			if delta.Content != "" {
 				onChunk(delta.Content)
 			}
+			calls.add(delta.ToolCalls)
 		}
 	}
 
-	return nil
+	return calls.calls(), nil
 }
\ No newline at end of file
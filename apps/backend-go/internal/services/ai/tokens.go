@@ -0,0 +1,32 @@
+package ai
+
+import "unicode/utf8"
+
+// messageOverhead approximates the tokens each message adds for its role
+// and separators
+const messageOverhead = 4
+
+// EstimateTokens approximates the number of tokens in text without a
+// tokenizer: about four characters per token for ASCII text, and one token
+// per character otherwise (CJK and other scripts tokenize much less densely).
+// It errs on the high side, which is the safe direction for budgeting.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// EstimateMessageTokens approximates the tokens messages take in a request
+func EstimateMessageTokens(messages ...Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageOverhead + EstimateTokens(msg.Content)
+	}
+	return total
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the name, purpose and JSON Schema parameters of a tool
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// NewTool builds a function tool from its JSON Schema parameters
+func NewTool(name, description, parameters string) Tool {
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters:  json.RawMessage(parameters),
+		},
+	}
+}
+
+// ToolCall is a call the model made to a tool
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the tool and carries its arguments as JSON text
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolCallDelta is a fragment of a tool call in a streamed response. The
+// first fragment of a call carries its ID and name; the arguments arrive in
+// pieces that are concatenated.
+type ToolCallDelta struct {
+	Index    int              `json:"index"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// toolCallBuilder assembles streamed tool call fragments
+type toolCallBuilder struct {
+	byIndex map[int]*ToolCall
+}
+
+func (b *toolCallBuilder) add(deltas []ToolCallDelta) {
+	for _, delta := range deltas {
+		if b.byIndex == nil {
+			b.byIndex = make(map[int]*ToolCall)
+		}
+		call, ok := b.byIndex[delta.Index]
+		if !ok {
+			call = &ToolCall{Type: "function"}
+			b.byIndex[delta.Index] = call
+		}
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
+
+// calls returns the assembled calls in the order the model made them
+func (b *toolCallBuilder) calls() []ToolCall {
+	indexes := make([]int, 0, len(b.byIndex))
+	for index := range b.byIndex {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	calls := make([]ToolCall, 0, len(indexes))
+	for _, index := range indexes {
+		calls = append(calls, *b.byIndex[index])
+	}
+	return calls
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend-go/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolCallBuilder(t *testing.T) {
+	var b toolCallBuilder
+	b.add([]ToolCallDelta{{Index: 1, ID: "call_b", Function: ToolCallFunction{Name: "add_section"}}})
+	b.add([]ToolCallDelta{{Index: 0, ID: "call_a", Function: ToolCallFunction{Name: "update_section", Arguments: `{"index":`}}})
+	b.add([]ToolCallDelta{{Index: 0, Function: ToolCallFunction{Arguments: `0}`}}})
+
+	calls := b.calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_a", calls[0].ID)
+	assert.Equal(t, "update_section", calls[0].Function.Name)
+	assert.Equal(t, `{"index":0}`, calls[0].Function.Arguments)
+	assert.Equal(t, "function", calls[1].Type)
+	assert.Equal(t, "add_section", calls[1].Function.Name)
+}
+
+func TestChatCompletionStreamTools(t *testing.T) {
+	var request ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"choices":[{"delta":{"content":"Updating "}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"set_design_token","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"token\":\"colors.primary\","}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"value\":\"#1D4ED8\"}"}}]}}]}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	}))
+	defer server.Close()
+
+	client := NewKimiClient(&config.KimiConfig{APIKey: "test", BaseURL: server.URL})
+	tool := NewTool("set_design_token", "Change a design token", `{"type":"object"}`)
+
+	var text strings.Builder
+	calls, err := client.ChatCompletionStreamTools(context.Background(), []Message{{Role: "user", Content: "make it blue"}}, []Tool{tool}, func(chunk string) {
+		text.WriteString(chunk)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Updating ", text.String())
+	require.Len(t, request.Tools, 1)
+	assert.Equal(t, "set_design_token", request.Tools[0].Function.Name)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.JSONEq(t, `{"token":"colors.primary","value":"#1D4ED8"}`, calls[0].Function.Arguments)
+}
@@ -0,0 +1,220 @@
+// Package catalog holds the website templates users pick from: their
+// default sections and design tokens, the guidance given to the AI when it
+// writes a site for them, and the subscription tier they need.
+package catalog
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/section"
+)
+
+//go:embed templates/*.json thumbnails/*.svg
+var files embed.FS
+
+var (
+	// ErrUnknownTemplate is returned for template IDs not in the catalog
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrTemplateNotAllowed is returned when the user's subscription does
+	// not include the template
+	ErrTemplateNotAllowed = errors.New("template not included in subscription")
+)
+
+var idPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+
+// tierRanks orders the subscription tiers; a template is available to its
+// own tier and those above it
+var tierRanks = map[string]int{"free": 0, "pro": 1, "business": 2}
+
+// Template is one entry of the catalog
+type Template struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Tier is the lowest subscription tier that may use the template
+	Tier string `json:"tier"`
+	// Sections are the section types a new site starts with, in order
+	Sections []string `json:"sections"`
+	// DesignTokens are the template's colors, fonts and spacing
+	DesignTokens *models.DesignTokens `json:"designTokens"`
+	// Guidance tells the AI how to write content for the template
+	Guidance string `json:"guidance"`
+
+	thumbnail []byte
+}
+
+// Allows reports whether a subscription tier may use the template. Unknown
+// tiers are treated as free.
+func (t *Template) Allows(tier string) bool {
+	return tierRanks[tier] >= tierRanks[t.Tier]
+}
+
+// Thumbnail returns the template's SVG preview image
+func (t *Template) Thumbnail() []byte {
+	return t.thumbnail
+}
+
+// Tokens returns a copy of the template's design tokens
+func (t *Template) Tokens() *models.DesignTokens {
+	tokens := &models.DesignTokens{
+		Colors:       make(map[string]string, len(t.DesignTokens.Colors)),
+		Typography:   make(map[string]string, len(t.DesignTokens.Typography)),
+		Spacing:      make(map[string]string, len(t.DesignTokens.Spacing)),
+		BorderRadius: t.DesignTokens.BorderRadius,
+	}
+	for key, value := range t.DesignTokens.Colors {
+		tokens.Colors[key] = value
+	}
+	for key, value := range t.DesignTokens.Typography {
+		tokens.Typography[key] = value
+	}
+	for key, value := range t.DesignTokens.Spacing {
+		tokens.Spacing[key] = value
+	}
+	return tokens
+}
+
+// Content returns the content of a new site made from the template: its
+// sections filled with their types' defaults
+func (t *Template) Content(title, description string) *models.SiteContent {
+	content := &models.SiteContent{Title: title, Description: description}
+	for _, name := range t.Sections {
+		sectionType, _ := section.Builtin.Lookup(name)
+		content.Sections = append(content.Sections, models.Section{
+			Type:    name,
+			Content: sectionType.DefaultContent(nil),
+		})
+	}
+	if len(content.Sections) > 0 && content.Sections[0].Type == "hero" && title != "" {
+		content.Sections[0].Content["title"] = title
+	}
+	return content
+}
+
+// Guide describes the template to the AI generating a site with it
+func (t *Template) Guide() string {
+	return fmt.Sprintf("The site uses the %s template (%s). Use these sections, in this order, unless the requirements call for others: %s.\n%s",
+		t.Name, t.Description, strings.Join(t.Sections, ", "), t.Guidance)
+}
+
+// Catalog is the set of templates, loaded once at startup
+type Catalog struct {
+	templates map[string]*Template
+	ordered   []*Template
+}
+
+// Load reads the templates embedded in the binary
+func Load() (*Catalog, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) (*Catalog, error) {
+	paths, err := fs.Glob(fsys, "templates/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{templates: make(map[string]*Template, len(paths))}
+	for _, p := range paths {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		t := &Template{}
+		if err := json.Unmarshal(data, t); err != nil {
+			return nil, fmt.Errorf("template %s: %w", p, err)
+		}
+		if id := strings.TrimSuffix(path.Base(p), ".json"); t.ID != id {
+			return nil, fmt.Errorf("template %s: id %q does not match the file name", p, t.ID)
+		}
+		if err := t.check(); err != nil {
+			return nil, fmt.Errorf("template %s: %w", t.ID, err)
+		}
+		if t.thumbnail, err = fs.ReadFile(fsys, "thumbnails/"+t.ID+".svg"); err != nil {
+			return nil, fmt.Errorf("template %s has no thumbnail: %w", t.ID, err)
+		}
+		catalog.templates[t.ID] = t
+		catalog.ordered = append(catalog.ordered, t)
+	}
+
+	sort.SliceStable(catalog.ordered, func(i, j int) bool {
+		a, b := catalog.ordered[i], catalog.ordered[j]
+		if tierRanks[a.Tier] != tierRanks[b.Tier] {
+			return tierRanks[a.Tier] < tierRanks[b.Tier]
+		}
+		return a.ID < b.ID
+	})
+	return catalog, nil
+}
+
+// check validates a template as read from its file
+func (t *Template) check() error {
+	if !idPattern.MatchString(t.ID) {
+		return fmt.Errorf("invalid id %q", t.ID)
+	}
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, ok := tierRanks[t.Tier]; !ok {
+		return fmt.Errorf("unknown tier %q", t.Tier)
+	}
+	if len(t.Sections) == 0 {
+		return errors.New("at least one section is required")
+	}
+	for _, name := range t.Sections {
+		if _, ok := section.Builtin.Lookup(name); !ok {
+			return fmt.Errorf("unknown section type %q", name)
+		}
+	}
+	if t.DesignTokens == nil {
+		return errors.New("designTokens are required")
+	}
+	for _, key := range []string{"primary", "secondary", "accent", "background", "text"} {
+		if t.DesignTokens.Colors[key] == "" {
+			return fmt.Errorf("designTokens.colors.%s is required", key)
+		}
+	}
+	return design.Validate(t.DesignTokens)
+}
+
+// List returns every template, the free ones first
+func (c *Catalog) List() []*Template {
+	return c.ordered
+}
+
+// Get looks up a template by ID
+func (c *Catalog) Get(id string) (*Template, bool) {
+	t, ok := c.templates[id]
+	return t, ok
+}
+
+// IDs lists the template IDs in catalog order
+func (c *Catalog) IDs() []string {
+	ids := make([]string, len(c.ordered))
+	for i, t := range c.ordered {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// Check looks up a template and makes sure a subscription tier may use it
+func (c *Catalog) Check(id, tier string) (*Template, error) {
+	t, ok := c.templates[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, use one of %s", ErrUnknownTemplate, id, strings.Join(c.IDs(), ", "))
+	}
+	if !t.Allows(tier) {
+		return nil, fmt.Errorf("%w: the %s template needs the %s plan", ErrTemplateNotAllowed, t.Name, t.Tier)
+	}
+	return t, nil
+}
@@ -0,0 +1,118 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"backend-go/internal/services/section"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	catalog, err := Load()
+	require.NoError(t, err)
+
+	for _, id := range []string{"modern", "minimal", "creative"} {
+		template, ok := catalog.Get(id)
+		require.True(t, ok, id)
+		assert.Equal(t, "free", template.Tier, id)
+	}
+
+	ranks := []int{}
+	for _, template := range catalog.List() {
+		assert.NotEmpty(t, template.Guidance, template.ID)
+		assert.Contains(t, string(template.Thumbnail()), "<svg", template.ID)
+		ranks = append(ranks, tierRanks[template.Tier])
+	}
+	assert.IsNonDecreasing(t, ranks, "free templates come first")
+}
+
+func TestCheck(t *testing.T) {
+	catalog, err := Load()
+	require.NoError(t, err)
+
+	template, err := catalog.Check("modern", "free")
+	require.NoError(t, err)
+	assert.Equal(t, "Modern", template.Name)
+
+	_, err = catalog.Check("business", "free")
+	assert.True(t, errors.Is(err, ErrTemplateNotAllowed))
+	assert.Contains(t, err.Error(), "needs the pro plan")
+
+	_, err = catalog.Check("business", "pro")
+	assert.NoError(t, err)
+	_, err = catalog.Check("elegant", "pro")
+	assert.True(t, errors.Is(err, ErrTemplateNotAllowed))
+	_, err = catalog.Check("elegant", "business")
+	assert.NoError(t, err)
+
+	// Unknown tiers get the free templates only
+	_, err = catalog.Check("business", "trial")
+	assert.True(t, errors.Is(err, ErrTemplateNotAllowed))
+
+	_, err = catalog.Check("retro", "business")
+	assert.True(t, errors.Is(err, ErrUnknownTemplate))
+	assert.Contains(t, err.Error(), `"retro", use one of creative, minimal, modern, business`)
+}
+
+func TestContent(t *testing.T) {
+	catalog, err := Load()
+	require.NoError(t, err)
+
+	template, _ := catalog.Get("business")
+	content := template.Content("Kopi Senja", "Coffee roasters")
+	require.Len(t, content.Sections, len(template.Sections))
+	assert.Equal(t, "Kopi Senja", content.Sections[0].Content["title"])
+	for _, s := range content.Sections {
+		assert.NoError(t, section.Builtin.Validate(s.Type, s.Content), s.Type)
+	}
+
+	// The sections are copies of the defaults
+	sectionType, _ := section.Builtin.Lookup("hero")
+	assert.NotEqual(t, "Kopi Senja", sectionType.Defaults["title"])
+
+	tokens := template.Tokens()
+	tokens.Colors["primary"] = "#000000"
+	assert.NotEqual(t, "#000000", template.DesignTokens.Colors["primary"])
+}
+
+func TestLoadRejectsInvalidTemplates(t *testing.T) {
+	valid := `{"id":"plain","name":"Plain","tier":"free","sections":["hero"],"guidance":"Be brief.",
+		"designTokens":{"colors":{"primary":"#000000","secondary":"#333333","accent":"#F59E0B","background":"#FFFFFF","text":"#111111"}}}`
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"mismatched id", `{"id":"other","name":"Plain"}`, "does not match the file name"},
+		{"unknown tier", `{"id":"plain","name":"Plain","tier":"gold"}`, `unknown tier "gold"`},
+		{"unknown section", `{"id":"plain","name":"Plain","tier":"free","sections":["carousel"]}`, `unknown section type "carousel"`},
+		{"missing tokens", `{"id":"plain","name":"Plain","tier":"free","sections":["hero"]}`, "designTokens are required"},
+		{"invalid font", `{"id":"plain","name":"Plain","tier":"free","sections":["hero"],"designTokens":{"colors":{"primary":"#000000","secondary":"#333333","accent":"#F59E0B","background":"#FFFFFF","text":"#111111"},"typography":{"bodyFont":"Wingdings"}}}`, "typography.bodyFont"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(fstest.MapFS{
+				"templates/plain.json": {Data: []byte(tt.template)},
+				"thumbnails/plain.svg": {Data: []byte("<svg/>")},
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	_, err := load(fstest.MapFS{"templates/plain.json": {Data: []byte(valid)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no thumbnail")
+
+	catalog, err := load(fstest.MapFS{
+		"templates/plain.json": {Data: []byte(valid)},
+		"thumbnails/plain.svg": {Data: []byte("<svg/>")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plain"}, catalog.IDs())
+}
@@ -0,0 +1,289 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultContextBudget is the context window assumed for models without
+	// a configured budget
+	defaultContextBudget = 8000
+
+	// maxLoadedMessages caps the unsummarized messages read for one
+	// response, in case summarizing has been failing
+	maxLoadedMessages = 200
+
+	// keepVerbatim is how many of the latest messages are never summarized
+	keepVerbatim = 2
+
+	// summaryMaxTokens bounds a generated summary
+	summaryMaxTokens = 512
+
+	// summaryTimeout bounds the request that updates a summary
+	summaryTimeout = 60 * time.Second
+)
+
+// summaryPrompt instructs the model that maintains a thread's summary
+const summaryPrompt = `You maintain the memory of a conversation between a user and SiteSpark's website-building assistant.
+Update the current summary with the new messages. Keep facts about the user's business and website, decisions made, stated preferences and open requests; drop greetings and small talk.
+Answer with the updated summary only, in at most 200 words, in the language of the conversation.`
+
+// contextBudget returns the tokens available for a prompt to the model: its
+// context window less the room reserved for the answer
+func contextBudget(budgets map[string]int, model string) int {
+	budget, ok := budgets[model]
+	if !ok {
+		budget, ok = budgets["default"]
+	}
+	if !ok || budget <= 0 {
+		budget = defaultContextBudget
+	}
+
+	prompt := budget - ai.ChatReplyTokens
+	if prompt < budget/2 {
+		prompt = budget / 2
+	}
+	return prompt
+}
+
+// historyShare is the part of the budget the verbatim messages may fill
+// before the oldest are summarized. The rest is left to the system prompt,
+// the website content (at most a quarter) and the summary.
+func historyShare(budget int) int {
+	return budget / 2
+}
+
+// buildContext assembles the model input for a thread: the system prompt,
+// the website's current content, the running summary and as many of the
+// latest messages as fit the model's budget
+func (s *Service) buildContext(thread *models.ChatThread, budget int) ([]ai.Message, error) {
+	messages, err := s.unsummarized(thread)
+	if err != nil {
+		return nil, err
+	}
+
+	fixed := []ai.Message{{Role: "system", Content: SystemPrompt}}
+	if website := s.websiteContext(thread); website != "" {
+		fixed = append(fixed, ai.Message{Role: "system", Content: clipTokens(website, budget/4)})
+	}
+	if thread.Summary != "" {
+		fixed = append(fixed, ai.Message{Role: "system", Content: "Summary of the earlier conversation:\n" + thread.Summary})
+	}
+
+	history, dropped := assembleContext(budget, fixed, messages)
+	if dropped > 0 {
+		logrus.WithFields(logrus.Fields{"threadId": thread.ID, "dropped": dropped}).Info("Chat history exceeds the context budget")
+	}
+	return history, nil
+}
+
+// assembleContext appends the newest messages (given oldest first) to the
+// fixed messages while they fit the budget. The latest message is always
+// included. It returns the model input and how many messages were left out.
+func assembleContext(budget int, fixed []ai.Message, messages []models.ChatMessage) ([]ai.Message, int) {
+	remaining := budget - ai.EstimateMessageTokens(fixed...)
+
+	first := len(messages)
+	for first > 0 {
+		msg := ai.Message{Role: messages[first-1].Role, Content: messages[first-1].Content}
+		cost := ai.EstimateMessageTokens(msg)
+		if cost > remaining && first < len(messages) {
+			break
+		}
+		remaining -= cost
+		first--
+	}
+
+	history := make([]ai.Message, 0, len(fixed)+len(messages)-first)
+	history = append(history, fixed...)
+	for _, msg := range messages[first:] {
+		history = append(history, ai.Message{Role: msg.Role, Content: msg.Content})
+	}
+	return history, first
+}
+
+// unsummarized loads the thread's messages after its summary, oldest first
+func (s *Service) unsummarized(thread *models.ChatThread) ([]models.ChatMessage, error) {
+	query := s.db.DB.Where("thread_id = ?", thread.ID)
+	if thread.SummarizedUntil != nil {
+		query = query.Where("created_at > ?", *thread.SummarizedUntil)
+	}
+
+	var recent []models.ChatMessage
+	if err := query.Order("created_at DESC").Limit(maxLoadedMessages).Find(&recent).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chat history: %w", err)
+	}
+
+	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+		recent[i], recent[j] = recent[j], recent[i]
+	}
+	return recent, nil
+}
+
+// websiteContext describes the website a thread is about, with its current
+// generated content, so answers refer to what is actually on the site
+func (s *Service) websiteContext(thread *models.ChatThread) string {
+	if thread.WebsiteID == nil {
+		return ""
+	}
+
+	var website models.Website
+	if err := s.db.DB.Where("id = ? AND user_id = ?", *thread.WebsiteID, thread.UserID).First(&website).Error; err != nil {
+		logrus.WithError(err).WithField("websiteId", *thread.WebsiteID).Warn("Failed to load website for chat context")
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The user is working on the website %q (subdomain %q).", website.Title, website.Subdomain)
+	if website.Description != "" {
+		fmt.Fprintf(&b, " Description: %s", website.Description)
+	}
+	var content bytes.Buffer
+	if len(website.GeneratedContent) > 0 && json.Compact(&content, website.GeneratedContent) == nil {
+		b.WriteString("\nIts current content is:\n")
+		b.Write(content.Bytes())
+	}
+	if s.editor != nil {
+		var tokens bytes.Buffer
+		if len(website.DesignTokens) > 0 && json.Compact(&tokens, website.DesignTokens) == nil {
+			b.WriteString("\nIts design tokens are:\n")
+			b.Write(tokens.Bytes())
+		}
+		b.WriteString("\nWhen the user asks for a change, make it with the tools; sections are numbered from 0 in page order, " +
+			"and the sections of the home page are edited unless page names the slug of another page.")
+	}
+	return b.String()
+}
+
+// compact folds the oldest verbatim messages of a thread into its summary
+// once they outgrow their share of the budget or CHAT_HISTORY_SIZE. It runs
+// after a response, so the next one starts from the shorter context.
+func (s *Service) compact(thread *models.ChatThread, budget int) {
+	key := thread.ID.String()
+	if _, running := s.compacting.LoadOrStore(key, true); running {
+		return
+	}
+	defer s.compacting.Delete(key)
+
+	messages, err := s.unsummarized(thread)
+	if err != nil {
+		logrus.WithError(err).WithField("threadId", thread.ID).Warn("Failed to load chat history for summary")
+		return
+	}
+
+	fold := foldCount(messages, historyShare(budget), s.config.HistorySize)
+	if fold == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+	defer cancel()
+
+	summary, err := s.summarize(ctx, thread.Summary, messages[:fold], budget)
+	if err != nil {
+		logrus.WithError(err).WithField("threadId", thread.ID).Warn("Failed to summarize chat history")
+		return
+	}
+
+	// Only replace the summary this one was based on, in case another
+	// replica summarized the thread meanwhile
+	until := messages[fold-1].CreatedAt
+	query := s.db.DB.Model(&models.ChatThread{}).Where("id = ?", thread.ID)
+	if thread.SummarizedUntil != nil {
+		query = query.Where("summarized_until = ?", *thread.SummarizedUntil)
+	} else {
+		query = query.Where("summarized_until IS NULL")
+	}
+	if err := query.Updates(map[string]interface{}{"summary": summary, "summarized_until": until}).Error; err != nil {
+		logrus.WithError(err).WithField("threadId", thread.ID).Warn("Failed to store chat summary")
+		return
+	}
+	logrus.WithFields(logrus.Fields{"threadId": thread.ID, "folded": fold}).Info("Chat history summarized")
+}
+
+// foldCount returns how many of the oldest messages to summarize. Nothing
+// is folded until the messages pass three quarters of their token share or
+// maxVerbatim messages; then enough are folded to get back to half of
+// either, so summaries are not regenerated on every turn.
+func foldCount(messages []models.ChatMessage, share, maxVerbatim int) int {
+	if maxVerbatim <= 0 {
+		maxVerbatim = 20
+	}
+
+	total := 0
+	for _, msg := range messages {
+		total += ai.EstimateMessageTokens(ai.Message{Role: msg.Role, Content: msg.Content})
+	}
+	if total <= share*3/4 && len(messages) <= maxVerbatim {
+		return 0
+	}
+
+	fold := 0
+	for fold < len(messages)-keepVerbatim && (total > share/2 || len(messages)-fold > maxVerbatim/2) {
+		total -= ai.EstimateMessageTokens(ai.Message{Role: messages[fold].Role, Content: messages[fold].Content})
+		fold++
+	}
+	return fold
+}
+
+// summarize asks the model to fold messages into the previous summary
+func (s *Service) summarize(ctx context.Context, previous string, messages []models.ChatMessage, budget int) (string, error) {
+	if previous == "" {
+		previous = "(none)"
+	}
+
+	var transcript strings.Builder
+	for _, msg := range messages {
+		role := "User"
+		if msg.Role == "assistant" {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, msg.Content)
+	}
+
+	prompt := fmt.Sprintf("Current summary:\n%s\n\nNew messages:\n%s", previous, transcript.String())
+	resp, err := s.kimi.ChatCompletion(ctx, []ai.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: clipTokens(prompt, budget-summaryMaxTokens)},
+	}, summaryMaxTokens)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// clipTokens shortens text to about limit tokens, as EstimateTokens counts
+// them
+func clipTokens(text string, limit int) string {
+	if ai.EstimateTokens(text) <= limit {
+		return text
+	}
+
+	// Count in quarter tokens: an ASCII character is a quarter, others a whole
+	quarters := 0
+	for i, r := range text {
+		if r < utf8.RuneSelf {
+			quarters++
+		} else {
+			quarters += 4
+		}
+		if quarters > (limit-1)*4 {
+			return text[:i] + "…"
+		}
+	}
+	return text
+}
@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chatMessages(contents ...string) []models.ChatMessage {
+	messages := make([]models.ChatMessage, len(contents))
+	for i, content := range contents {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages[i] = models.ChatMessage{Role: role, Content: content}
+	}
+	return messages
+}
+
+func TestContextBudget(t *testing.T) {
+	budgets := map[string]int{"default": 8000, "big": 128000}
+
+	assert.Equal(t, 128000-ai.ChatReplyTokens, contextBudget(budgets, "big"))
+	assert.Equal(t, 8000-ai.ChatReplyTokens, contextBudget(budgets, "unknown"))
+	assert.Equal(t, defaultContextBudget-ai.ChatReplyTokens, contextBudget(nil, "unknown"))
+
+	// Small windows keep at least half for the prompt
+	assert.Equal(t, 1500, contextBudget(map[string]int{"tiny": 3000}, "tiny"))
+}
+
+func TestAssembleContext(t *testing.T) {
+	fixed := []ai.Message{{Role: "system", Content: SystemPrompt}}
+	messages := chatMessages(strings.Repeat("a", 400), "short answer", "latest question")
+
+	// Everything fits
+	history, dropped := assembleContext(1000, fixed, messages)
+	assert.Equal(t, 0, dropped)
+	assert.Len(t, history, 4)
+	assert.Equal(t, "system", history[0].Role)
+	assert.Equal(t, "latest question", history[3].Content)
+
+	// The oldest message is left out, the system prompt is kept
+	budget := ai.EstimateMessageTokens(fixed...) + 30
+	history, dropped = assembleContext(budget, fixed, messages)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, SystemPrompt, history[0].Content)
+	assert.Equal(t, "short answer", history[1].Content)
+
+	// The latest message is sent even when nothing else fits
+	history, dropped = assembleContext(0, fixed, messages)
+	assert.Equal(t, 2, dropped)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "latest question", history[1].Content)
+}
+
+func TestFoldCount(t *testing.T) {
+	long := strings.Repeat("word ", 80) // about 100 tokens
+
+	// Under the thresholds nothing is folded
+	assert.Equal(t, 0, foldCount(chatMessages("hi", "hello"), 1000, 20))
+
+	// Over the token share, fold back to half of it
+	messages := chatMessages(long, long, long, long, long, long, long, long)
+	fold := foldCount(messages, 800, 20)
+	assert.Equal(t, 5, fold)
+
+	// Over the message count, fold back to half of it
+	many := chatMessages(strings.Split(strings.Repeat("x ", 12), " ")[:12]...)
+	assert.Equal(t, 7, foldCount(many, 100000, 10))
+
+	// The latest messages are never folded
+	assert.Equal(t, 2, foldCount(chatMessages(long, long, long, long), 10, 20))
+}
+
+func TestClipTokens(t *testing.T) {
+	assert.Equal(t, "short", clipTokens("short", 10))
+
+	clipped := clipTokens(strings.Repeat("abcd", 100), 10)
+	assert.True(t, strings.HasSuffix(clipped, "…"))
+	assert.LessOrEqual(t, ai.EstimateTokens(clipped), 10)
+
+	assert.Equal(t, 3, ai.EstimateTokens("ünïc"))
+	assert.Equal(t, 1, ai.EstimateTokens("abcd"))
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownEvent is returned when resuming from an event that is not (or
+// no longer) in the log
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Event is one server-sent event of a response. IDs have the form
+// "<responseID>:<n>" with n counting from 1 within the response.
+type Event struct {
+	ID   string
+	Name string
+	Data any
+}
+
+// EventLog records the events of recent responses so that SSE clients can
+// reconnect with Last-Event-ID and receive what they missed. Finished
+// responses are kept for the retention period.
+type EventLog struct {
+	mu        sync.Mutex
+	retention time.Duration
+	responses map[string]*responseLog
+}
+
+type responseLog struct {
+	userID     uuid.UUID
+	events     []Event
+	done       bool
+	finishedAt time.Time
+	changed    chan struct{} // closed and replaced on every change
+	// followers counts the clients following the response; attached is
+	// closed and replaced whenever it changes
+	followers int
+	attached  chan struct{}
+}
+
+// NewEventLog creates an event log
+func NewEventLog(retention time.Duration) *EventLog {
+	return &EventLog{
+		retention: retention,
+		responses: make(map[string]*responseLog),
+	}
+}
+
+// Start opens the log of a new response
+func (l *EventLog) Start(responseID string, userID uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop finished responses past retention so the log stays bounded
+	cutoff := time.Now().Add(-l.retention)
+	for id, response := range l.responses {
+		if response.done && response.finishedAt.Before(cutoff) {
+			delete(l.responses, id)
+		}
+	}
+
+	l.responses[responseID] = &responseLog{
+		userID:   userID,
+		changed:  make(chan struct{}),
+		attached: make(chan struct{}),
+	}
+}
+
+// Append adds an event to a response
+func (l *EventLog) Append(responseID, name string, data any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	response, ok := l.responses[responseID]
+	if !ok || response.done {
+		return
+	}
+
+	response.events = append(response.events, Event{
+		ID:   responseID + ":" + strconv.Itoa(len(response.events)+1),
+		Name: name,
+		Data: data,
+	})
+	close(response.changed)
+	response.changed = make(chan struct{})
+}
+
+// Finish marks a response as complete; followers stop after its last event
+func (l *EventLog) Finish(responseID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	response, ok := l.responses[responseID]
+	if !ok || response.done {
+		return
+	}
+	response.done = true
+	response.finishedAt = time.Now()
+	close(response.changed)
+}
+
+// Resolve parses a Last-Event-ID and checks it belongs to the user
+func (l *EventLog) Resolve(userID uuid.UUID, lastEventID string) (string, int, error) {
+	responseID, n, ok := strings.Cut(lastEventID, ":")
+	after, err := strconv.Atoi(n)
+	if !ok || err != nil || after < 0 {
+		return "", 0, ErrUnknownEvent
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	response, exists := l.responses[responseID]
+	if !exists || response.userID != userID || after > len(response.events) {
+		return "", 0, ErrUnknownEvent
+	}
+	return responseID, after, nil
+}
+
+// Follow emits the response's events after the first `after` ones, then
+// waits for new events until the response finishes or ctx is done.
+// onIdle is called whenever no event arrived for idleInterval, which the
+// SSE handler uses to send heartbeats.
+func (l *EventLog) Follow(ctx context.Context, responseID string, after int, idleInterval time.Duration, emit func(Event) error, onIdle func() error) error {
+	if !l.attach(responseID, 1) {
+		return fmt.Errorf("response %s: %w", responseID, ErrUnknownEvent)
+	}
+	defer l.attach(responseID, -1)
+
+	idle := time.NewTicker(idleInterval)
+	defer idle.Stop()
+
+	next := after
+	for {
+		l.mu.Lock()
+		response, ok := l.responses[responseID]
+		if !ok {
+			l.mu.Unlock()
+			return fmt.Errorf("response %s: %w", responseID, ErrUnknownEvent)
+		}
+		pending := append([]Event(nil), response.events[next:]...)
+		done := response.done
+		changed := response.changed
+		l.mu.Unlock()
+
+		for _, event := range pending {
+			if err := emit(event); err != nil {
+				return err
+			}
+		}
+		next += len(pending)
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+			idle.Reset(idleInterval)
+		case <-idle.C:
+			if err := onIdle(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// attach counts a follower joining (delta 1) or leaving (delta -1) a
+// response, reporting whether the response is known
+func (l *EventLog) attach(responseID string, delta int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	response, ok := l.responses[responseID]
+	if !ok {
+		return false
+	}
+	response.followers += delta
+	close(response.attached)
+	response.attached = make(chan struct{})
+	return true
+}
+
+// Supervise stops the stream with TruncatedDisconnected once nobody has
+// followed its response for grace, i.e. the client went away and did not
+// reconnect. Until then the response keeps being generated so a client
+// that resumes receives all of it. It returns when the stream ends.
+func (l *EventLog) Supervise(stream *Stream, grace time.Duration) {
+	if l.awaitAbandoned(stream.ctx, stream.ID, grace) {
+		stream.Stop(TruncatedDisconnected)
+	}
+}
+
+// awaitAbandoned waits until the response has had no follower for grace
+// and reports true, or reports false once it finishes or ctx is done
+func (l *EventLog) awaitAbandoned(ctx context.Context, responseID string, grace time.Duration) bool {
+	for {
+		l.mu.Lock()
+		response, ok := l.responses[responseID]
+		if !ok || response.done {
+			l.mu.Unlock()
+			return false
+		}
+		followers, attached, changed := response.followers, response.attached, response.changed
+		l.mu.Unlock()
+
+		if followers > 0 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-attached:
+			case <-changed:
+			}
+			continue
+		}
+
+		timer := time.NewTimer(grace)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-attached:
+			timer.Stop()
+		case <-timer.C:
+			// The response may have finished meanwhile
+			l.mu.Lock()
+			abandoned := !response.done && response.followers == 0
+			l.mu.Unlock()
+			return abandoned
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend-go/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, log *EventLog, responseID string, after int) []Event {
+	t.Helper()
+
+	var events []Event
+	err := log.Follow(context.Background(), responseID, after, time.Second, func(event Event) error {
+		events = append(events, event)
+		return nil
+	}, func() error { return nil })
+	require.NoError(t, err)
+	return events
+}
+
+func TestEventLog_Resume(t *testing.T) {
+	log := NewEventLog(time.Minute)
+	userID := uuid.New()
+
+	log.Start("resp", userID)
+	log.Append("resp", "chat:stream", "Hel")
+	log.Append("resp", "chat:stream", "lo")
+	log.Append("resp", "chat:message", "Hello")
+	log.Finish("resp")
+
+	// Events appended after Finish are ignored
+	log.Append("resp", "chat:stream", "late")
+
+	events := collect(t, log, "resp", 0)
+	require.Len(t, events, 3)
+	assert.Equal(t, "resp:1", events[0].ID)
+	assert.Equal(t, "chat:message", events[2].Name)
+
+	responseID, after, err := log.Resolve(userID, "resp:1")
+	require.NoError(t, err)
+	assert.Equal(t, "resp", responseID)
+
+	events = collect(t, log, responseID, after)
+	require.Len(t, events, 2)
+	assert.Equal(t, "lo", events[0].Data)
+}
+
+func TestEventLog_ResolveRejects(t *testing.T) {
+	log := NewEventLog(time.Minute)
+	owner := uuid.New()
+	log.Start("resp", owner)
+	log.Append("resp", "chat:stream", "x")
+
+	for _, id := range []string{"resp", "resp:x", "resp:5", "other:1"} {
+		_, _, err := log.Resolve(owner, id)
+		assert.ErrorIs(t, err, ErrUnknownEvent, id)
+	}
+
+	// Another user's stream cannot be resumed
+	_, _, err := log.Resolve(uuid.New(), "resp:1")
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
+
+func TestEventLog_FollowLive(t *testing.T) {
+	log := NewEventLog(time.Minute)
+	log.Start("resp", uuid.New())
+
+	received := make(chan Event, 10)
+	heartbeats := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- log.Follow(context.Background(), "resp", 0, 20*time.Millisecond, func(event Event) error {
+			received <- event
+			return nil
+		}, func() error {
+			heartbeats <- struct{}{}
+			return nil
+		})
+	}()
+
+	// Quiet periods produce heartbeats
+	select {
+	case <-heartbeats:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for heartbeat")
+	}
+
+	log.Append("resp", "chat:stream", "live")
+	select {
+	case event := <-received:
+		assert.Equal(t, "live", event.Data)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+
+	log.Finish("resp")
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Follow did not return after Finish")
+	}
+}
+
+func TestEventLog_FollowStopsOnEmitError(t *testing.T) {
+	log := NewEventLog(time.Minute)
+	log.Start("resp", uuid.New())
+	log.Append("resp", "chat:stream", "x")
+
+	writeErr := errors.New("client gone")
+	err := log.Follow(context.Background(), "resp", 0, time.Second, func(Event) error {
+		return writeErr
+	}, func() error { return nil })
+	assert.ErrorIs(t, err, writeErr)
+}
+
+func TestEventLog_ResumeAfterDisconnect(t *testing.T) {
+	log := NewEventLog(time.Minute)
+	service := NewService(nil, nil, nil, nil, nil, &config.ChatConfig{ResponseTimeout: time.Minute})
+	userID := uuid.New()
+	stream, err := service.Begin(context.Background(), userID)
+	require.NoError(t, err)
+	log.Start(stream.ID, userID)
+	go log.Supervise(stream, 50*time.Millisecond)
+
+	log.Append(stream.ID, "chat:stream", "one")
+	log.Append(stream.ID, "chat:stream", "two")
+
+	// The client drops after the second chunk
+	ctx, disconnect := context.WithCancel(context.Background())
+	var lastEventID string
+	err = log.Follow(ctx, stream.ID, 0, time.Second, func(event Event) error {
+		lastEventID = event.ID
+		if event.Data == "two" {
+			disconnect()
+		}
+		return nil
+	}, func() error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Generation goes on while the client is away
+	log.Append(stream.ID, "chat:stream", "three")
+	require.NoError(t, stream.ctx.Err(), "the response outlives the connection")
+
+	responseID, after, err := log.Resolve(userID, lastEventID)
+	require.NoError(t, err)
+	resumed := make(chan []Event)
+	go func() { resumed <- collect(t, log, responseID, after) }()
+
+	// A follower keeps the response alive past the grace period
+	time.Sleep(150 * time.Millisecond)
+	require.NoError(t, stream.ctx.Err())
+	log.Append(stream.ID, "chat:message", "one two three four")
+	log.Finish(stream.ID)
+
+	var data []any
+	for _, event := range <-resumed {
+		data = append(data, event.Data)
+	}
+	assert.Equal(t, []any{"three", "one two three four"}, data)
+	service.End(userID, stream)
+}
+
+func TestEventLog_SuperviseStopsAbandonedResponse(t *testing.T) {
+	log := NewEventLog(time.Minute)
+	service := NewService(nil, nil, nil, nil, nil, &config.ChatConfig{ResponseTimeout: time.Minute})
+	stream, err := service.Begin(context.Background(), uuid.New())
+	require.NoError(t, err)
+	log.Start(stream.ID, uuid.New())
+
+	go log.Supervise(stream, 20*time.Millisecond)
+	select {
+	case <-stream.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("a response nobody follows was not stopped")
+	}
+	assert.Equal(t, TruncatedDisconnected, stream.truncation())
+}
+
+func TestService_OneStreamPerUser(t *testing.T) {
+	service := NewService(nil, nil, nil, nil, nil, &config.ChatConfig{ResponseTimeout: time.Minute})
+	userID := uuid.New()
+
+	stream, err := service.Begin(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Same(t, stream, service.Active(userID))
+
+	_, err = service.Begin(context.Background(), userID)
+	assert.ErrorIs(t, err, ErrBusy)
+
+	// Other users are independent
+	other, err := service.Begin(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.NotSame(t, stream, other)
+
+	stream.Stop(TruncatedCancelled)
+	assert.Equal(t, TruncatedCancelled, stream.truncation())
+
+	service.End(userID, stream)
+	assert.Nil(t, service.Active(userID))
+	<-stream.Done()
+
+	_, err = service.Begin(context.Background(), userID)
+	assert.NoError(t, err)
+}
+
+func TestStream_TruncationReason(t *testing.T) {
+	service := NewService(nil, nil, nil, nil, nil, &config.ChatConfig{ResponseTimeout: time.Millisecond})
+
+	// Parent cancelled means the client went away
+	parent, cancel := context.WithCancel(context.Background())
+	stream, err := service.Begin(parent, uuid.New())
+	require.NoError(t, err)
+	cancel()
+	assert.Equal(t, TruncatedDisconnected, stream.truncation())
+
+	// Otherwise the response ran out of time
+	stream, err = service.Begin(context.Background(), uuid.New())
+	require.NoError(t, err)
+	<-stream.ctx.Done()
+	assert.Equal(t, TruncatedTimeout, stream.truncation())
+}
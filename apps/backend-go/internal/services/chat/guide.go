@@ -0,0 +1,411 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+	"backend-go/internal/services/website"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+)
+
+// GuideStep is a step of the guided site-building conversation
+type GuideStep string
+
+const (
+	StepBusinessType   GuideStep = "business_type"
+	StepBusinessName   GuideStep = "business_name"
+	StepTargetAudience GuideStep = "target_audience"
+	StepFeatures       GuideStep = "features"
+	StepStyle          GuideStep = "style"
+	StepContactInfo    GuideStep = "contact_info"
+	StepComplete       GuideStep = "complete"
+)
+
+// GuideSteps lists the questions of the guided conversation in order
+var GuideSteps = []GuideStep{StepBusinessType, StepBusinessName, StepTargetAudience, StepFeatures, StepStyle, StepContactInfo}
+
+// Guide errors
+var (
+	ErrNotGuided    = errors.New("thread is not guided")
+	ErrCannotGoBack = errors.New("cannot go back")
+)
+
+// stepQuestions are asked for each step. Clients may show their own,
+// localized text for the step instead.
+var stepQuestions = map[GuideStep]string{
+	StepBusinessType:   "What kind of business or activity is the website for? For example a restaurant, coffee shop, personal portfolio, online store or consultancy.",
+	StepBusinessName:   "What is the name of your business or brand? It will be the main title of the website.",
+	StepTargetAudience: "Who are your target audience or main customers? For example young adults, professionals or families.",
+	StepFeatures:       "Which features should the website have? For example a photo gallery, menu, testimonials, contact form or blog. You can list several.",
+	StepStyle:          "What design style would you like, for example modern and minimal, elegant or playful? And which colors do you like?",
+	StepContactInfo:    "Which contact information should the website show? For example WhatsApp, email, address or opening hours.",
+}
+
+// Guide is the state of a guided conversation: the step being asked and the
+// answers collected so far. It is stored on the thread.
+type Guide struct {
+	Step  GuideStep     `json:"step"`
+	Brief website.Brief `json:"brief"`
+}
+
+// NewGuide starts a guided conversation at the first step
+func NewGuide() *Guide {
+	return &Guide{Step: GuideSteps[0]}
+}
+
+// Missing lists the steps without an answer, in order
+func (g *Guide) Missing() []GuideStep {
+	var missing []GuideStep
+	for _, step := range GuideSteps {
+		if !g.answered(step) {
+			missing = append(missing, step)
+		}
+	}
+	return missing
+}
+
+func (g *Guide) answered(step GuideStep) bool {
+	b := &g.Brief
+	switch step {
+	case StepBusinessType:
+		return b.BusinessType != ""
+	case StepBusinessName:
+		return b.BusinessName != ""
+	case StepTargetAudience:
+		return b.TargetAudience != ""
+	case StepFeatures:
+		return len(b.Features) > 0
+	case StepStyle:
+		return b.Style != ""
+	case StepContactInfo:
+		return b.ContactInfo != ""
+	}
+	return false
+}
+
+// Complete reports whether every step has been answered
+func (g *Guide) Complete() bool {
+	return g.Step == StepComplete
+}
+
+// Advance moves to the first step still missing an answer
+func (g *Guide) Advance() {
+	if missing := g.Missing(); len(missing) > 0 {
+		g.Step = missing[0]
+		return
+	}
+	g.Step = StepComplete
+}
+
+// Back returns to the step before the current one so its answer can be
+// changed; from complete that is the last step. It reports false on the
+// first step.
+func (g *Guide) Back() bool {
+	index := g.StepNumber() - 1
+	if index <= 0 {
+		return false
+	}
+	g.Step = GuideSteps[index-1]
+	return true
+}
+
+// Apply merges the non-empty fields of an extracted brief into the answers
+func (g *Guide) Apply(answers website.Brief) {
+	b := &g.Brief
+	if answers.BusinessType != "" {
+		b.BusinessType = answers.BusinessType
+	}
+	if answers.BusinessName != "" {
+		b.BusinessName = answers.BusinessName
+	}
+	if answers.TargetAudience != "" {
+		b.TargetAudience = answers.TargetAudience
+	}
+	if len(answers.Features) > 0 {
+		b.Features = answers.Features
+	}
+	if answers.Style != "" {
+		b.Style = answers.Style
+	}
+	if answers.ColorPreference != "" {
+		b.ColorPreference = answers.ColorPreference
+	}
+	if answers.ContactInfo != "" {
+		b.ContactInfo = answers.ContactInfo
+	}
+}
+
+// Answer records text as the answer to the current step, for when the
+// model could not extract structured answers
+func (g *Guide) Answer(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	var answers website.Brief
+	switch g.Step {
+	case StepBusinessType:
+		answers.BusinessType = text
+	case StepBusinessName:
+		answers.BusinessName = text
+	case StepTargetAudience:
+		answers.TargetAudience = text
+	case StepFeatures:
+		answers.Features = splitFeatures(text)
+	case StepStyle:
+		answers.Style = text
+	case StepContactInfo:
+		answers.ContactInfo = text
+	}
+	g.Apply(answers)
+}
+
+// StepNumber is the 1-based position of the current step, or one past the
+// last step once complete
+func (g *Guide) StepNumber() int {
+	for i, step := range GuideSteps {
+		if step == g.Step {
+			return i + 1
+		}
+	}
+	return len(GuideSteps) + 1
+}
+
+// Question asks for the current step's answer
+func (g *Guide) Question() string {
+	return stepQuestions[g.Step]
+}
+
+// Response is the public guide data
+func (g *Guide) Response() map[string]interface{} {
+	return map[string]interface{}{
+		"step":       g.Step,
+		"stepNumber": g.StepNumber(),
+		"totalSteps": len(GuideSteps),
+		"brief":      g.Brief,
+		"missing":    g.Missing(),
+		"question":   g.Question(),
+	}
+}
+
+var featureSeparators = regexp.MustCompile(`[,;\n]+`)
+
+// splitFeatures splits a list of features written as text
+func splitFeatures(text string) []string {
+	var features []string
+	for _, feature := range featureSeparators.Split(text, -1) {
+		if feature = strings.TrimSpace(feature); feature != "" {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// LoadGuide returns the guide stored on a thread, nil for free-form threads
+func LoadGuide(thread *models.ChatThread) (*Guide, error) {
+	if len(thread.Guide) == 0 || string(thread.Guide) == "null" {
+		return nil, nil
+	}
+	var guide Guide
+	if err := json.Unmarshal(thread.Guide, &guide); err != nil {
+		return nil, fmt.Errorf("invalid guide state: %w", err)
+	}
+	return &guide, nil
+}
+
+// saveGuide stores the guide on the thread
+func (s *Service) saveGuide(thread *models.ChatThread, guide *Guide) error {
+	data, err := json.Marshal(guide)
+	if err != nil {
+		return err
+	}
+	thread.Guide = datatypes.JSON(data)
+	if err := s.db.DB.Model(thread).Update("guide", thread.Guide).Error; err != nil {
+		return fmt.Errorf("failed to save guide: %w", err)
+	}
+	return nil
+}
+
+// extractPrompt asks the model for the brief fields in a user's message
+const extractPrompt = `You collect the brief for a new website in a guided conversation with these steps, in order: business_type, business_name, target_audience, features, style, contact_info.
+Answers so far (JSON): %s
+The user was asked about: %s
+Extract every brief field the user's latest message provides, keeping the user's language. Return ONLY JSON of this form:
+{"businessType": "", "businessName": "", "targetAudience": "", "features": [], "style": "", "colorPreference": "", "contactInfo": "", "back": false}
+Leave fields empty when the message does not provide them. Set "back" to true only when the user asks to go back to or change the previous answer.`
+
+// extraction is the model's reading of a guided turn
+type extraction struct {
+	website.Brief
+	Back bool `json:"back"`
+}
+
+// extract reads the answers in a user's message. It returns false when the
+// model's reply could not be used.
+func (s *Service) extract(ctx context.Context, guide *Guide, content string) (extraction, bool) {
+	brief, _ := json.Marshal(guide.Brief)
+	resp, err := s.kimi.ChatCompletion(ctx, []ai.Message{
+		{Role: "system", Content: fmt.Sprintf(extractPrompt, brief, guide.Step)},
+		{Role: "user", Content: content},
+	}, 512)
+	if err != nil || len(resp.Choices) == 0 {
+		logrus.WithError(err).Warn("Failed to extract guide answers")
+		return extraction{}, false
+	}
+
+	var result extraction
+	if err := json.Unmarshal([]byte(website.ExtractJSON(resp.Choices[0].Message.Content)), &result); err != nil {
+		return extraction{}, false
+	}
+	return result, true
+}
+
+// Guiding reports whether a thread is in a guided conversation that has not
+// produced its website yet. Afterwards the thread is a regular chat about
+// the generated website.
+func Guiding(thread *models.ChatThread) bool {
+	return len(thread.Guide) > 0 && string(thread.Guide) != "null" && thread.WebsiteID == nil
+}
+
+// Guide answers a user message in a guided thread: it records the answers
+// the message contains, asks for the next missing one and, once the brief is
+// complete, generates the website and binds the thread to it. A failed
+// generation is retried with the next message. The message must already
+// have been added with AddUserMessage.
+func (s *Service) Guide(stream *Stream, thread *models.ChatThread, content string) (*Reply, error) {
+	defer s.End(thread.UserID, stream)
+
+	if !Guiding(thread) {
+		return nil, ErrNotGuided
+	}
+	guide, err := LoadGuide(thread)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := s.extract(stream.ctx, guide, content)
+	switch {
+	case ok && result.Back:
+		guide.Back()
+	case ok:
+		guide.Apply(result.Brief)
+		guide.Advance()
+	default:
+		guide.Answer(content)
+		guide.Advance()
+	}
+	if err := s.saveGuide(thread, guide); err != nil {
+		return nil, err
+	}
+
+	reply := &Reply{ID: stream.ID, ThreadID: thread.ID, Guide: guide}
+	if guide.Complete() {
+		site, err := s.generate(stream.ctx, thread, guide)
+		if err != nil {
+			return nil, err
+		}
+		reply.Website = site
+		reply.Content = fmt.Sprintf("Your website %q is ready at %s. Tell me what you would like to change.", site.Title, site.Subdomain)
+	} else {
+		reply.Content = guide.Question()
+	}
+
+	if err := s.save(thread, "assistant", reply.Content, false); err != nil {
+		logrus.WithError(err).Error("Failed to save chat message")
+	}
+	reply.TokensUsed = s.bill(thread.UserID, thread.WebsiteID)
+	return reply, nil
+}
+
+// generate creates the website described by a complete guide and binds the
+// thread to it
+func (s *Service) generate(ctx context.Context, thread *models.ChatThread, guide *Guide) (*models.Website, error) {
+	subdomain, err := s.generator.AvailableSubdomain(guide.Brief.BusinessName)
+	if err != nil {
+		return nil, err
+	}
+
+	brief := guide.Brief
+	result, err := s.generator.Generate(ctx, website.GenerateRequest{
+		UserID:     thread.UserID,
+		TemplateID: website.TemplateFor(brief.Style),
+		Subdomain:  subdomain,
+		Brief:      &brief,
+	})
+	if err != nil {
+		if errors.Is(err, website.ErrInsufficientTokens) {
+			return nil, ErrInsufficientTokens
+		}
+		return nil, fmt.Errorf("failed to generate website: %w", err)
+	}
+
+	thread.WebsiteID = &result.Website.ID
+	if err := s.db.DB.Model(thread).Update("website_id", thread.WebsiteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to bind thread to website: %w", err)
+	}
+	return result.Website, nil
+}
+
+// StartGuide creates a guided thread and stores its first question
+func (s *Service) StartGuide(userID uuid.UUID, title string) (*models.ChatThread, *Guide, error) {
+	thread, err := s.CreateThread(userID, nil, title)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	guide := NewGuide()
+	if err := s.saveGuide(thread, guide); err != nil {
+		return nil, nil, err
+	}
+	if err := s.save(thread, "assistant", guide.Question(), false); err != nil {
+		return nil, nil, err
+	}
+	return thread, guide, nil
+}
+
+// GuideBack returns a guided thread to its previous step and stores the
+// question for it
+func (s *Service) GuideBack(userID, threadID uuid.UUID) (*Guide, error) {
+	thread, guide, err := s.GuideState(userID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if !Guiding(thread) || !guide.Back() {
+		return nil, ErrCannotGoBack
+	}
+
+	if err := s.saveGuide(thread, guide); err != nil {
+		return nil, err
+	}
+	if err := s.save(thread, "assistant", guide.Question(), false); err != nil {
+		return nil, err
+	}
+	return guide, nil
+}
+
+// GuideState returns a thread with its guide
+func (s *Service) GuideState(userID, threadID uuid.UUID) (*models.ChatThread, *Guide, error) {
+	thread, err := s.GetThread(userID, threadID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	guide, err := LoadGuide(thread)
+	if err != nil {
+		return nil, nil, err
+	}
+	if guide == nil {
+		return nil, nil, ErrNotGuided
+	}
+	return thread, guide, nil
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"testing"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/website"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestGuide_StepByStep(t *testing.T) {
+	guide := NewGuide()
+	assert.Equal(t, StepBusinessType, guide.Step)
+	assert.Equal(t, 1, guide.StepNumber())
+	assert.NotEmpty(t, guide.Question())
+
+	answers := []string{"Bakery", "Sweet Bites", "Families", "Menu, gallery\nContact form", "Warm and playful", "hello@sweetbites.test"}
+	for _, answer := range answers {
+		assert.False(t, guide.Complete())
+		guide.Answer(answer)
+		guide.Advance()
+	}
+
+	assert.True(t, guide.Complete())
+	assert.Empty(t, guide.Missing())
+	assert.Equal(t, []string{"Menu", "gallery", "Contact form"}, guide.Brief.Features)
+	assert.Equal(t, len(GuideSteps)+1, guide.StepNumber())
+}
+
+func TestGuide_ApplySkipsAnsweredSteps(t *testing.T) {
+	guide := NewGuide()
+
+	// One message can answer several steps
+	guide.Apply(website.Brief{BusinessType: "Coffee shop", BusinessName: "Kopi Kita"})
+	guide.Advance()
+	assert.Equal(t, StepTargetAudience, guide.Step)
+
+	// Answers to later steps are kept, and earlier gaps are asked first
+	guide.Apply(website.Brief{Style: "Minimal"})
+	guide.Advance()
+	assert.Equal(t, StepTargetAudience, guide.Step)
+	assert.Equal(t, []GuideStep{StepTargetAudience, StepFeatures, StepContactInfo}, guide.Missing())
+
+	// Empty fields do not erase answers
+	guide.Apply(website.Brief{BusinessName: ""})
+	assert.Equal(t, "Kopi Kita", guide.Brief.BusinessName)
+}
+
+func TestGuide_Back(t *testing.T) {
+	guide := NewGuide()
+	assert.False(t, guide.Back())
+
+	guide.Answer("Bakery")
+	guide.Advance()
+	guide.Answer("Sweet Bites")
+	guide.Advance()
+	assert.Equal(t, StepTargetAudience, guide.Step)
+
+	// Going back re-asks the previous step and the new answer replaces it
+	assert.True(t, guide.Back())
+	assert.Equal(t, StepBusinessName, guide.Step)
+	guide.Answer("Sweeter Bites")
+	guide.Advance()
+	assert.Equal(t, "Sweeter Bites", guide.Brief.BusinessName)
+	assert.Equal(t, StepTargetAudience, guide.Step)
+
+	// From complete, back is the last step
+	guide.Step = StepComplete
+	assert.True(t, guide.Back())
+	assert.Equal(t, StepContactInfo, guide.Step)
+}
+
+func TestGuiding(t *testing.T) {
+	thread := &models.ChatThread{}
+	assert.False(t, Guiding(thread))
+
+	thread.Guide = datatypes.JSON(`{"step":"business_type"}`)
+	assert.True(t, Guiding(thread))
+
+	guide, err := LoadGuide(thread)
+	assert.NoError(t, err)
+	assert.Equal(t, StepBusinessType, guide.Step)
+
+	// Once the website exists the thread is a regular chat about it
+	websiteID := uuid.New()
+	thread.WebsiteID = &websiteID
+	assert.False(t, Guiding(thread))
+}
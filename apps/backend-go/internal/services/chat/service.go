@@ -0,0 +1,373 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go/internal/config"
+	"backend-go/internal/database"
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+	"backend-go/internal/services/editor"
+	"backend-go/internal/services/token"
+	"backend-go/internal/services/website"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// SystemPrompt opens every conversation
+const SystemPrompt = "You are a helpful AI assistant for SiteSpark, an AI-powered website builder. Help users create and improve their websites."
+
+// maxToolRounds bounds how many times one response may call tools before
+// it has to answer in text
+const maxToolRounds = 4
+
+// Reasons a response was cut short
+const (
+	TruncatedCancelled    = "cancelled"
+	TruncatedRegenerated  = "regenerated"
+	TruncatedDisconnected = "disconnected"
+	TruncatedTimeout      = "timeout"
+)
+
+// Chat errors
+var (
+	ErrBusy                = errors.New("a response is already being generated")
+	ErrInsufficientTokens  = errors.New("insufficient tokens")
+	ErrNothingToRegenerate = errors.New("nothing to regenerate")
+)
+
+// Service runs AI chat conversations: it stores messages in threads, allows
+// one response per user at a time and bills responses. The WebSocket and
+// SSE transports both go through it.
+type Service struct {
+	db        *database.Database
+	kimi      *ai.KimiClient
+	tokenMgr  *token.Manager
+	generator *website.Generator
+	editor    *editor.Editor
+	config    *config.ChatConfig
+
+	streamsMu sync.Mutex
+	streams   map[string]*Stream // userID -> response being generated
+
+	compacting sync.Map // threadID -> summary being generated
+}
+
+// NewService creates a chat service
+func NewService(db *database.Database, kimi *ai.KimiClient, tokenMgr *token.Manager, generator *website.Generator, editor *editor.Editor, cfg *config.ChatConfig) *Service {
+	return &Service{
+		db:        db,
+		kimi:      kimi,
+		tokenMgr:  tokenMgr,
+		generator: generator,
+		editor:    editor,
+		config:    cfg,
+		streams:   make(map[string]*Stream),
+	}
+}
+
+// Stream is a response being generated for a user
+type Stream struct {
+	ID string
+
+	ctx    context.Context
+	parent context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	reason   string
+	threadID uuid.UUID
+}
+
+// Stop cancels the response, recording why
+func (s *Stream) Stop(reason string) {
+	s.mu.Lock()
+	if s.reason == "" {
+		s.reason = reason
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// Done is closed once the response has finished and been recorded
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// inThread reports whether the response answers the given thread
+func (s *Stream) inThread(threadID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.threadID == threadID
+}
+
+// truncation explains why the response ended early
+func (s *Stream) truncation() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.reason != "":
+		return s.reason
+	case s.parent.Err() != nil:
+		return TruncatedDisconnected
+	default:
+		return TruncatedTimeout
+	}
+}
+
+// Reply is the outcome of a response
+type Reply struct {
+	ID       string
+	ThreadID uuid.UUID
+	Content  string
+	// Truncated is the reason the response was cut short, empty if complete
+	Truncated  string
+	TokensUsed int
+	// Guide is the state of a guided thread after the turn
+	Guide *Guide
+	// Website is the website generated when a guided thread completed
+	Website *models.Website
+	// Edits are the changes made to the thread's website through tools
+	Edits []editor.Edit
+}
+
+// Begin reserves the user's response slot. The stream's context derives
+// from parent, so cancelling parent (e.g. the client disconnecting) aborts
+// the upstream request. Every stream must be passed to Respond or End.
+func (s *Service) Begin(parent context.Context, userID uuid.UUID) (*Stream, error) {
+	if s.config.ResponseCost > 0 {
+		hasTokens, err := s.tokenMgr.HasEnoughTokens(userID, s.config.ResponseCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token balance: %w", err)
+		}
+		if !hasTokens {
+			return nil, ErrInsufficientTokens
+		}
+	}
+
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	if s.streams[userID.String()] != nil {
+		return nil, ErrBusy
+	}
+
+	timeout := s.config.ResponseTimeout
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	stream := &Stream{
+		ID:     uuid.New().String(),
+		ctx:    ctx,
+		parent: parent,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.streams[userID.String()] = stream
+	return stream, nil
+}
+
+// End releases the user's response slot
+func (s *Service) End(userID uuid.UUID, stream *Stream) {
+	s.streamsMu.Lock()
+	if s.streams[userID.String()] == stream {
+		delete(s.streams, userID.String())
+	}
+	s.streamsMu.Unlock()
+
+	stream.cancel()
+	close(stream.done)
+}
+
+// Active returns the response being generated for a user, if any
+func (s *Service) Active(userID uuid.UUID) *Stream {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	return s.streams[userID.String()]
+}
+
+// AddUserMessage stores a user message in the thread. A thread without a
+// title is named after its first message.
+func (s *Service) AddUserMessage(thread *models.ChatThread, content string) error {
+	if thread.Title == "" {
+		thread.Title = threadTitle(content)
+		if err := s.db.DB.Model(thread).Update("title", thread.Title).Error; err != nil {
+			return fmt.Errorf("failed to name thread: %w", err)
+		}
+	}
+	return s.save(thread, "user", content, false)
+}
+
+// PrepareRegenerate deletes the thread's trailing assistant answer so the
+// next response answers the last prompt again
+func (s *Service) PrepareRegenerate(thread *models.ChatThread) error {
+	// Guided questions are not generated, so there is nothing to redo
+	if Guiding(thread) {
+		return ErrNothingToRegenerate
+	}
+
+	var recent []models.ChatMessage
+	if err := s.db.DB.Where("thread_id = ?", thread.ID).
+		Order("created_at DESC").
+		Limit(regenerateLookback).
+		Find(&recent).Error; err != nil {
+		return fmt.Errorf("failed to load thread: %w", err)
+	}
+
+	var answers []uuid.UUID
+	for _, msg := range recent {
+		if msg.Role != "assistant" {
+			if msg.Role != "user" {
+				break
+			}
+			if len(answers) == 0 {
+				return nil
+			}
+			if err := s.db.DB.Where("id IN ?", answers).Delete(&models.ChatMessage{}).Error; err != nil {
+				return fmt.Errorf("failed to discard answer: %w", err)
+			}
+			return nil
+		}
+		answers = append(answers, msg.ID)
+	}
+	return ErrNothingToRegenerate
+}
+
+// Respond streams the assistant's answer to the thread, calling onChunk for
+// each piece, then records and bills it and ends the stream. A response
+// that is cancelled, times out or loses its client is kept and persisted as
+// truncated; only upstream failures return an error. A response of edits
+// alone is recorded as a summary of them.
+func (s *Service) Respond(stream *Stream, thread *models.ChatThread, onChunk func(chunk string)) (*Reply, error) {
+	defer s.End(thread.UserID, stream)
+
+	stream.mu.Lock()
+	stream.threadID = thread.ID
+	stream.mu.Unlock()
+
+	budget := contextBudget(s.config.ContextBudgets, s.kimi.Model())
+	history, err := s.buildContext(thread, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &Reply{ID: stream.ID, ThreadID: thread.ID}
+	tools := s.tools(thread)
+
+	var content strings.Builder
+	for round := 1; ; round++ {
+		if round > maxToolRounds {
+			tools = nil
+		}
+
+		var text strings.Builder
+		var calls []ai.ToolCall
+		calls, err = s.kimi.ChatCompletionStreamTools(stream.ctx, history, tools, func(chunk string) {
+			text.WriteString(chunk)
+			content.WriteString(chunk)
+			onChunk(chunk)
+		})
+		if err != nil || len(calls) == 0 {
+			break
+		}
+
+		// Run the calls and let the model continue with their results
+		history = append(history, ai.Message{Role: "assistant", Content: text.String(), ToolCalls: calls})
+		for _, call := range calls {
+			edit := s.editor.Execute(stream.ctx, thread.UserID, *thread.WebsiteID, call)
+			reply.Edits = append(reply.Edits, edit)
+			history = append(history, ai.Message{Role: "tool", ToolCallID: call.ID, Content: edit.Result()})
+		}
+	}
+
+	if err != nil {
+		if stream.ctx.Err() == nil {
+			return nil, fmt.Errorf("failed to get AI response: %w", err)
+		}
+		reply.Truncated = stream.truncation()
+		logrus.WithFields(logrus.Fields{"userId": thread.UserID, "reason": reply.Truncated}).Info("AI response truncated")
+	}
+
+	reply.Content = content.String()
+	if reply.Content == "" {
+		if len(reply.Edits) == 0 {
+			return reply, nil
+		}
+		// The model only called tools; the thread still records what changed
+		reply.Content = editSummary(reply.Edits)
+	}
+
+	if err := s.save(thread, "assistant", reply.Content, reply.Truncated != ""); err != nil {
+		// The client already has the text; only the stored copy is missing
+		logrus.WithError(err).Error("Failed to save chat message")
+	}
+	reply.TokensUsed = s.bill(thread.UserID, thread.WebsiteID)
+
+	go s.compact(thread, budget)
+	return reply, nil
+}
+
+// editSummary describes the edits of a reply without text, one line per
+// tool call with its result
+func editSummary(edits []editor.Edit) string {
+	var b strings.Builder
+	b.WriteString("Edited the website:")
+	for _, edit := range edits {
+		fmt.Fprintf(&b, "\n- %s: %s", edit.Tool, edit.Result())
+	}
+	return b.String()
+}
+
+// tools returns the tools offered for the thread: website edits when the
+// thread is about a website
+func (s *Service) tools(thread *models.ChatThread) []ai.Tool {
+	if s.editor == nil || thread.WebsiteID == nil {
+		return nil
+	}
+	return s.editor.Tools()
+}
+
+// bill charges the response cost, returning the tokens used
+func (s *Service) bill(userID uuid.UUID, websiteID *uuid.UUID) int {
+	if s.config.ResponseCost <= 0 {
+		return 0
+	}
+
+	if _, err := s.tokenMgr.DeductTokens(userID, s.config.ResponseCost, token.TypeChatMessage, "AI chat response", websiteID); err != nil {
+		// The balance was checked in Begin; a concurrent spend can still win
+		logrus.WithError(err).WithField("userId", userID).Warn("Failed to bill chat response")
+		return 0
+	}
+	return s.config.ResponseCost
+}
+
+// save stores a chat message and moves its thread to the top of the list
+func (s *Service) save(thread *models.ChatThread, role, content string, truncated bool) error {
+	chatMsg := models.ChatMessage{
+		UserID:    thread.UserID,
+		WebsiteID: thread.WebsiteID,
+		ThreadID:  &thread.ID,
+		Role:      role,
+		Content:   content,
+		Truncated: truncated,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.db.DB.Create(&chatMsg).Error; err != nil {
+		return fmt.Errorf("failed to save chat message: %w", err)
+	}
+	if err := s.db.DB.Model(thread).Update("updated_at", chatMsg.CreatedAt).Error; err != nil {
+		logrus.WithError(err).WithField("threadId", thread.ID).Warn("Failed to touch chat thread")
+	}
+	return nil
+}
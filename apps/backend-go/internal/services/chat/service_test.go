@@ -0,0 +1,19 @@
+package chat
+
+import (
+	"testing"
+
+	"backend-go/internal/services/editor"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditSummary(t *testing.T) {
+	summary := editSummary([]editor.Edit{
+		{Tool: "update_section", Fields: []string{"generated_content"}, Version: 4},
+		{Tool: "update_theme", Error: "unknown color"},
+	})
+	assert.Equal(t, "Edited the website:\n"+
+		`- update_section: {"ok":true,"version":4}`+"\n"+
+		`- update_theme: {"error":"unknown color"}`, summary)
+}
@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Thread errors
+var (
+	ErrThreadNotFound  = errors.New("thread not found")
+	ErrWebsiteNotFound = errors.New("website not found")
+)
+
+const (
+	// maxTitleLength bounds a thread title, in characters
+	maxTitleLength = 60
+
+	// regenerateLookback is how many trailing messages PrepareRegenerate
+	// inspects for the prompt being answered again
+	regenerateLookback = 10
+)
+
+// Thread returns the thread a chat message goes to. With a thread ID it
+// must be one of the user's threads. Otherwise the user's most recent thread
+// about the website (or about no website) is continued, and a new one is
+// started if there is none.
+func (s *Service) Thread(userID uuid.UUID, threadID, websiteID *uuid.UUID) (*models.ChatThread, error) {
+	if threadID != nil {
+		return s.GetThread(userID, *threadID)
+	}
+
+	query := s.db.DB.Where("user_id = ?", userID)
+	if websiteID != nil {
+		query = query.Where("website_id = ?", *websiteID)
+	} else {
+		query = query.Where("website_id IS NULL")
+	}
+
+	var thread models.ChatThread
+	err := query.Order("updated_at DESC").First(&thread).Error
+	if err == nil {
+		return &thread, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find thread: %w", err)
+	}
+	return s.CreateThread(userID, websiteID, "")
+}
+
+// CreateThread starts a thread, checking the website belongs to the user
+func (s *Service) CreateThread(userID uuid.UUID, websiteID *uuid.UUID, title string) (*models.ChatThread, error) {
+	if websiteID != nil {
+		var count int64
+		if err := s.db.DB.Model(&models.Website{}).Where("id = ? AND user_id = ?", *websiteID, userID).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check website: %w", err)
+		}
+		if count == 0 {
+			return nil, ErrWebsiteNotFound
+		}
+	}
+
+	thread := models.ChatThread{
+		UserID:    userID,
+		WebsiteID: websiteID,
+		Title:     threadTitle(title),
+	}
+	if err := s.db.DB.Create(&thread).Error; err != nil {
+		return nil, fmt.Errorf("failed to create thread: %w", err)
+	}
+	return &thread, nil
+}
+
+// GetThread returns one of the user's threads
+func (s *Service) GetThread(userID, threadID uuid.UUID) (*models.ChatThread, error) {
+	var thread models.ChatThread
+	if err := s.db.DB.Where("id = ? AND user_id = ?", threadID, userID).First(&thread).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThreadNotFound
+		}
+		return nil, fmt.Errorf("failed to get thread: %w", err)
+	}
+	return &thread, nil
+}
+
+// ListThreads returns the user's threads, most recently active first,
+// optionally only those about one website
+func (s *Service) ListThreads(userID uuid.UUID, websiteID *uuid.UUID, limit, offset int) ([]models.ChatThread, int64, error) {
+	query := s.db.DB.Model(&models.ChatThread{}).Where("user_id = ?", userID)
+	if websiteID != nil {
+		query = query.Where("website_id = ?", *websiteID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count threads: %w", err)
+	}
+
+	var threads []models.ChatThread
+	if err := query.Order("updated_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&threads).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get threads: %w", err)
+	}
+	return threads, total, nil
+}
+
+// Messages returns a page of a thread's messages, newest first
+func (s *Service) Messages(userID, threadID uuid.UUID, limit, offset int) ([]models.ChatMessage, int64, error) {
+	if _, err := s.GetThread(userID, threadID); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := s.db.DB.Model(&models.ChatMessage{}).Where("thread_id = ?", threadID).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	var messages []models.ChatMessage
+	if err := s.db.DB.Where("thread_id = ?", threadID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&messages).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get messages: %w", err)
+	}
+	return messages, total, nil
+}
+
+// RenameThread changes a thread's title
+func (s *Service) RenameThread(userID, threadID uuid.UUID, title string) (*models.ChatThread, error) {
+	thread, err := s.GetThread(userID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	thread.Title = threadTitle(title)
+	if err := s.db.DB.Model(thread).Update("title", thread.Title).Error; err != nil {
+		return nil, fmt.Errorf("failed to rename thread: %w", err)
+	}
+	return thread, nil
+}
+
+// DeleteThread deletes a thread and its messages, cancelling a response
+// being generated for it so that the answer is not stored afterwards
+func (s *Service) DeleteThread(userID, threadID uuid.UUID) error {
+	thread, err := s.GetThread(userID, threadID)
+	if err != nil {
+		return err
+	}
+
+	if stream := s.Active(userID); stream != nil && stream.inThread(thread.ID) {
+		stream.Stop(TruncatedCancelled)
+		<-stream.Done()
+	}
+
+	return s.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("thread_id = ?", thread.ID).Delete(&models.ChatMessage{}).Error; err != nil {
+			return fmt.Errorf("failed to delete messages: %w", err)
+		}
+		if err := tx.Delete(thread).Error; err != nil {
+			return fmt.Errorf("failed to delete thread: %w", err)
+		}
+		return nil
+	})
+}
+
+// threadTitle trims a title, or the first message of a thread, to a single
+// line of at most maxTitleLength characters
+func threadTitle(text string) string {
+	title := strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:maxTitleLength-1])) + "…"
+}
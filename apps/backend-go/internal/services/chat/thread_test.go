@@ -0,0 +1,18 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadTitle(t *testing.T) {
+	assert.Equal(t, "Build me a bakery site", threadTitle("  Build me\na  bakery site "))
+	assert.Equal(t, "", threadTitle(""))
+
+	long := threadTitle(strings.Repeat("ä", 100))
+	assert.Equal(t, maxTitleLength, utf8.RuneCountInString(long))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
@@ -0,0 +1,135 @@
+package design
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RGB is an opaque sRGB color with channels from 0 to 255
+type RGB struct {
+	R, G, B uint8
+}
+
+// ParseHex reads a #rgb, #rgba, #rrggbb or #rrggbbaa color; alpha is ignored
+func ParseHex(hex string) (RGB, error) {
+	if !hexColorPattern.MatchString(hex) {
+		return RGB{}, fmt.Errorf("invalid hex color %q", hex)
+	}
+	digits := strings.TrimPrefix(hex, "#")
+	if len(digits) <= 4 {
+		long := make([]byte, 0, 6)
+		for i := 0; i < 3; i++ {
+			long = append(long, digits[i], digits[i])
+		}
+		digits = string(long)
+	}
+	value, _ := strconv.ParseUint(digits[:6], 16, 32)
+	return RGB{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value)}, nil
+}
+
+// Hex formats the color as #RRGGBB
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// Luminance is the color's relative luminance as defined by WCAG 2
+func (c RGB) Luminance() float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// Contrast is the WCAG 2 contrast ratio of two colors, from 1 to 21
+func Contrast(a, b RGB) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// Mix blends c towards other; weight 0 is c and 1 is other
+func (c RGB) Mix(other RGB, weight float64) RGB {
+	blend := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-weight) + float64(b)*weight))
+	}
+	return RGB{R: blend(c.R, other.R), G: blend(c.G, other.G), B: blend(c.B, other.B)}
+}
+
+var (
+	white = RGB{255, 255, 255}
+	ink   = RGB{17, 24, 39}
+)
+
+// OnColor returns the text color, white or near-black, that reads best on
+// the given background
+func OnColor(background RGB) RGB {
+	if Contrast(background, white) >= Contrast(background, ink) {
+		return white
+	}
+	return ink
+}
+
+// HSL returns the color's hue in degrees and its saturation and lightness
+// from 0 to 1
+func (c RGB) HSL() (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+
+	d := max - min
+	if l > 0.5 {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// FromHSL builds a color from a hue in degrees and saturation and lightness
+// from 0 to 1
+func FromHSL(h, s, l float64) RGB {
+	h = math.Mod(math.Mod(h, 360)+360, 360)
+	s = math.Max(0, math.Min(1, s))
+	l = math.Max(0, math.Min(1, l))
+
+	chroma := (1 - math.Abs(2*l-1)) * s
+	x := chroma * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - chroma/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = chroma, x, 0
+	case h < 120:
+		r, g, b = x, chroma, 0
+	case h < 180:
+		r, g, b = 0, chroma, x
+	case h < 240:
+		r, g, b = 0, x, chroma
+	case h < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	channel := func(v float64) uint8 { return uint8(math.Round((v + m) * 255)) }
+	return RGB{R: channel(r), G: channel(g), B: channel(b)}
+}
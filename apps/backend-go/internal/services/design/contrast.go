@@ -0,0 +1,74 @@
+package design
+
+import "backend-go/internal/models"
+
+// MinLargeContrast is the WCAG AA contrast ratio for large text, such as
+// headings and the hero
+const MinLargeContrast = 3.0
+
+// textPairs are the text colors the templates set on each background
+var textPairs = [][2]string{
+	{"text", "background"},
+	{"muted", "background"},
+	{"muted", "surface"},
+	{"primary", "background"},
+	{"primaryDark", "background"},
+	{"text", "primaryLight"},
+	{"on-primary", "primary"},
+	{"on-primary", "secondary"},
+	{"on-accent", "accent"},
+	{"background", "text"},
+}
+
+// pageColors resolves the colors a page is styled with, as hex values: the
+// base colors, the variants a palette did not set, readable text on the
+// brand colors, and tints of the background for alternating sections and
+// secondary text. Compile declares them and the contrast checks judge them.
+func pageColors(tokens *models.DesignTokens) map[string]string {
+	defaults := Defaults()
+	if tokens == nil {
+		tokens = defaults
+	}
+
+	colors := map[string]string{}
+	rgb := map[string]RGB{}
+	for _, key := range baseColors {
+		colors[key] = tokenOr("colors", key, tokens.Colors, defaults.Colors)
+		rgb[key], _ = ParseHex(colors[key])
+	}
+	for _, key := range brandColors {
+		light, dark := variants(rgb[key], rgb["background"], rgb["text"])
+		derived := map[string]string{key + "Light": light.Hex(), key + "Dark": dark.Hex()}
+		for _, variant := range []string{key + "Light", key + "Dark"} {
+			colors[variant] = tokenOr("colors", variant, tokens.Colors, derived)
+		}
+		colors["on-"+key] = OnColor(rgb[key]).Hex()
+	}
+	colors["surface"] = rgb["background"].Mix(rgb["text"], 0.04).Hex()
+	colors["muted"] = rgb["text"].Mix(rgb["background"], 0.3).Hex()
+	return colors
+}
+
+// pageColorNames lists the colors of pageColors in the order Compile
+// declares them
+func pageColorNames() []string {
+	names := append([]string{}, baseColors...)
+	for _, key := range brandColors {
+		names = append(names, key+"Light", key+"Dark", "on-"+key)
+	}
+	return append(names, "surface", "muted")
+}
+
+// ContrastChecks checks the contrast of every pair of colors the templates
+// put together when styled with the tokens
+func ContrastChecks(tokens *models.DesignTokens) []Check {
+	colors := map[string]RGB{}
+	for name, value := range pageColors(tokens) {
+		colors[name], _ = ParseHex(value)
+	}
+	checks := make([]Check, 0, len(textPairs))
+	for _, pair := range textPairs {
+		checks = append(checks, check(pair[0], colors[pair[0]], pair[1], colors[pair[1]]))
+	}
+	return checks
+}
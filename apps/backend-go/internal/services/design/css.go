@@ -0,0 +1,113 @@
+package design
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"backend-go/internal/models"
+)
+
+// FontHost serves the stylesheets of hosted fonts
+const FontHost = "https://fonts.googleapis.com"
+
+// Stylesheet is the compiled form of a site's design tokens
+type Stylesheet struct {
+	// Variables is a :root rule declaring the tokens as CSS custom
+	// properties, e.g. --color-primary
+	Variables string
+	// FontURL loads the hosted fonts the site uses; empty when it only uses
+	// system fonts
+	FontURL string
+}
+
+// Compile turns design tokens into CSS custom properties. Tokens that are
+// missing or invalid get their default value, so any stored tokens render.
+func Compile(tokens *models.DesignTokens) *Stylesheet {
+	defaults := Defaults()
+	if tokens == nil {
+		tokens = defaults
+	}
+
+	var b strings.Builder
+	b.WriteString(":root {\n")
+	// The same colors the contrast checks judge
+	colors := pageColors(tokens)
+	for _, name := range pageColorNames() {
+		fmt.Fprintf(&b, "\t--color-%s: %s;\n", kebab(name), colors[name])
+	}
+
+	var hosted []Font
+	for _, key := range TokenKeys["typography"] {
+		font, _ := LookupFont(tokenOr("typography", key, tokens.Typography, defaults.Typography))
+		fmt.Fprintf(&b, "\t--font-%s: %s;\n", strings.TrimSuffix(strings.ToLower(key), "font"), fontStack(font))
+		if font.Hosted && !containsFont(hosted, font) {
+			hosted = append(hosted, font)
+		}
+	}
+
+	for _, key := range TokenKeys["spacing"] {
+		fmt.Fprintf(&b, "\t--space-%s: %s;\n", key, tokenOr("spacing", key, tokens.Spacing, defaults.Spacing))
+	}
+	radius := defaults.BorderRadius
+	if ValidateToken("borderRadius", "", tokens.BorderRadius) == nil {
+		radius = tokens.BorderRadius
+	}
+	fmt.Fprintf(&b, "\t--radius: %s;\n", radius)
+	b.WriteString("}\n")
+
+	return &Stylesheet{Variables: b.String(), FontURL: fontURL(hosted)}
+}
+
+// tokenOr returns the valid token value, or the fallback's
+func tokenOr(group, key string, values, fallback map[string]string) string {
+	if value := strings.TrimSpace(values[key]); value != "" && ValidateToken(group, key, value) == nil {
+		return value
+	}
+	return fallback[key]
+}
+
+// kebab turns a camel-case token name into a CSS name, e.g. primaryLight
+// into primary-light
+func kebab(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('-')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fontStack is a font-family value with the font's generic fallback
+func fontStack(font Font) string {
+	if font.Name == "system-ui" {
+		return font.Name + ", " + font.Fallback
+	}
+	return "'" + font.Name + "', " + font.Fallback
+}
+
+func containsFont(fonts []Font, font Font) bool {
+	for _, f := range fonts {
+		if f.Name == font.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// fontURL is the Google Fonts stylesheet for the fonts, with the weights
+// the templates use
+func fontURL(fonts []Font) string {
+	if len(fonts) == 0 {
+		return ""
+	}
+	query := make([]string, 0, len(fonts)+1)
+	for _, font := range fonts {
+		query = append(query, "family="+url.QueryEscape(font.Name)+":wght@400;600;700")
+	}
+	query = append(query, "display=swap")
+	return FontHost + "/css2?" + strings.Join(query, "&")
+}
@@ -0,0 +1,173 @@
+// Package design validates a website's design tokens and compiles them into
+// the CSS custom properties the page templates are styled with.
+package design
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"backend-go/internal/models"
+)
+
+var (
+	hexColorPattern  = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	cssLengthPattern = regexp.MustCompile(`^(0|\d+(\.\d+)?(px|rem|em|%))$`)
+)
+
+// TokenKeys lists the tokens of each group. The light and dark variants of
+// the brand colors are optional; they are derived when not set.
+var TokenKeys = map[string][]string{
+	"colors": {"primary", "secondary", "accent", "background", "text",
+		"primaryLight", "primaryDark", "secondaryLight", "secondaryDark", "accentLight", "accentDark"},
+	"typography": {"headingFont", "bodyFont"},
+	"spacing":    {"small", "medium", "large"},
+}
+
+// baseColors are the color tokens every design has
+var baseColors = []string{"primary", "secondary", "accent", "background", "text"}
+
+// brandColors are the colors that have light and dark variants
+var brandColors = []string{"primary", "secondary", "accent"}
+
+// Font is a font family a site may use
+type Font struct {
+	Name string
+	// Fallback is the generic family used until the font loads
+	Fallback string
+	// Hosted fonts are loaded from Google Fonts; the others are installed
+	// on most systems
+	Hosted bool
+}
+
+// fonts are the known font families, by lower-case name
+var fonts = map[string]Font{}
+
+func init() {
+	hosted := map[string][]string{
+		"sans-serif": {"Inter", "Roboto", "Open Sans", "Lato", "Montserrat", "Poppins", "Nunito", "Raleway",
+			"Work Sans", "DM Sans", "Source Sans 3", "Plus Jakarta Sans", "Rubik", "Manrope", "Oswald"},
+		"serif":     {"Playfair Display", "Merriweather", "Lora", "PT Serif", "Libre Baskerville", "DM Serif Display", "Cormorant Garamond"},
+		"cursive":   {"Pacifico", "Caveat"},
+		"monospace": {"JetBrains Mono", "Space Mono"},
+	}
+	for fallback, names := range hosted {
+		for _, name := range names {
+			fonts[strings.ToLower(name)] = Font{Name: name, Fallback: fallback, Hosted: true}
+		}
+	}
+
+	system := map[string]string{
+		"system-ui": "sans-serif", "Arial": "sans-serif", "Helvetica": "sans-serif", "Verdana": "sans-serif",
+		"Georgia": "serif", "Times New Roman": "serif",
+	}
+	for name, fallback := range system {
+		fonts[strings.ToLower(name)] = Font{Name: name, Fallback: fallback}
+	}
+}
+
+// LookupFont finds a known font family by name, ignoring case
+func LookupFont(name string) (Font, bool) {
+	font, ok := fonts[strings.ToLower(strings.TrimSpace(name))]
+	return font, ok
+}
+
+// FontNames lists the known font families
+func FontNames() []string {
+	names := make([]string, 0, len(fonts))
+	for _, font := range fonts {
+		names = append(names, font.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Defaults returns the tokens of a site without a design of its own
+func Defaults() *models.DesignTokens {
+	return &models.DesignTokens{
+		Colors: map[string]string{
+			"primary":    "#3B82F6",
+			"secondary":  "#10B981",
+			"accent":     "#F59E0B",
+			"background": "#FFFFFF",
+			"text":       "#1F2937",
+		},
+		Typography: map[string]string{
+			"headingFont": "Inter",
+			"bodyFont":    "Inter",
+		},
+		Spacing: map[string]string{
+			"small":  "1rem",
+			"medium": "2rem",
+			"large":  "4rem",
+		},
+		BorderRadius: "0.5rem",
+	}
+}
+
+// ValidateToken checks one token, e.g. group "colors" and key "primary".
+// borderRadius has no key.
+func ValidateToken(group, key, value string) error {
+	if group == "borderRadius" {
+		if !cssLengthPattern.MatchString(value) {
+			return fmt.Errorf("borderRadius must be a CSS length such as 0.5rem")
+		}
+		return nil
+	}
+
+	known := false
+	for _, k := range TokenKeys[group] {
+		if k == key {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown design token %s.%s", group, key)
+	}
+
+	switch group {
+	case "colors":
+		if !hexColorPattern.MatchString(value) {
+			return fmt.Errorf("%s.%s must be a hex color such as #3B82F6", group, key)
+		}
+	case "typography":
+		if _, ok := LookupFont(value); !ok {
+			return fmt.Errorf("%s.%s must be a known font family such as Inter or Playfair Display", group, key)
+		}
+	case "spacing":
+		if !cssLengthPattern.MatchString(value) {
+			return fmt.Errorf("%s.%s must be a CSS length such as 2rem", group, key)
+		}
+	}
+	return nil
+}
+
+// Validate checks every token that is set. Tokens left out fall back to the
+// defaults when the site is rendered.
+func Validate(tokens *models.DesignTokens) error {
+	groups := []struct {
+		name   string
+		values map[string]string
+	}{
+		{"colors", tokens.Colors},
+		{"typography", tokens.Typography},
+		{"spacing", tokens.Spacing},
+	}
+	for _, group := range groups {
+		keys := make([]string, 0, len(group.values))
+		for key := range group.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := ValidateToken(group.name, key, group.values[key]); err != nil {
+				return err
+			}
+		}
+	}
+	if tokens.BorderRadius != "" {
+		return ValidateToken("borderRadius", "", tokens.BorderRadius)
+	}
+	return nil
+}
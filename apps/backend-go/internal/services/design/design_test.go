@@ -0,0 +1,66 @@
+package design
+
+import (
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(Defaults()))
+	require.NoError(t, Validate(&models.DesignTokens{Typography: map[string]string{"bodyFont": "playfair display"}}))
+
+	for _, tokens := range []*models.DesignTokens{
+		{Colors: map[string]string{"primary": "blue"}},
+		{Colors: map[string]string{"link": "#000000"}},
+		{Typography: map[string]string{"bodyFont": "Comic Sans MS"}},
+		{Typography: map[string]string{"bodyFont": "Inter; color: red"}},
+		{Spacing: map[string]string{"large": "calc(100vw)"}},
+		{BorderRadius: "1px;background:red"},
+	} {
+		assert.Error(t, Validate(tokens), "%+v", tokens)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	sheet := Compile(&models.DesignTokens{
+		Colors:       map[string]string{"primary": "#fde68a", "text": "#000", "accent": "red;}"},
+		Typography:   map[string]string{"headingFont": "Lora", "bodyFont": "Georgia"},
+		Spacing:      map[string]string{"small": "8px"},
+		BorderRadius: "12px",
+	})
+
+	assert.Contains(t, sheet.Variables, "--color-primary: #fde68a;")
+	assert.Contains(t, sheet.Variables, "--color-on-primary: #111827;", "dark text on a light primary")
+	assert.Contains(t, sheet.Variables, "--color-accent: #F59E0B;", "invalid tokens fall back to the defaults")
+	assert.Contains(t, sheet.Variables, "--color-background: #FFFFFF;")
+	assert.Contains(t, sheet.Variables, "--font-heading: 'Lora', serif;")
+	assert.Contains(t, sheet.Variables, "--font-body: 'Georgia', serif;")
+	assert.Contains(t, sheet.Variables, "--space-small: 8px;")
+	assert.Contains(t, sheet.Variables, "--space-large: 4rem;")
+	assert.Contains(t, sheet.Variables, "--radius: 12px;")
+	assert.Equal(t, "https://fonts.googleapis.com/css2?family=Lora:wght@400;600;700&display=swap", sheet.FontURL)
+
+	sheet = Compile(nil)
+	assert.Contains(t, sheet.Variables, "--color-primary: #3B82F6;")
+	assert.Equal(t, "https://fonts.googleapis.com/css2?family=Inter:wght@400;600;700&display=swap", sheet.FontURL, "a font used twice is loaded once")
+}
+
+func TestContrast(t *testing.T) {
+	black, _ := ParseHex("#000")
+	white, _ := ParseHex("#FFFFFF")
+	assert.InDelta(t, 21, Contrast(black, white), 0.001)
+	assert.InDelta(t, 1, Contrast(white, white), 0.001)
+
+	blue, err := ParseHex("#3B82F6")
+	require.NoError(t, err)
+	assert.InDelta(t, 3.68, Contrast(blue, white), 0.01)
+	assert.Equal(t, "#3B82F6", blue.Hex())
+	assert.Equal(t, "#808080", black.Mix(white, 0.5).Hex())
+
+	_, err = ParseHex("3B82F6")
+	assert.Error(t, err)
+}
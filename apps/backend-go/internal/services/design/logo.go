@@ -0,0 +1,146 @@
+package design
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"sort"
+
+	// Logo formats
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// maxLogoPixels bounds the size of a decoded logo
+	maxLogoPixels = 4096 * 4096
+	// logoSamples is about how many pixels are read from a logo
+	logoSamples = 20000
+	// minHueDistance separates the colors extracted from a logo, in degrees
+	minHueDistance = 30
+)
+
+// ErrInvalidLogo is returned for logos that are not a PNG, JPEG or GIF image
+// of a supported size
+var ErrInvalidLogo = errors.New("invalid logo")
+
+// DecodeLogo reads a PNG, JPEG or GIF image, checking its size before
+// decoding the pixels
+func DecodeLogo(r io.ReadSeeker) (image.Image, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a PNG, JPEG or GIF image", ErrInvalidLogo)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxLogoPixels {
+		return nil, fmt.Errorf("%w: images may have at most %d pixels", ErrInvalidLogo, maxLogoPixels)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLogo, err)
+	}
+	return img, nil
+}
+
+// bucket accumulates the pixels of one quantized color
+type bucket struct {
+	count   int
+	r, g, b int
+}
+
+func (b *bucket) color() RGB {
+	return RGB{R: uint8(b.r / b.count), G: uint8(b.g / b.count), B: uint8(b.b / b.count)}
+}
+
+// ExtractColors returns up to n brand colors of a logo, most prominent
+// first. Transparent pixels are ignored and saturated colors preferred over
+// white, black and greys, which logos mostly use for background and text;
+// those are only returned when the logo has no other colors. Colors are at
+// least minHueDistance apart.
+func ExtractColors(img image.Image, n int) []RGB {
+	bounds := img.Bounds()
+	step := int(math.Max(1, math.Sqrt(float64(bounds.Dx()*bounds.Dy())/logoSamples)))
+
+	buckets := map[int]*bucket{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a < 0x8000 {
+				continue
+			}
+			// Undo the alpha premultiplication and scale to 8 bits
+			r, g, b = r*0xffff/a>>8, g*0xffff/a>>8, b*0xffff/a>>8
+
+			key := int(r>>4)<<8 | int(g>>4)<<4 | int(b>>4)
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += int(r)
+			bk.g += int(g)
+			bk.b += int(b)
+		}
+	}
+
+	type candidate struct {
+		color  RGB
+		weight float64
+		vivid  bool
+	}
+	candidates := make([]candidate, 0, len(buckets))
+	for _, bk := range buckets {
+		color := bk.color()
+		_, s, l := color.HSL()
+		vivid := s > 0.2 && l > 0.1 && l < 0.92
+		candidates = append(candidates, candidate{
+			color:  color,
+			weight: float64(bk.count) * (0.25 + s),
+			vivid:  vivid,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].vivid != candidates[j].vivid {
+			return candidates[i].vivid
+		}
+		if candidates[i].weight != candidates[j].weight {
+			return candidates[i].weight > candidates[j].weight
+		}
+		return candidates[i].color.Hex() < candidates[j].color.Hex()
+	})
+
+	var colors []RGB
+	for _, c := range candidates {
+		if len(colors) == n {
+			break
+		}
+		if len(colors) > 0 && !c.vivid {
+			break
+		}
+		distinct := true
+		for _, picked := range colors {
+			if hueDistance(picked, c.color) < minHueDistance {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			colors = append(colors, c.color)
+		}
+	}
+	return colors
+}
+
+// hueDistance is the angle between two colors' hues
+func hueDistance(a, b RGB) float64 {
+	ha, _, _ := a.HSL()
+	hb, _, _ := b.HSL()
+	d := math.Abs(ha - hb)
+	return math.Min(d, 360-d)
+}
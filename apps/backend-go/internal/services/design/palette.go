@@ -0,0 +1,116 @@
+package design
+
+import (
+	"math"
+)
+
+// MinContrast is the WCAG AA contrast ratio for normal text
+const MinContrast = 4.5
+
+// Palette is a set of brand colors derived from a seed color. Every pair of
+// colors the templates put together passes MinContrast.
+type Palette struct {
+	// Colors are the color tokens, e.g. primary and primaryLight
+	Colors map[string]string `json:"colors"`
+	// Checks are the contrast ratios of the pairs the templates use
+	Checks []Check `json:"checks"`
+}
+
+// Check is the contrast of text in one color on another
+type Check struct {
+	Foreground string  `json:"foreground"`
+	Background string  `json:"background"`
+	Ratio      float64 `json:"ratio"`
+	Passes     bool    `json:"passes"`
+}
+
+var black = RGB{0, 0, 0}
+
+// NewPalette derives a palette from a seed color: the seed, darkened as far
+// as needed, is the primary color; the secondary is the second seed when
+// given and a neighbouring hue otherwise; the accent is the complementary
+// hue. The background and text are a near-white and a near-black tinted
+// with the seed's hue.
+func NewPalette(seed RGB, second *RGB) *Palette {
+	h, s, l := seed.HSL()
+	tint := math.Min(s, 0.5)
+
+	background := FromHSL(h, tint, 0.985)
+	text := FromHSL(h, math.Min(s, 0.3), 0.12)
+
+	primary := readableOn(seed, background)
+	secondarySeed := FromHSL(h+30, s, l)
+	if second != nil {
+		secondarySeed = *second
+	}
+	secondary := readableOn(secondarySeed, background)
+	accent := FromHSL(h+180, math.Max(s, 0.5), 0.45)
+	if Contrast(accent, OnColor(accent)) < MinContrast {
+		accent = readableOn(accent, white)
+	}
+
+	colors := map[string]RGB{
+		"primary":    primary,
+		"secondary":  secondary,
+		"accent":     accent,
+		"background": background,
+		"text":       text,
+	}
+	for _, key := range []string{"primary", "secondary", "accent"} {
+		light, dark := variants(colors[key], background, text)
+		colors[key+"Light"] = light
+		colors[key+"Dark"] = dark
+	}
+
+	palette := &Palette{Colors: make(map[string]string, len(colors))}
+	for key, color := range colors {
+		palette.Colors[key] = color.Hex()
+	}
+	pairs := [][2]string{
+		{"text", "background"},
+		{"primary", "background"},
+		{"secondary", "background"},
+		{"primaryDark", "background"},
+		{"text", "primaryLight"},
+		{"text", "secondaryLight"},
+		{"text", "accentLight"},
+	}
+	for _, pair := range pairs {
+		palette.Checks = append(palette.Checks, check(pair[0], colors[pair[0]], pair[1], colors[pair[1]]))
+	}
+	for _, key := range []string{"primary", "secondary", "accent"} {
+		palette.Checks = append(palette.Checks, check("on-"+key, OnColor(colors[key]), key, colors[key]))
+	}
+	return palette
+}
+
+// readableOn darkens color until text in it reads on background
+func readableOn(color, background RGB) RGB {
+	h, s, l := color.HSL()
+	for Contrast(color, background) < MinContrast && l > 0 {
+		l = math.Max(0, l-0.02)
+		color = FromHSL(h, s, l)
+	}
+	return color
+}
+
+// variants returns a light tint of color to set text on, and a dark shade
+// for hover states and text
+func variants(color, background, text RGB) (light, dark RGB) {
+	light = color.Mix(white, 0.85)
+	for weight := 0.85; Contrast(text, light) < MinContrast && weight < 1; weight += 0.02 {
+		light = color.Mix(white, weight)
+	}
+	dark = readableOn(color.Mix(black, 0.3), background)
+	return light, dark
+}
+
+func check(foreground string, fg RGB, background string, bg RGB) Check {
+	ratio := Contrast(fg, bg)
+	return Check{
+		Foreground: foreground,
+		Background: background,
+		Ratio:      math.Round(ratio*100) / 100,
+		Passes:     ratio >= MinContrast,
+	}
+}
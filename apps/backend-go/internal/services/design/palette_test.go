@@ -0,0 +1,92 @@
+package design
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPalettePassesContrastChecks(t *testing.T) {
+	for _, seed := range []string{"#3B82F6", "#FDE047", "#FFFFFF", "#000000", "#22C55E", "#EC4899", "#808080", "#00FFFF", "#7C2D12"} {
+		rgb, err := ParseHex(seed)
+		require.NoError(t, err)
+
+		palette := NewPalette(rgb, nil)
+		for _, key := range TokenKeys["colors"] {
+			assert.NoError(t, ValidateToken("colors", key, palette.Colors[key]), "%s %s", seed, key)
+		}
+		for _, check := range palette.Checks {
+			assert.True(t, check.Passes, "%s: %s on %s is %.2f", seed, check.Foreground, check.Background, check.Ratio)
+		}
+	}
+}
+
+func TestPaletteKeepsHue(t *testing.T) {
+	seed, _ := ParseHex("#FDE047")
+	second, _ := ParseHex("#0EA5E9")
+	palette := NewPalette(seed, &second)
+
+	primary, _ := ParseHex(palette.Colors["primary"])
+	assert.Less(t, hueDistance(seed, primary), 5.0, "a light seed is darkened, not recolored")
+
+	secondary, _ := ParseHex(palette.Colors["secondary"])
+	assert.Less(t, hueDistance(second, secondary), 5.0)
+}
+
+func TestExtractColors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			switch {
+			case x < 10:
+				img.Set(x, y, color.NRGBA{0, 0, 0, 0})
+			case y < 40:
+				img.Set(x, y, color.NRGBA{255, 255, 255, 255})
+			case y < 80:
+				img.Set(x, y, color.NRGBA{37, 99, 235, 255})
+			case y < 90:
+				img.Set(x, y, color.NRGBA{220, 38, 38, 255})
+			default:
+				img.Set(x, y, color.NRGBA{17, 17, 17, 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	decoded, err := DecodeLogo(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	colors := ExtractColors(decoded, 3)
+	require.Len(t, colors, 2, "white, black and transparent pixels are left out")
+	assert.Equal(t, "#2563EB", colors[0].Hex())
+	assert.Equal(t, "#DC2626", colors[1].Hex())
+
+	grey := image.NewGray(image.Rect(0, 0, 10, 10))
+	colors = ExtractColors(grey, 2)
+	require.Len(t, colors, 1, "a logo without colors still gives its most common one")
+	assert.Equal(t, "#000000", colors[0].Hex())
+
+	_, err = DecodeLogo(bytes.NewReader([]byte("<svg></svg>")))
+	assert.True(t, errors.Is(err, ErrInvalidLogo))
+}
+
+func TestContrastChecks(t *testing.T) {
+	palette := NewPalette(RGB{59, 130, 246}, nil)
+	for _, check := range ContrastChecks(&models.DesignTokens{Colors: palette.Colors}) {
+		assert.True(t, check.Passes, "%s on %s is %.2f", check.Foreground, check.Background, check.Ratio)
+	}
+
+	checks := ContrastChecks(&models.DesignTokens{Colors: map[string]string{"text": "#777777", "background": "#888888"}})
+	require.Equal(t, "text", checks[0].Foreground)
+	assert.False(t, checks[0].Passes)
+	assert.Less(t, checks[0].Ratio, 1.5)
+}
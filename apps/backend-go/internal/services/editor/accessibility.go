@@ -0,0 +1,176 @@
+package editor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+	"backend-go/internal/services/revision"
+	"backend-go/internal/services/token"
+	"backend-go/internal/services/website"
+
+	"github.com/google/uuid"
+)
+
+// fixMaxTokens bounds the reply of an accessibility fix, which repeats a
+// whole page
+const fixMaxTokens = 4096
+
+// AccessibilityFix is the outcome of fixing accessibility findings
+type AccessibilityFix struct {
+	// Pages are the slugs of the fixed pages, the empty slug for the home
+	// page
+	Pages      []string
+	Version    int
+	TokensUsed int
+}
+
+// pageSectionsJSON is how a page's sections are sent to and read from the
+// model
+type pageSectionsJSON struct {
+	Sections []models.Section `json:"sections"`
+}
+
+// FixAccessibility rewrites the sections of pages with AI to fix the
+// accessibility findings of each, keyed by page slug with the empty slug
+// for the home page, and charges ACCESSIBILITY_FIX_COST per page. Only the
+// default locale's content is fixed. The model's reply must keep each
+// page's sections and their shapes.
+func (e *Editor) FixAccessibility(ctx context.Context, userID, websiteID uuid.UUID, findings map[string][]string) (*AccessibilityFix, error) {
+	var slugs []string
+	for slug, problems := range findings {
+		if len(problems) > 0 {
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return nil, fmt.Errorf("%w: there are no findings to fix", ErrInvalidEdit)
+	}
+	sort.Strings(slugs)
+
+	cost := e.config.AccessibilityFixCost * len(slugs)
+	if cost > 0 {
+		hasTokens, err := e.tokenMgr.HasEnoughTokens(userID, cost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token balance: %w", err)
+		}
+		if !hasTokens {
+			return nil, fmt.Errorf("%w: need %d", ErrInsufficientTokens, cost)
+		}
+	}
+
+	site, err := e.load(userID, websiteID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := models.ParseContent(site.GeneratedContent)
+	if err != nil {
+		return nil, err
+	}
+
+	fixed := make(map[string][]models.Section, len(slugs))
+	for _, slug := range slugs {
+		sections, err := pageSections(content, slug)
+		if err != nil {
+			return nil, err
+		}
+		fixed[slug], err = e.fixAccessibility(ctx, site, slug, *sections, findings[slug])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	origin := revision.Origin{Author: revision.AuthorAI, UserID: &userID, Reason: "Fixed accessibility issues on " + pageList(slugs)}
+	bill := &charge{amount: cost, txType: token.TypeAccessibilityFix, description: fmt.Sprintf("Fixed accessibility issues of %s", site.Title)}
+	result := &AccessibilityFix{Pages: slugs}
+	result.Version, _, err = e.save(userID, websiteID, FieldGeneratedContent, origin, bill, func(current *models.Website) (map[string]interface{}, error) {
+		// The fixes are of the content as it was read
+		if current.ContentVersion != site.ContentVersion {
+			return nil, fmt.Errorf("%w: the website changed while it was being fixed", ErrConflict)
+		}
+		content, err := models.ParseContent(current.GeneratedContent)
+		if err != nil {
+			return nil, err
+		}
+		for slug, sections := range fixed {
+			target, err := pageSections(content, slug)
+			if err != nil {
+				return nil, err
+			}
+			*target = sections
+		}
+		data, err := content.JSON()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"generated_content": data}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cost > 0 {
+		result.TokensUsed = cost
+	}
+	return result, nil
+}
+
+// fixAccessibility has the model rewrite a page's sections to fix its
+// findings
+func (e *Editor) fixAccessibility(ctx context.Context, site *models.Website, slug string, sections []models.Section, findings []string) ([]models.Section, error) {
+	current, err := json.Marshal(pageSectionsJSON{Sections: sections})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []ai.Message{
+		{
+			Role: "system",
+			Content: "You fix accessibility issues in website content. Reply with only the JSON object of the page's sections, " +
+				"with the same sections in the same order and the same keys. Describe images in their alt text, give links and " +
+				"buttons text that says where they go or what they do, fill empty headings, and change nothing else.",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("Website: %s\nPage: %s\nIssues:\n- %s\nSections: %s",
+				site.Title, pageName(slug), strings.Join(findings, "\n- "), current),
+		},
+	}
+
+	resp, err := e.kimi.ChatCompletion(ctx, messages, fixMaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrGenerationFailed)
+	}
+
+	var reply pageSectionsJSON
+	if err := json.Unmarshal([]byte(website.ExtractJSON(resp.Choices[0].Message.Content)), &reply); err != nil {
+		return nil, fmt.Errorf("%w: reply is not page sections", ErrGenerationFailed)
+	}
+	if err := sameSections(pageName(slug), sections, reply.Sections); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	return reply.Sections, nil
+}
+
+// pageName names a page in prompts and errors
+func pageName(slug string) string {
+	if slug == "" {
+		return "the home page"
+	}
+	return "page " + slug
+}
+
+// pageList names the pages of a revision's reason
+func pageList(slugs []string) string {
+	names := make([]string, len(slugs))
+	for i, slug := range slugs {
+		names[i] = pageName(slug)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,527 @@
+// Package editor applies small, validated edits to a website's content and
+// design tokens. The chat assistant drives it through tool calls; the
+// website API uses it to rewrite single sections.
+package editor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"backend-go/internal/config"
+	"backend-go/internal/database"
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/revision"
+	"backend-go/internal/services/section"
+	"backend-go/internal/services/token"
+	"backend-go/internal/services/website"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidEdit is returned when a tool call does not describe a valid edit
+	ErrInvalidEdit = errors.New("invalid edit")
+	// ErrSectionNotFound is returned for section indexes outside the page
+	ErrSectionNotFound = errors.New("section not found")
+	// ErrPageNotFound is returned for page slugs the website does not have
+	ErrPageNotFound = errors.New("page not found")
+	// ErrUnknownTool is returned for tool names the editor does not offer
+	ErrUnknownTool = errors.New("unknown tool")
+	// ErrWebsiteNotFound is returned when the website does not exist or is
+	// owned by someone else
+	ErrWebsiteNotFound = errors.New("website not found")
+	// ErrConflict is returned when concurrent edits kept winning the race
+	ErrConflict = errors.New("website changed concurrently")
+	// ErrInsufficientTokens is returned when the user cannot pay for an edit
+	ErrInsufficientTokens = errors.New("insufficient tokens")
+	// ErrGenerationFailed is returned when the model's reply is unusable
+	ErrGenerationFailed = errors.New("AI generation failed")
+)
+
+// saveAttempts bounds the retries of an edit that lost a concurrent update
+const saveAttempts = 3
+
+// regenerateMaxTokens bounds the reply of a section rewrite
+const regenerateMaxTokens = 1024
+
+// maxReasonLength bounds the tool arguments quoted in a revision's reason
+const maxReasonLength = 200
+
+// defaultInstructions are used when a rewrite is requested without any
+const defaultInstructions = "Improve the wording while keeping the same information."
+
+// Notifier is told about every saved edit; websocket.Manager implements it
+type Notifier interface {
+	NotifyContentChanged(websiteID, actorID uuid.UUID, fields []string, version int)
+}
+
+type Editor struct {
+	db        *database.Database
+	kimi      *ai.KimiClient
+	tokenMgr  *token.Manager
+	revisions *revision.Service
+	notifier  Notifier
+	config    *config.EditorConfig
+}
+
+func NewEditor(db *database.Database, kimi *ai.KimiClient, tokenMgr *token.Manager, revisions *revision.Service, notifier Notifier, cfg *config.EditorConfig) *Editor {
+	return &Editor{
+		db:        db,
+		kimi:      kimi,
+		tokenMgr:  tokenMgr,
+		revisions: revisions,
+		notifier:  notifier,
+		config:    cfg,
+	}
+}
+
+// Edit is the outcome of one tool call
+type Edit struct {
+	Tool    string   `json:"tool"`
+	Fields  []string `json:"fields,omitempty"`
+	Version int      `json:"version,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Result is the tool message content reported back to the model
+func (e Edit) Result() string {
+	if e.Error != "" {
+		data, _ := json.Marshal(map[string]string{"error": e.Error})
+		return string(data)
+	}
+	data, _ := json.Marshal(map[string]interface{}{"ok": true, "version": e.Version})
+	return string(data)
+}
+
+// Tools returns the tools the model may call
+func (e *Editor) Tools() []ai.Tool {
+	return tools
+}
+
+// Execute runs a tool call against one of the user's websites. Failures are
+// reported in the Edit rather than returned, so the model can read them and
+// correct itself.
+func (e *Editor) Execute(ctx context.Context, userID, websiteID uuid.UUID, call ai.ToolCall) Edit {
+	edit := Edit{Tool: call.Function.Name}
+
+	version, fields, err := e.execute(ctx, userID, websiteID, call)
+	if err != nil {
+		if !isUserError(err) {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"tool":       call.Function.Name,
+				"website_id": websiteID,
+			}).Warn("Website edit failed")
+		}
+		edit.Error = err.Error()
+		return edit
+	}
+
+	edit.Fields = fields
+	edit.Version = version
+	return edit
+}
+
+func (e *Editor) execute(ctx context.Context, userID, websiteID uuid.UUID, call ai.ToolCall) (int, []string, error) {
+	origin := revision.Origin{Author: revision.AuthorTool, UserID: &userID, Reason: toolReason(call)}
+
+	switch call.Function.Name {
+	case ToolUpdateSection:
+		var args updateSectionArgs
+		if err := decodeArgs(call.Function.Arguments, &args); err != nil {
+			return 0, nil, err
+		}
+		return e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+			return updateSection(content, args)
+		})
+
+	case ToolAddSection:
+		var args addSectionArgs
+		if err := decodeArgs(call.Function.Arguments, &args); err != nil {
+			return 0, nil, err
+		}
+		return e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+			return addSection(content, args)
+		})
+
+	case ToolReorderSections:
+		var args reorderSectionsArgs
+		if err := decodeArgs(call.Function.Arguments, &args); err != nil {
+			return 0, nil, err
+		}
+		return e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+			return reorderSections(content, args)
+		})
+
+	case ToolSetDesignToken:
+		var args setDesignTokenArgs
+		if err := decodeArgs(call.Function.Arguments, &args); err != nil {
+			return 0, nil, err
+		}
+		return e.editTokens(userID, websiteID, origin, func(tokens *models.DesignTokens) error {
+			return setDesignToken(tokens, args)
+		})
+
+	case ToolRegenerateSection:
+		var args regenerateSectionArgs
+		if err := decodeArgs(call.Function.Arguments, &args); err != nil {
+			return 0, nil, err
+		}
+		result, err := e.regenerateSection(ctx, userID, websiteID, args.Page, args.Index, args.Instructions, origin)
+		if err != nil {
+			return 0, nil, err
+		}
+		return result.Version, []string{FieldGeneratedContent}, nil
+
+	default:
+		return 0, nil, fmt.Errorf("%w: %s", ErrUnknownTool, call.Function.Name)
+	}
+}
+
+// Regeneration is the outcome of a section rewrite
+type Regeneration struct {
+	Section    models.Section
+	Version    int
+	TokensUsed int
+}
+
+// toolReason describes a tool call for the revision it creates
+func toolReason(call ai.ToolCall) string {
+	args := call.Function.Arguments
+	if len(args) > maxReasonLength {
+		cut := maxReasonLength
+		for cut > 0 && !utf8.RuneStart(args[cut]) {
+			cut--
+		}
+		args = args[:cut] + "…"
+	}
+	return call.Function.Name + " " + args
+}
+
+// RegenerateSection rewrites one section of a page, the home page for the
+// empty slug, with AI following the instructions (a general polish when
+// empty) and charges SECTION_REGENERATE_COST. The model sees only that
+// section and an outline of the rest of the page, and its reply must keep
+// the section's shape.
+func (e *Editor) RegenerateSection(ctx context.Context, userID, websiteID uuid.UUID, page string, index int, instructions string) (*Regeneration, error) {
+	reason := fmt.Sprintf("Regenerated section %d", index)
+	if page != "" {
+		reason += " of page " + page
+	}
+	if instructions != "" {
+		reason += ": " + instructions
+	}
+	return e.regenerateSection(ctx, userID, websiteID, page, index, instructions, revision.Origin{Author: revision.AuthorAI, UserID: &userID, Reason: reason})
+}
+
+// regenerateSection runs the model before the save loop so a retried save
+// does not pay for it twice; the save then checks the section is still the
+// one rewritten
+func (e *Editor) regenerateSection(ctx context.Context, userID, websiteID uuid.UUID, page string, index int, instructions string, origin revision.Origin) (*Regeneration, error) {
+	cost := e.config.SectionRegenerateCost
+	if cost > 0 {
+		hasTokens, err := e.tokenMgr.HasEnoughTokens(userID, cost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token balance: %w", err)
+		}
+		if !hasTokens {
+			return nil, fmt.Errorf("%w: need %d", ErrInsufficientTokens, cost)
+		}
+	}
+
+	site, err := e.load(userID, websiteID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := models.ParseContent(site.GeneratedContent)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := pageSections(content, page)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIndex(*sections, index); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(instructions) == "" {
+		instructions = defaultInstructions
+	}
+	original := (*sections)[index]
+
+	rewritten, err := e.rewrite(ctx, sectionContext(site, content, page, index), original, instructions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Regeneration{Section: models.Section{Type: original.Type, Content: rewritten}}
+	bill := &charge{amount: cost, txType: token.TypeSectionRegen, description: fmt.Sprintf("Regenerated %s section of %s", original.Type, site.Title)}
+	result.Version, _, err = e.save(userID, websiteID, FieldGeneratedContent, origin, bill, func(site *models.Website) (map[string]interface{}, error) {
+		content, err := models.ParseContent(site.GeneratedContent)
+		if err != nil {
+			return nil, err
+		}
+		sections, err := pageSections(content, page)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkIndex(*sections, index); err != nil {
+			return nil, err
+		}
+		if (*sections)[index].Type != original.Type {
+			return nil, fmt.Errorf("%w: the section moved while it was being rewritten", ErrInvalidEdit)
+		}
+		(*sections)[index].Content = rewritten
+		data, err := content.JSON()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"generated_content": data}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cost > 0 {
+		result.TokensUsed = cost
+	}
+	return result, nil
+}
+
+// sectionContext describes the site around a section: its title and
+// description, the page it is on and an outline of the page's other sections
+func sectionContext(site *models.Website, content *models.SiteContent, slug string, index int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Website: %s\n", site.Title)
+	if site.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", site.Description)
+	}
+	sections := content.Sections
+	if page := content.Page(slug); page != nil {
+		fmt.Fprintf(&b, "Page: %s (/%s)\n", page.Title, page.Slug)
+		sections = page.Sections
+	}
+	b.WriteString("Sections:\n")
+	for i, section := range sections {
+		fmt.Fprintf(&b, "%d. %s", i, section.Type)
+		if i == index {
+			b.WriteString(" (the section to rewrite)")
+		} else if heading := sectionHeading(section); heading != "" {
+			fmt.Fprintf(&b, ": %s", heading)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// sectionHeading returns the title-like text of a section, if any
+func sectionHeading(section models.Section) string {
+	for _, key := range []string{"title", "heading", "headline", "name"} {
+		if text, ok := section.Content[key].(string); ok && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// rewrite returns new content for a section following the instructions
+func (e *Editor) rewrite(ctx context.Context, siteContext string, target models.Section, instructions string) (map[string]interface{}, error) {
+	current, err := json.Marshal(target.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionType := target.Type
+	if registered, ok := section.Builtin.Lookup(target.Type); ok {
+		sectionType = fmt.Sprintf("%s (%s Content schema: %s)", target.Type, registered.Hint, registered.Schema)
+	}
+
+	messages := []ai.Message{
+		{
+			Role: "system",
+			Content: "You rewrite one section of a website. Reply with only the JSON object of the section's new content, " +
+				"with the same keys and the same kind of value for each key.",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("%s\nSection type: %s\nCurrent content: %s\nInstructions: %s",
+				siteContext, sectionType, current, instructions),
+		},
+	}
+
+	resp, err := e.kimi.ChatCompletion(ctx, messages, regenerateMaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrGenerationFailed)
+	}
+
+	var rewritten map[string]interface{}
+	if err := json.Unmarshal([]byte(website.ExtractJSON(resp.Choices[0].Message.Content)), &rewritten); err != nil {
+		return nil, fmt.Errorf("%w: reply is not section content", ErrGenerationFailed)
+	}
+	if err := validateSection(models.Section{Type: target.Type, Content: rewritten}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	if err := sameShape(target.Content, rewritten); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	return rewritten, nil
+}
+
+// SetColors replaces the color tokens of one of the user's websites, e.g.
+// with a generated palette, and returns the new content version
+func (e *Editor) SetColors(userID, websiteID uuid.UUID, colors map[string]string, reason string) (int, error) {
+	for key, value := range colors {
+		if err := design.ValidateToken("colors", key, value); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidEdit, err)
+		}
+	}
+
+	version, _, err := e.editTokens(userID, websiteID, revision.ByUser(userID, reason), func(tokens *models.DesignTokens) error {
+		tokens.Colors = make(map[string]string, len(colors))
+		for key, value := range colors {
+			tokens.Colors[key] = value
+		}
+		return nil
+	})
+	return version, err
+}
+
+// editContent applies mutate to the website's content and saves it
+func (e *Editor) editContent(userID, websiteID uuid.UUID, origin revision.Origin, mutate func(*models.SiteContent) error) (int, []string, error) {
+	return e.save(userID, websiteID, FieldGeneratedContent, origin, nil, func(site *models.Website) (map[string]interface{}, error) {
+		content, err := models.ParseContent(site.GeneratedContent)
+		if err != nil {
+			return nil, err
+		}
+		if err := mutate(content); err != nil {
+			return nil, err
+		}
+		data, err := content.JSON()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"generated_content": data}, nil
+	})
+}
+
+// editTokens applies mutate to the website's design tokens and saves them
+func (e *Editor) editTokens(userID, websiteID uuid.UUID, origin revision.Origin, mutate func(*models.DesignTokens) error) (int, []string, error) {
+	return e.save(userID, websiteID, FieldDesignTokens, origin, nil, func(site *models.Website) (map[string]interface{}, error) {
+		tokens, err := models.ParseDesignTokens(site.DesignTokens)
+		if err != nil {
+			return nil, err
+		}
+		if err := mutate(tokens); err != nil {
+			return nil, err
+		}
+		data, err := tokens.JSON()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"design_tokens": data}, nil
+	})
+}
+
+// charge is a price billed with an edit
+type charge struct {
+	amount      int
+	txType      string
+	description string
+}
+
+// save loads the website, computes the updates and writes them only if no
+// other edit landed in between, bumping the content version. The revision
+// and the charge, if any, are written in the same transaction. Lost races
+// are retried on fresh data; the watchers of the website are notified on
+// success.
+func (e *Editor) save(userID, websiteID uuid.UUID, field string, origin revision.Origin, bill *charge, build func(*models.Website) (map[string]interface{}, error)) (int, []string, error) {
+	for attempt := 0; attempt < saveAttempts; attempt++ {
+		site, err := e.load(userID, websiteID)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		updates, err := build(site)
+		if err != nil {
+			return 0, nil, err
+		}
+		version := site.ContentVersion + 1
+		updates["content_version"] = version
+
+		saved := false
+		err = e.db.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Website{}).
+				Where("id = ? AND content_version = ?", site.ID, site.ContentVersion).
+				Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+
+			var after models.Website
+			if err := tx.First(&after, "id = ?", site.ID).Error; err != nil {
+				return err
+			}
+			if err := e.revisions.Record(tx, site, &after, origin); err != nil {
+				return err
+			}
+
+			if bill != nil && bill.amount > 0 {
+				if _, err := e.tokenMgr.DeductTokensTx(tx, userID, bill.amount, bill.txType, bill.description, &site.ID); err != nil {
+					return fmt.Errorf("%w: %v", ErrInsufficientTokens, err)
+				}
+			}
+			saved = true
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, ErrInsufficientTokens) {
+				return 0, nil, err
+			}
+			return 0, nil, fmt.Errorf("failed to save website: %w", err)
+		}
+		if !saved {
+			continue
+		}
+
+		fields := []string{field}
+		if e.notifier != nil {
+			e.notifier.NotifyContentChanged(websiteID, userID, fields, version)
+		}
+		return version, fields, nil
+	}
+	return 0, nil, ErrConflict
+}
+
+// isUserError reports whether err is the caller's mistake rather than a
+// failure worth logging
+func isUserError(err error) bool {
+	return errors.Is(err, ErrInvalidEdit) ||
+		errors.Is(err, ErrUnknownTool) ||
+		errors.Is(err, ErrSectionNotFound) ||
+		errors.Is(err, ErrPageNotFound) ||
+		errors.Is(err, ErrTranslationNotFound) ||
+		errors.Is(err, ErrInsufficientTokens)
+}
+
+func (e *Editor) load(userID, websiteID uuid.UUID) (*models.Website, error) {
+	var site models.Website
+	if err := e.db.DB.Where("id = ? AND user_id = ?", websiteID, userID).First(&site).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebsiteNotFound
+		}
+		return nil, fmt.Errorf("failed to load website: %w", err)
+	}
+	return &site, nil
+}
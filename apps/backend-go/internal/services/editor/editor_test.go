@@ -0,0 +1,26 @@
+package editor
+
+import (
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditResult(t *testing.T) {
+	assert.JSONEq(t, `{"ok":true,"version":4}`, Edit{Tool: ToolAddSection, Version: 4}.Result())
+	assert.JSONEq(t, `{"error":"invalid edit: content is empty"}`, Edit{Tool: ToolUpdateSection, Error: "invalid edit: content is empty"}.Result())
+}
+
+func TestSectionContext(t *testing.T) {
+	site := &models.Website{Title: "Sweet Bites", Description: "A family bakery"}
+	content := testContent(t)
+
+	assert.Equal(t, "Website: Sweet Bites\n"+
+		"Description: A family bakery\n"+
+		"Sections:\n"+
+		"0. hero: Welcome\n"+
+		"1. about (the section to rewrite)\n"+
+		"2. contact\n", sectionContext(site, content, "", 1))
+}
@@ -0,0 +1,346 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/revision"
+	"backend-go/internal/services/section"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxPages bounds the pages of a site besides the home page
+	maxPages = 20
+	// maxPageTitleLength bounds page titles and SEO titles, in characters
+	maxPageTitleLength = 120
+	// maxNavLabelLength bounds the label of a page in the menu
+	maxNavLabelLength = 40
+	// maxSEODescriptionLength bounds a page's meta description
+	maxSEODescriptionLength = 300
+	// maxKeywords bounds a page's SEO keywords
+	maxKeywords = 20
+	// maxImageURLLength bounds the URL of a page's share image
+	maxImageURLLength = 2048
+)
+
+// PageFields describe a new page
+type PageFields struct {
+	Slug     string
+	Title    string
+	NavLabel string
+	SEO      *models.SEO
+	// Sections default to a hero with the page's title
+	Sections []models.Section
+	// Position is where the page goes in the menu; the end when nil
+	Position *int
+}
+
+// PageUpdate lists the changes to a page; nil fields are kept
+type PageUpdate struct {
+	Slug     *string
+	Title    *string
+	NavLabel *string
+	SEO      *models.SEO
+	Sections []models.Section
+}
+
+// AddPage adds a page to one of the user's websites and returns the new
+// content version
+func (e *Editor) AddPage(userID, websiteID uuid.UUID, fields PageFields) (int, error) {
+	origin := revision.ByUser(userID, "Added page "+fields.Slug)
+	version, _, err := e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+		return addPage(content, fields)
+	})
+	return version, err
+}
+
+// UpdatePage changes a page of one of the user's websites. Renaming a page
+// also moves the pages below it.
+func (e *Editor) UpdatePage(userID, websiteID uuid.UUID, slug string, update PageUpdate) (int, error) {
+	origin := revision.ByUser(userID, "Updated page "+slug)
+	version, _, err := e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+		return updatePage(content, slug, update)
+	})
+	return version, err
+}
+
+// DeletePage removes a page that has no pages below it
+func (e *Editor) DeletePage(userID, websiteID uuid.UUID, slug string) (int, error) {
+	origin := revision.ByUser(userID, "Deleted page "+slug)
+	version, _, err := e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+		return deletePage(content, slug)
+	})
+	return version, err
+}
+
+// ReorderPages changes the order of the pages in the menu; order lists
+// every slug once
+func (e *Editor) ReorderPages(userID, websiteID uuid.UUID, order []string) (int, error) {
+	origin := revision.ByUser(userID, "Reordered pages")
+	version, _, err := e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+		return reorderPages(content, order)
+	})
+	return version, err
+}
+
+// findPage returns the index of the page with the slug
+func findPage(content *models.SiteContent, slug string) (int, error) {
+	for i := range content.Pages {
+		if content.Pages[i].Slug == slug {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: no page %q", ErrPageNotFound, slug)
+}
+
+// checkSlug validates the slug of a new or renamed page: nested pages need
+// their parent, and no two pages share a slug
+func checkSlug(content *models.SiteContent, slug string) error {
+	if !models.ValidSlug(slug) {
+		return fmt.Errorf("%w: slug %q must be lower-case words joined by hyphens, e.g. about or blog/opening-day", ErrInvalidEdit, slug)
+	}
+	if content.Page(slug) != nil {
+		return fmt.Errorf("%w: there is already a page %q", ErrInvalidEdit, slug)
+	}
+	if parent, nested := parentOf(slug); nested && content.Page(parent) == nil {
+		return fmt.Errorf("%w: add the page %q before %q", ErrInvalidEdit, parent, slug)
+	}
+	if _, ok := content.Translations[slug]; ok {
+		return fmt.Errorf("%w: %q is the path of the site's %s translation", ErrInvalidEdit, slug, slug)
+	}
+	return nil
+}
+
+// translatedPages calls fn with the pages of each translation, so they
+// follow the pages they translate
+func translatedPages(content *models.SiteContent, fn func(pages *[]models.Page)) {
+	for _, t := range content.Translations {
+		if t != nil {
+			fn(&t.Pages)
+		}
+	}
+}
+
+// movePages renames the page with the slug and the pages below it
+func movePages(pages []models.Page, slug, renamed string) {
+	for i := range pages {
+		if child := pages[i].Slug; child == slug || strings.HasPrefix(child, slug+"/") {
+			pages[i].Slug = renamed + strings.TrimPrefix(child, slug)
+		}
+	}
+}
+
+// parentOf returns the slug of the page a nested page is below
+func parentOf(slug string) (string, bool) {
+	i := strings.LastIndex(slug, "/")
+	if i < 0 {
+		return "", false
+	}
+	return slug[:i], true
+}
+
+func checkText(field, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("%w: %s may be at most %d characters", ErrInvalidEdit, field, max)
+	}
+	return nil
+}
+
+func checkSEO(seo *models.SEO) error {
+	if seo == nil {
+		return nil
+	}
+	if err := checkText("seo.title", seo.Title, maxPageTitleLength); err != nil {
+		return err
+	}
+	if err := checkText("seo.description", seo.Description, maxSEODescriptionLength); err != nil {
+		return err
+	}
+	if seo.Image != "" && !strings.HasPrefix(seo.Image, "https://") {
+		return fmt.Errorf("%w: seo.image must be an https URL", ErrInvalidEdit)
+	}
+	if err := checkText("seo.image", seo.Image, maxImageURLLength); err != nil {
+		return err
+	}
+	if len(seo.Keywords) > maxKeywords {
+		return fmt.Errorf("%w: a page has at most %d keywords", ErrInvalidEdit, maxKeywords)
+	}
+	for _, keyword := range seo.Keywords {
+		if err := checkText("seo.keywords", keyword, maxNavLabelLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSections(sections []models.Section) error {
+	if len(sections) > maxSections {
+		return fmt.Errorf("%w: a page has at most %d sections", ErrInvalidEdit, maxSections)
+	}
+	for i, s := range sections {
+		if err := validateSection(s); err != nil {
+			return fmt.Errorf("%w: section %d: %v", ErrInvalidEdit, i, err)
+		}
+	}
+	return nil
+}
+
+// addPage inserts a page into the menu order
+func addPage(content *models.SiteContent, fields PageFields) error {
+	if len(content.Pages) >= maxPages {
+		return fmt.Errorf("%w: a site has at most %d pages besides the home page", ErrInvalidEdit, maxPages)
+	}
+	slug := strings.Trim(strings.ToLower(strings.TrimSpace(fields.Slug)), "/")
+	if err := checkSlug(content, slug); err != nil {
+		return err
+	}
+
+	page := models.Page{
+		Slug:     slug,
+		Title:    strings.TrimSpace(fields.Title),
+		NavLabel: strings.TrimSpace(fields.NavLabel),
+		SEO:      fields.SEO,
+		Sections: fields.Sections,
+	}
+	if page.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidEdit)
+	}
+	if err := checkPage(&page); err != nil {
+		return err
+	}
+	if len(page.Sections) == 0 {
+		hero, _ := section.Builtin.Lookup("hero")
+		page.Sections = []models.Section{{Type: "hero", Content: hero.DefaultContent(map[string]interface{}{"title": page.Title})}}
+	}
+
+	position := len(content.Pages)
+	if fields.Position != nil {
+		position = *fields.Position
+		if position < 0 || position > len(content.Pages) {
+			return fmt.Errorf("%w: position must be between 0 and %d", ErrInvalidEdit, len(content.Pages))
+		}
+	}
+	content.Pages = append(content.Pages, models.Page{})
+	copy(content.Pages[position+1:], content.Pages[position:])
+	content.Pages[position] = page
+	return nil
+}
+
+// checkPage validates a page's title, label, SEO and sections
+func checkPage(page *models.Page) error {
+	if err := checkText("title", page.Title, maxPageTitleLength); err != nil {
+		return err
+	}
+	if err := checkText("navLabel", page.NavLabel, maxNavLabelLength); err != nil {
+		return err
+	}
+	if err := checkSEO(page.SEO); err != nil {
+		return err
+	}
+	return checkSections(page.Sections)
+}
+
+// updatePage applies the changes to a page, moving the pages below it when
+// it is renamed
+func updatePage(content *models.SiteContent, slug string, update PageUpdate) error {
+	index, err := findPage(content, slug)
+	if err != nil {
+		return err
+	}
+	page := content.Pages[index]
+
+	if update.Title != nil {
+		page.Title = strings.TrimSpace(*update.Title)
+		if page.Title == "" {
+			return fmt.Errorf("%w: title is required", ErrInvalidEdit)
+		}
+	}
+	if update.NavLabel != nil {
+		page.NavLabel = strings.TrimSpace(*update.NavLabel)
+	}
+	if update.SEO != nil {
+		page.SEO = update.SEO
+	}
+	if update.Sections != nil {
+		page.Sections = update.Sections
+	}
+	if err := checkPage(&page); err != nil {
+		return err
+	}
+
+	if update.Slug != nil {
+		renamed := strings.Trim(strings.ToLower(strings.TrimSpace(*update.Slug)), "/")
+		if renamed != slug {
+			if strings.HasPrefix(renamed, slug+"/") {
+				return fmt.Errorf("%w: a page cannot move below itself", ErrInvalidEdit)
+			}
+			if err := checkSlug(content, renamed); err != nil {
+				return err
+			}
+			for _, child := range content.Pages {
+				if strings.HasPrefix(child.Slug, slug+"/") && !models.ValidSlug(renamed+strings.TrimPrefix(child.Slug, slug)) {
+					return fmt.Errorf("%w: the page %q would be nested too deeply", ErrInvalidEdit, child.Slug)
+				}
+			}
+			movePages(content.Pages, slug, renamed)
+			translatedPages(content, func(pages *[]models.Page) {
+				movePages(*pages, slug, renamed)
+			})
+			page.Slug = renamed
+		}
+	}
+
+	content.Pages[index] = page
+	return nil
+}
+
+// deletePage removes a page without pages below it
+func deletePage(content *models.SiteContent, slug string) error {
+	index, err := findPage(content, slug)
+	if err != nil {
+		return err
+	}
+	for _, page := range content.Pages {
+		if strings.HasPrefix(page.Slug, slug+"/") {
+			return fmt.Errorf("%w: delete the page %q first", ErrInvalidEdit, page.Slug)
+		}
+	}
+	content.Pages = append(content.Pages[:index], content.Pages[index+1:]...)
+	translatedPages(content, func(pages *[]models.Page) {
+		kept := (*pages)[:0]
+		for _, page := range *pages {
+			if page.Slug != slug {
+				kept = append(kept, page)
+			}
+		}
+		*pages = kept
+	})
+	return nil
+}
+
+// reorderPages puts the pages in the order of their slugs
+func reorderPages(content *models.SiteContent, order []string) error {
+	if len(order) != len(content.Pages) {
+		return fmt.Errorf("%w: order must list all %d pages", ErrInvalidEdit, len(content.Pages))
+	}
+
+	reordered := make([]models.Page, 0, len(order))
+	seen := make(map[string]bool, len(order))
+	for _, slug := range order {
+		index, err := findPage(content, slug)
+		if err != nil {
+			return err
+		}
+		if seen[slug] {
+			return fmt.Errorf("%w: order lists %q twice", ErrInvalidEdit, slug)
+		}
+		seen[slug] = true
+		reordered = append(reordered, content.Pages[index])
+	}
+	content.Pages = reordered
+	return nil
+}
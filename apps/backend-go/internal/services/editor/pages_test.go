@@ -0,0 +1,110 @@
+package editor
+
+import (
+	"errors"
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pageSlugs(content *models.SiteContent) []string {
+	slugs := make([]string, len(content.Pages))
+	for i, page := range content.Pages {
+		slugs[i] = page.Slug
+	}
+	return slugs
+}
+
+func TestAddPage(t *testing.T) {
+	content := testContent(t)
+
+	require.NoError(t, addPage(content, PageFields{Slug: " About ", Title: "About us"}))
+	about := content.Page("about")
+	require.NotNil(t, about)
+	require.Len(t, about.Sections, 1)
+	assert.Equal(t, "hero", about.Sections[0].Type)
+	assert.Equal(t, "About us", about.Sections[0].Content["title"])
+
+	first := 0
+	require.NoError(t, addPage(content, PageFields{Slug: "blog", Title: "Blog", Position: &first}))
+	require.NoError(t, addPage(content, PageFields{Slug: "blog/opening-day", Title: "Opening day"}))
+	assert.Equal(t, []string{"blog", "about", "blog/opening-day"}, pageSlugs(content))
+
+	tests := []struct {
+		fields PageFields
+		want   string
+	}{
+		{PageFields{Slug: "about", Title: "Again"}, `already a page "about"`},
+		{PageFields{Slug: "../etc", Title: "Escape"}, "must be lower-case words"},
+		{PageFields{Slug: "news/today", Title: "Today"}, `add the page "news" before "news/today"`},
+		{PageFields{Slug: "contact"}, "title is required"},
+		{PageFields{Slug: "contact", Title: "Contact", Sections: []models.Section{{Type: "faq", Content: map[string]interface{}{}}}}, "section 0: content.title is required"},
+		{PageFields{Slug: "contact", Title: "Contact", SEO: &models.SEO{Keywords: make([]string, maxKeywords+1)}}, "at most 20 keywords"},
+		{PageFields{Slug: "contact", Title: "Contact", SEO: &models.SEO{Image: "javascript:alert(1)"}}, "seo.image must be an https URL"},
+	}
+	for _, tt := range tests {
+		err := addPage(content, tt.fields)
+		require.Error(t, err, tt.fields.Slug)
+		assert.True(t, errors.Is(err, ErrInvalidEdit))
+		assert.Contains(t, err.Error(), tt.want)
+	}
+}
+
+func TestUpdatePage(t *testing.T) {
+	content := testContent(t)
+	require.NoError(t, addPage(content, PageFields{Slug: "blog", Title: "Blog"}))
+	require.NoError(t, addPage(content, PageFields{Slug: "blog/opening-day", Title: "Opening day"}))
+
+	title, label, slug := "News", " Latest ", "news"
+	require.NoError(t, updatePage(content, "blog", PageUpdate{Title: &title, NavLabel: &label, Slug: &slug}))
+	assert.Equal(t, []string{"news", "news/opening-day"}, pageSlugs(content), "pages below move along")
+	assert.Equal(t, "Latest", content.Page("news").Label())
+
+	inside := "news/opening-day/news"
+	err := updatePage(content, "news", PageUpdate{Slug: &inside})
+	assert.True(t, errors.Is(err, ErrInvalidEdit))
+
+	empty := " "
+	err = updatePage(content, "news", PageUpdate{Title: &empty})
+	assert.True(t, errors.Is(err, ErrInvalidEdit))
+
+	err = updatePage(content, "blog", PageUpdate{Title: &title})
+	assert.True(t, errors.Is(err, ErrPageNotFound))
+}
+
+func TestDeleteAndReorderPages(t *testing.T) {
+	content := testContent(t)
+	for _, slug := range []string{"about", "blog", "blog/opening-day"} {
+		require.NoError(t, addPage(content, PageFields{Slug: slug, Title: slug}))
+	}
+
+	err := deletePage(content, "blog")
+	assert.True(t, errors.Is(err, ErrInvalidEdit), "pages below must go first")
+	require.NoError(t, deletePage(content, "blog/opening-day"))
+	assert.True(t, errors.Is(deletePage(content, "blog/opening-day"), ErrPageNotFound))
+
+	require.NoError(t, reorderPages(content, []string{"blog", "about"}))
+	assert.Equal(t, []string{"blog", "about"}, pageSlugs(content))
+	assert.True(t, errors.Is(reorderPages(content, []string{"blog", "blog"}), ErrInvalidEdit))
+	assert.True(t, errors.Is(reorderPages(content, []string{"blog"}), ErrInvalidEdit))
+}
+
+func TestSectionToolsEditPages(t *testing.T) {
+	content := testContent(t)
+	require.NoError(t, addPage(content, PageFields{Slug: "about", Title: "About us"}))
+
+	require.NoError(t, addSection(content, addSectionArgs{Page: "about", Type: "contact"}))
+	require.NoError(t, updateSection(content, updateSectionArgs{Page: "about", Index: 0, Content: map[string]interface{}{"title": "Our story"}}))
+	require.NoError(t, reorderSections(content, reorderSectionsArgs{Page: "about", Order: []int{1, 0}}))
+
+	about := content.Page("about")
+	assert.Equal(t, "contact", about.Sections[0].Type)
+	assert.Equal(t, "Our story", about.Sections[1].Content["title"])
+	assert.Equal(t, []string{"hero", "about", "contact"}, sectionTypes(content), "the home page is unchanged")
+
+	err := updateSection(content, updateSectionArgs{Page: "menu", Index: 0, Content: map[string]interface{}{"title": "x"}})
+	assert.True(t, errors.Is(err, ErrPageNotFound))
+}
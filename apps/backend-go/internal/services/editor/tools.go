@@ -0,0 +1,245 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/section"
+)
+
+// Tool names
+const (
+	ToolUpdateSection     = "update_section"
+	ToolAddSection        = "add_section"
+	ToolReorderSections   = "reorder_sections"
+	ToolSetDesignToken    = "set_design_token"
+	ToolRegenerateSection = "regenerate_section"
+)
+
+// Edited fields, named as in the website's JSON
+const (
+	FieldGeneratedContent = "generatedContent"
+	FieldDesignTokens     = "designTokens"
+)
+
+// tools are offered to the model when the chat is about a website
+var tools = []ai.Tool{
+	ai.NewTool(ToolUpdateSection,
+		"Change fields of an existing section, e.g. the hero title. Only the given fields change; a null value removes a field.",
+		`{"type":"object","properties":{
+			"page":{"type":"string","description":"Slug of the page, e.g. about; the home page when omitted"},
+			"index":{"type":"integer","description":"Position of the section, starting at 0"},
+			"content":{"type":"object","description":"Fields to set, e.g. {\"title\": \"Fresh bread daily\"}"}
+		},"required":["index","content"]}`),
+	ai.NewTool(ToolAddSection,
+		"Add a new section to the page. Fields left out of content get the section type's defaults.",
+		`{"type":"object","properties":{
+			"page":{"type":"string","description":"Slug of the page, e.g. about; the home page when omitted"},
+			"type":{"type":"string","enum":`+sectionTypeNames()+`},
+			"content":{"type":"object","description":"The section's fields, following the section type's schema"},
+			"position":{"type":"integer","description":"Where to insert it, starting at 0; the end of the page when omitted"}
+		},"required":["type","content"]}`),
+	ai.NewTool(ToolReorderSections,
+		"Change the order of the sections.",
+		`{"type":"object","properties":{
+			"page":{"type":"string","description":"Slug of the page, e.g. about; the home page when omitted"},
+			"order":{"type":"array","items":{"type":"integer"},"description":"The current positions of all sections, in their new order"}
+		},"required":["order"]}`),
+	ai.NewTool(ToolSetDesignToken,
+		"Change a design token such as a color or font.",
+		`{"type":"object","properties":{
+			"token":{"type":"string","enum":["colors.primary","colors.secondary","colors.accent","colors.background","colors.text","colors.primaryLight","colors.primaryDark","colors.secondaryLight","colors.secondaryDark","colors.accentLight","colors.accentDark","typography.headingFont","typography.bodyFont","spacing.small","spacing.medium","spacing.large","borderRadius"]},
+			"value":{"type":"string","description":"A hex color for colors, a known font family such as Inter or Playfair Display for typography, a CSS length otherwise"}
+		},"required":["token","value"]}`),
+	ai.NewTool(ToolRegenerateSection,
+		"Rewrite the content of a section from scratch following instructions, e.g. to change its tone.",
+		`{"type":"object","properties":{
+			"page":{"type":"string","description":"Slug of the page, e.g. about; the home page when omitted"},
+			"index":{"type":"integer","description":"Position of the section, starting at 0"},
+			"instructions":{"type":"string","description":"What the new content should be like"}
+		},"required":["index","instructions"]}`),
+}
+
+// sectionTypeNames lists the registered section types as a JSON array
+func sectionTypeNames() string {
+	names, _ := json.Marshal(section.Builtin.Names())
+	return string(names)
+}
+
+type updateSectionArgs struct {
+	Page    string                 `json:"page"`
+	Index   int                    `json:"index"`
+	Content map[string]interface{} `json:"content"`
+}
+
+type addSectionArgs struct {
+	Page     string                 `json:"page"`
+	Type     string                 `json:"type"`
+	Content  map[string]interface{} `json:"content"`
+	Position *int                   `json:"position"`
+}
+
+type reorderSectionsArgs struct {
+	Page  string `json:"page"`
+	Order []int  `json:"order"`
+}
+
+type setDesignTokenArgs struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+type regenerateSectionArgs struct {
+	Page         string `json:"page"`
+	Index        int    `json:"index"`
+	Instructions string `json:"instructions"`
+}
+
+// decodeArgs parses a tool call's arguments
+func decodeArgs(arguments string, args interface{}) error {
+	if err := json.Unmarshal([]byte(arguments), args); err != nil {
+		return fmt.Errorf("%w: arguments are not valid JSON", ErrInvalidEdit)
+	}
+	return nil
+}
+
+// pageSections returns the sections of the page with the slug; the empty
+// slug is the home page
+func pageSections(content *models.SiteContent, slug string) (*[]models.Section, error) {
+	sections, ok := content.SectionsOf(slug)
+	if !ok {
+		return nil, fmt.Errorf("%w: no page %q", ErrPageNotFound, slug)
+	}
+	return sections, nil
+}
+
+func checkIndex(sections []models.Section, index int) error {
+	if index < 0 || index >= len(sections) {
+		return fmt.Errorf("%w: no section at index %d, the page has %d", ErrSectionNotFound, index, len(sections))
+	}
+	return nil
+}
+
+// updateSection merges fields into a section
+func updateSection(content *models.SiteContent, args updateSectionArgs) error {
+	sections, err := pageSections(content, args.Page)
+	if err != nil {
+		return err
+	}
+	if err := checkIndex(*sections, args.Index); err != nil {
+		return err
+	}
+	if len(args.Content) == 0 {
+		return fmt.Errorf("%w: content is empty", ErrInvalidEdit)
+	}
+
+	updated := (*sections)[args.Index]
+	merged := make(map[string]interface{}, len(updated.Content)+len(args.Content))
+	for key, value := range updated.Content {
+		merged[key] = value
+	}
+	for key, value := range args.Content {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	updated.Content = merged
+
+	if err := validateSection(updated); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEdit, err)
+	}
+	(*sections)[args.Index] = updated
+	return nil
+}
+
+// addSection inserts a section of a registered type, filling fields the
+// content leaves out from the type's defaults
+func addSection(content *models.SiteContent, args addSectionArgs) error {
+	sections, err := pageSections(content, args.Page)
+	if err != nil {
+		return err
+	}
+	if len(*sections) >= maxSections {
+		return fmt.Errorf("%w: a page has at most %d sections", ErrInvalidEdit, maxSections)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(args.Type))
+	sectionType, ok := section.Builtin.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrInvalidEdit, section.Builtin.Validate(name, nil))
+	}
+	added := models.Section{Type: name, Content: sectionType.DefaultContent(args.Content)}
+	if err := validateSection(added); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEdit, err)
+	}
+
+	position := len(*sections)
+	if args.Position != nil {
+		position = *args.Position
+		if position < 0 || position > len(*sections) {
+			return fmt.Errorf("%w: position must be between 0 and %d", ErrInvalidEdit, len(*sections))
+		}
+	}
+
+	*sections = append(*sections, models.Section{})
+	copy((*sections)[position+1:], (*sections)[position:])
+	(*sections)[position] = added
+	return nil
+}
+
+// reorderSections applies a permutation of the section positions
+func reorderSections(content *models.SiteContent, args reorderSectionsArgs) error {
+	sections, err := pageSections(content, args.Page)
+	if err != nil {
+		return err
+	}
+	if len(args.Order) != len(*sections) {
+		return fmt.Errorf("%w: order must list all %d sections", ErrInvalidEdit, len(*sections))
+	}
+
+	sorted := append([]int(nil), args.Order...)
+	sort.Ints(sorted)
+	for i, index := range sorted {
+		if index != i {
+			return fmt.Errorf("%w: order must list each position from 0 to %d once", ErrInvalidEdit, len(*sections)-1)
+		}
+	}
+
+	reordered := make([]models.Section, len(args.Order))
+	for i, index := range args.Order {
+		reordered[i] = (*sections)[index]
+	}
+	*sections = reordered
+	return nil
+}
+
+// setDesignToken changes one design token
+func setDesignToken(tokens *models.DesignTokens, args setDesignTokenArgs) error {
+	value := strings.TrimSpace(args.Value)
+	group, key, _ := strings.Cut(args.Token, ".")
+	if err := design.ValidateToken(group, key, value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEdit, err)
+	}
+	if font, ok := design.LookupFont(value); ok && group == "typography" {
+		value = font.Name
+	}
+
+	switch group {
+	case "colors":
+		tokens.Colors[key] = value
+	case "typography":
+		tokens.Typography[key] = value
+	case "spacing":
+		tokens.Spacing[key] = value
+	case "borderRadius":
+		tokens.BorderRadius = value
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func testContent(t *testing.T) *models.SiteContent {
+	t.Helper()
+	content, err := models.ParseContent(datatypes.JSON(`{
+		"title": "Sweet Bites",
+		"sections": [
+			{"type": "hero", "content": {"title": "Welcome", "subtitle": "Fresh bread"}},
+			{"type": "about", "content": {"text": "Since 1990"}},
+			{"type": "contact", "content": {"email": "hello@sweetbites.test"}}
+		],
+		"theme": {"mood": "warm"}
+	}`))
+	require.NoError(t, err)
+	return content
+}
+
+func sectionTypes(content *models.SiteContent) []string {
+	types := make([]string, len(content.Sections))
+	for i, section := range content.Sections {
+		types[i] = section.Type
+	}
+	return types
+}
+
+func TestUpdateSection(t *testing.T) {
+	content := testContent(t)
+
+	err := updateSection(content, updateSectionArgs{Index: 0, Content: map[string]interface{}{
+		"title":    "Fresh bread daily",
+		"subtitle": nil,
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"title": "Fresh bread daily"}, content.Sections[0].Content)
+
+	err = updateSection(content, updateSectionArgs{Index: 3, Content: map[string]interface{}{"title": "x"}})
+	assert.True(t, errors.Is(err, ErrSectionNotFound))
+
+	err = updateSection(content, updateSectionArgs{Index: 0, Content: map[string]interface{}{"title": strings.Repeat("a", maxTextLength+1)}})
+	assert.True(t, errors.Is(err, ErrInvalidEdit))
+	assert.Equal(t, "Fresh bread daily", content.Sections[0].Content["title"], "a rejected edit leaves the section alone")
+
+	err = updateSection(content, updateSectionArgs{Index: 0, Content: map[string]interface{}{"title": nil}})
+	assert.True(t, errors.Is(err, ErrInvalidEdit), "the hero's title is required")
+}
+
+func TestAddSection(t *testing.T) {
+	content := testContent(t)
+	position := 1
+
+	require.NoError(t, addSection(content, addSectionArgs{Type: "Gallery", Position: &position}))
+	assert.Equal(t, []string{"hero", "gallery", "about", "contact"}, sectionTypes(content))
+	assert.Equal(t, "Gallery", content.Sections[1].Content["title"], "fields left out get the type's defaults")
+
+	faq := []interface{}{map[string]interface{}{"question": "Open on Sundays?", "answer": "Yes"}}
+	require.NoError(t, addSection(content, addSectionArgs{Type: "faq", Content: map[string]interface{}{"items": faq}}))
+	assert.Equal(t, "faq", content.Sections[4].Type)
+	assert.Equal(t, faq, content.Sections[4].Content["items"])
+
+	for _, args := range []addSectionArgs{
+		{Type: "<script>"},
+		{Type: "carousel"},
+		{Type: "faq", Content: map[string]interface{}{"items": []interface{}{"Open on Sundays?"}}},
+	} {
+		err := addSection(content, args)
+		assert.True(t, errors.Is(err, ErrInvalidEdit), "type %s", args.Type)
+	}
+
+	position = 9
+	err := addSection(content, addSectionArgs{Type: "pricing", Position: &position})
+	assert.True(t, errors.Is(err, ErrInvalidEdit))
+}
+
+func TestReorderSections(t *testing.T) {
+	content := testContent(t)
+
+	require.NoError(t, reorderSections(content, reorderSectionsArgs{Order: []int{2, 0, 1}}))
+	assert.Equal(t, []string{"contact", "hero", "about"}, sectionTypes(content))
+
+	for _, order := range [][]int{{0, 1}, {0, 0, 1}, {0, 1, 3}} {
+		err := reorderSections(content, reorderSectionsArgs{Order: order})
+		assert.True(t, errors.Is(err, ErrInvalidEdit), "order %v", order)
+	}
+}
+
+func TestSetDesignToken(t *testing.T) {
+	tokens, err := models.ParseDesignTokens(datatypes.JSON(`{"colors":{"primary":"#3B82F6"},"borderRadius":"0.5rem"}`))
+	require.NoError(t, err)
+
+	require.NoError(t, setDesignToken(tokens, setDesignTokenArgs{Token: "colors.primary", Value: " #1d4ed8 "}))
+	require.NoError(t, setDesignToken(tokens, setDesignTokenArgs{Token: "typography.headingFont", Value: "Playfair Display"}))
+	require.NoError(t, setDesignToken(tokens, setDesignTokenArgs{Token: "typography.bodyFont", Value: "open sans"}))
+	require.NoError(t, setDesignToken(tokens, setDesignTokenArgs{Token: "borderRadius", Value: "0"}))
+	assert.Equal(t, "#1d4ed8", tokens.Colors["primary"])
+	assert.Equal(t, "Playfair Display", tokens.Typography["headingFont"])
+	assert.Equal(t, "Open Sans", tokens.Typography["bodyFont"], "font names are stored as listed")
+	assert.Equal(t, "0", tokens.BorderRadius)
+
+	for _, args := range []setDesignTokenArgs{
+		{Token: "colors.primary", Value: "blue"},
+		{Token: "colors.link", Value: "#000"},
+		{Token: "typography.bodyFont", Value: "Inter; color: red"},
+		{Token: "typography.bodyFont", Value: "Wingdings"},
+		{Token: "spacing.large", Value: "calc(100vw)"},
+		{Token: "shadow", Value: "none"},
+	} {
+		err := setDesignToken(tokens, args)
+		assert.True(t, errors.Is(err, ErrInvalidEdit), "token %s", args.Token)
+	}
+}
+
+func TestContentKeepsUnknownKeys(t *testing.T) {
+	content := testContent(t)
+	require.NoError(t, reorderSections(content, reorderSectionsArgs{Order: []int{1, 0, 2}}))
+
+	data, err := content.JSON()
+	require.NoError(t, err)
+
+	var stored map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.Equal(t, map[string]interface{}{"mood": "warm"}, stored["theme"])
+	assert.Equal(t, "Sweet Bites", stored["title"])
+}
@@ -0,0 +1,271 @@
+package editor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/ai"
+	"backend-go/internal/services/revision"
+	"backend-go/internal/services/token"
+	"backend-go/internal/services/website"
+
+	"github.com/google/uuid"
+)
+
+// ErrTranslationNotFound is returned for locales the website is not
+// translated into
+var ErrTranslationNotFound = errors.New("translation not found")
+
+const (
+	// maxTranslations bounds the locales of a site besides the default one
+	maxTranslations = 5
+	// translateMaxTokens bounds the reply of a translation, which repeats
+	// the whole site
+	translateMaxTokens = 8192
+)
+
+// TranslationResult is the outcome of a translation
+type TranslationResult struct {
+	Locales    []string
+	Version    int
+	TokensUsed int
+}
+
+// Translate translates the website's content into each locale with AI and
+// charges TRANSLATE_COST per locale. Existing translations into those
+// locales are replaced. The model translates the default locale's content
+// and its reply must keep its structure.
+func (e *Editor) Translate(ctx context.Context, userID, websiteID uuid.UUID, locales []string) (*TranslationResult, error) {
+	locales = normalizeLocales(locales)
+	if len(locales) == 0 {
+		return nil, fmt.Errorf("%w: name at least one locale", ErrInvalidEdit)
+	}
+
+	cost := e.config.TranslateCost * len(locales)
+	if cost > 0 {
+		hasTokens, err := e.tokenMgr.HasEnoughTokens(userID, cost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token balance: %w", err)
+		}
+		if !hasTokens {
+			return nil, fmt.Errorf("%w: need %d", ErrInsufficientTokens, cost)
+		}
+	}
+
+	site, err := e.load(userID, websiteID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := models.ParseContent(site.GeneratedContent)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLocales(content, locales); err != nil {
+		return nil, err
+	}
+
+	source := content.Translation()
+	translations := make(map[string]*models.Translation, len(locales))
+	for _, locale := range locales {
+		translated, err := e.translate(ctx, source, content.DefaultLocale(), locale)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", locale, err)
+		}
+		translations[locale] = translated
+	}
+
+	list := strings.Join(locales, ", ")
+	origin := revision.Origin{Author: revision.AuthorAI, UserID: &userID, Reason: "Translated into " + list}
+	bill := &charge{amount: cost, txType: token.TypeTranslation, description: fmt.Sprintf("Translated %s into %s", site.Title, list)}
+	result := &TranslationResult{Locales: locales}
+	result.Version, _, err = e.save(userID, websiteID, FieldGeneratedContent, origin, bill, func(current *models.Website) (map[string]interface{}, error) {
+		// The translations are of the content as it was read
+		if current.ContentVersion != site.ContentVersion {
+			return nil, fmt.Errorf("%w: the website changed while it was being translated", ErrConflict)
+		}
+		content, err := models.ParseContent(current.GeneratedContent)
+		if err != nil {
+			return nil, err
+		}
+		if content.Translations == nil {
+			content.Translations = make(map[string]*models.Translation, len(translations))
+		}
+		for locale, translated := range translations {
+			content.Translations[locale] = translated
+		}
+		data, err := content.JSON()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"generated_content": data}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cost > 0 {
+		result.TokensUsed = cost
+	}
+	return result, nil
+}
+
+// SetDefaultLocale declares the language the website's content is written
+// in
+func (e *Editor) SetDefaultLocale(userID, websiteID uuid.UUID, locale string) (int, error) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	origin := revision.ByUser(userID, "Set the default locale to "+locale)
+	version, _, err := e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+		return setDefaultLocale(content, locale)
+	})
+	return version, err
+}
+
+// DeleteTranslation removes the website's translation into a locale
+func (e *Editor) DeleteTranslation(userID, websiteID uuid.UUID, locale string) (int, error) {
+	origin := revision.ByUser(userID, "Deleted the "+locale+" translation")
+	version, _, err := e.editContent(userID, websiteID, origin, func(content *models.SiteContent) error {
+		if _, ok := content.Translations[locale]; !ok {
+			return fmt.Errorf("%w: no translation into %q", ErrTranslationNotFound, locale)
+		}
+		delete(content.Translations, locale)
+		return nil
+	})
+	return version, err
+}
+
+// normalizeLocales lower-cases the locales and drops repeats
+func normalizeLocales(locales []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, locale := range locales {
+		locale = strings.ToLower(strings.TrimSpace(locale))
+		if !seen[locale] {
+			seen[locale] = true
+			normalized = append(normalized, locale)
+		}
+	}
+	return normalized
+}
+
+// checkLocales validates the locales of new translations: each is a
+// language code other than the default locale whose path no page uses
+func checkLocales(content *models.SiteContent, locales []string) error {
+	added := 0
+	for _, locale := range locales {
+		if !models.ValidLocale(locale) {
+			return fmt.Errorf("%w: locale %q must be a language code such as en or pt-br", ErrInvalidEdit, locale)
+		}
+		if locale == content.DefaultLocale() {
+			return fmt.Errorf("%w: the content is already in %q", ErrInvalidEdit, locale)
+		}
+		if content.HasPagesAt(locale) {
+			return fmt.Errorf("%w: the page %q uses the path of locale %q", ErrInvalidEdit, locale, locale)
+		}
+		if _, ok := content.Translations[locale]; !ok {
+			added++
+		}
+	}
+	if len(content.Translations)+added > maxTranslations {
+		return fmt.Errorf("%w: a site has at most %d translations", ErrInvalidEdit, maxTranslations)
+	}
+	return nil
+}
+
+// setDefaultLocale changes the locale of the content, which cannot be one
+// it is translated into
+func setDefaultLocale(content *models.SiteContent, locale string) error {
+	if !models.ValidLocale(locale) {
+		return fmt.Errorf("%w: locale %q must be a language code such as en or pt-br", ErrInvalidEdit, locale)
+	}
+	if _, ok := content.Translations[locale]; ok {
+		return fmt.Errorf("%w: the site is translated into %q; delete the translation first", ErrInvalidEdit, locale)
+	}
+	content.Locale = locale
+	return nil
+}
+
+// translate has the model translate the site's content from one locale to
+// another
+func (e *Editor) translate(ctx context.Context, source *models.Translation, from, to string) (*models.Translation, error) {
+	current, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []ai.Message{
+		{
+			Role: "system",
+			Content: "You translate website content. Reply with only the JSON object of the translated content, " +
+				"with the same keys, the same sections in the same order and the same page slugs. Translate the text " +
+				"visitors read, and keep URLs, email addresses, phone numbers, slugs and section types unchanged.",
+		},
+		{
+			Role:    "user",
+			Content: fmt.Sprintf("Translate from %s to %s:\n%s", from, to, current),
+		},
+	}
+
+	resp, err := e.kimi.ChatCompletion(ctx, messages, translateMaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrGenerationFailed)
+	}
+
+	var translated models.Translation
+	if err := json.Unmarshal([]byte(website.ExtractJSON(resp.Choices[0].Message.Content)), &translated); err != nil {
+		return nil, fmt.Errorf("%w: reply is not website content", ErrGenerationFailed)
+	}
+	if err := checkTranslation(source, &translated); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	return &translated, nil
+}
+
+// checkTranslation verifies a translation has the structure of its source:
+// the same pages and, on each, sections of the same types and shapes
+func checkTranslation(source, translated *models.Translation) error {
+	if strings.TrimSpace(translated.Title) == "" {
+		translated.Title = source.Title
+	}
+	if err := sameSections(pageName(""), source.Sections, translated.Sections); err != nil {
+		return err
+	}
+	if len(translated.Pages) != len(source.Pages) {
+		return fmt.Errorf("the translation has %d pages instead of %d", len(translated.Pages), len(source.Pages))
+	}
+	for i, page := range source.Pages {
+		if translated.Pages[i].Slug != page.Slug {
+			return fmt.Errorf("page %d has slug %q instead of %q", i, translated.Pages[i].Slug, page.Slug)
+		}
+		if err := sameSections(pageName(page.Slug), page.Sections, translated.Pages[i].Sections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameSections(where string, source, translated []models.Section) error {
+	if len(translated) != len(source) {
+		return fmt.Errorf("%s has %d sections instead of %d", where, len(translated), len(source))
+	}
+	for i, s := range source {
+		if translated[i].Type != s.Type {
+			return fmt.Errorf("%s: section %d is %q instead of %q", where, i, translated[i].Type, s.Type)
+		}
+		// Sections the source already breaks are left out when rendered either way
+		if validateSection(s) == nil {
+			if err := validateSection(translated[i]); err != nil {
+				return fmt.Errorf("%s: section %d: %v", where, i, err)
+			}
+		}
+		if err := sameShape(s.Content, translated[i].Content); err != nil {
+			return fmt.Errorf("%s: section %d: %v", where, i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyTranslation deep-copies a translation through JSON, as the model
+// would reply
+func copyTranslation(t *testing.T, source *models.Translation) *models.Translation {
+	t.Helper()
+	data, err := json.Marshal(source)
+	require.NoError(t, err)
+	var copied models.Translation
+	require.NoError(t, json.Unmarshal(data, &copied))
+	return &copied
+}
+
+func TestCheckLocales(t *testing.T) {
+	content := testContent(t)
+	content.Locale = "id"
+	require.NoError(t, addPage(content, PageFields{Slug: "menu", Title: "Menu"}))
+
+	assert.NoError(t, checkLocales(content, normalizeLocales([]string{" EN ", "en", "pt-br"})))
+	assert.Equal(t, []string{"en", "pt-br"}, normalizeLocales([]string{" EN ", "en", "pt-br"}))
+
+	for _, locale := range []string{"english", "id", "menu", "en_US"} {
+		err := checkLocales(content, []string{locale})
+		assert.True(t, errors.Is(err, ErrInvalidEdit), locale)
+	}
+
+	content.Translations = map[string]*models.Translation{}
+	for _, locale := range []string{"de", "es", "fr", "ja", "ko"} {
+		content.Translations[locale] = &models.Translation{}
+	}
+	assert.NoError(t, checkLocales(content, []string{"de"}), "replacing a translation adds no locale")
+	assert.Contains(t, checkLocales(content, []string{"en"}).Error(), "at most 5 translations")
+}
+
+func TestCheckTranslation(t *testing.T) {
+	content := testContent(t)
+	require.NoError(t, addPage(content, PageFields{Slug: "about", Title: "About us"}))
+	source := content.Translation()
+
+	translated := copyTranslation(t, source)
+	translated.Title = ""
+	translated.Sections[0].Content["title"] = "Selamat datang"
+	require.NoError(t, checkTranslation(source, translated))
+	assert.Equal(t, "Sweet Bites", translated.Title, "a missing title keeps the original")
+
+	tests := []struct {
+		name   string
+		change func(*models.Translation)
+		want   string
+	}{
+		{"dropped section", func(tr *models.Translation) { tr.Sections = tr.Sections[1:] }, "the home page has 2 sections instead of 3"},
+		{"changed type", func(tr *models.Translation) { tr.Sections[1].Type = "faq" }, `section 1 is "faq" instead of "about"`},
+		{"changed shape", func(tr *models.Translation) { tr.Sections[0].Content["subtitle"] = []interface{}{} }, "content.subtitle must be text"},
+		{"renamed page", func(tr *models.Translation) { tr.Pages[0].Slug = "tentang" }, `page 0 has slug "tentang" instead of "about"`},
+		{"dropped page", func(tr *models.Translation) { tr.Pages = nil }, "0 pages instead of 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translated := copyTranslation(t, source)
+			tt.change(translated)
+			err := checkTranslation(source, translated)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestLocaleEdits(t *testing.T) {
+	content := testContent(t)
+	assert.Equal(t, "en", content.DefaultLocale())
+	require.NoError(t, setDefaultLocale(content, "id"))
+	assert.Equal(t, []string{"id"}, content.Locales())
+
+	require.NoError(t, addPage(content, PageFields{Slug: "blog", Title: "Blog"}))
+	require.NoError(t, addPage(content, PageFields{Slug: "blog/opening-day", Title: "Opening day"}))
+	content.Translations = map[string]*models.Translation{"en": copyTranslation(t, content.Translation())}
+	assert.True(t, errors.Is(setDefaultLocale(content, "en"), ErrInvalidEdit))
+	assert.True(t, errors.Is(addPage(content, PageFields{Slug: "en", Title: "English"}), ErrInvalidEdit))
+
+	slug := "news"
+	require.NoError(t, updatePage(content, "blog", PageUpdate{Slug: &slug}))
+	require.NoError(t, deletePage(content, "news/opening-day"))
+	translated := content.Translations["en"].Pages
+	require.Len(t, translated, 1, "translated pages follow the pages they translate")
+	assert.Equal(t, "news", translated[0].Slug)
+}
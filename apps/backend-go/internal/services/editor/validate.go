@@ -0,0 +1,107 @@
+package editor
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/section"
+)
+
+const (
+	// maxSections bounds the sections of one page
+	maxSections = 30
+	// maxTextLength bounds a single text value, in characters
+	maxTextLength = 5000
+	// maxDepth bounds how deeply section content may nest
+	maxDepth = 4
+)
+
+var contentKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,40}$`)
+
+// validateSection checks a section's type and content. Content of a
+// registered type must match its schema; sections of other types, which
+// older sites may have, only get the general checks.
+func validateSection(s models.Section) error {
+	if !section.ValidName(s.Type) {
+		return fmt.Errorf("invalid section type %q", s.Type)
+	}
+	if err := validateValue("content", s.Content, 0); err != nil {
+		return err
+	}
+	if sectionType, ok := section.Builtin.Lookup(s.Type); ok {
+		return sectionType.Validate(s.Content)
+	}
+	return nil
+}
+
+// validateValue checks content values: text of bounded length, numbers,
+// booleans, and lists or objects of those up to maxDepth
+func validateValue(path string, value interface{}, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%s is nested too deeply", path)
+	}
+
+	switch v := value.(type) {
+	case nil, bool, float64, int:
+		return nil
+	case string:
+		if utf8.RuneCountInString(v) > maxTextLength {
+			return fmt.Errorf("%s must be at most %d characters", path, maxTextLength)
+		}
+		return nil
+	case []interface{}:
+		for i, item := range v {
+			if err := validateValue(fmt.Sprintf("%s[%d]", path, i), item, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		for key, item := range v {
+			if !contentKeyPattern.MatchString(key) {
+				return fmt.Errorf("invalid key %q in %s", key, path)
+			}
+			if err := validateValue(path+"."+key, item, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s has an unsupported value", path)
+	}
+}
+
+// sameShape checks that rewritten keeps every key of original with the same
+// kind of value, so a rewrite cannot change what the section renders
+func sameShape(original, rewritten map[string]interface{}) error {
+	for key, value := range original {
+		replacement, ok := rewritten[key]
+		if !ok {
+			return fmt.Errorf("content.%s is missing", key)
+		}
+		if kindOf(value) != kindOf(replacement) {
+			return fmt.Errorf("content.%s must be a %s", key, kindOf(value))
+		}
+	}
+	return nil
+}
+
+// kindOf names the JSON kind of a decoded value
+func kindOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, int:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "null"
+	}
+}
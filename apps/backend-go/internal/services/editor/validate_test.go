@@ -0,0 +1,28 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateValue(t *testing.T) {
+	assert.NoError(t, validateValue("content", map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"name": "Bread", "price": 3.5, "vegan": true}},
+	}, 0))
+	assert.Error(t, validateValue("content", map[string]interface{}{"bad key": "x"}, 0))
+
+	nested := interface{}("deep")
+	for i := 0; i <= maxDepth; i++ {
+		nested = []interface{}{nested}
+	}
+	assert.Error(t, validateValue("content", nested, 0))
+}
+
+func TestSameShape(t *testing.T) {
+	original := map[string]interface{}{"title": "Welcome", "items": []interface{}{"Bread"}}
+
+	assert.NoError(t, sameShape(original, map[string]interface{}{"title": "Hello", "items": []interface{}{"Cake", "Pie"}, "subtitle": "New"}))
+	assert.EqualError(t, sameShape(original, map[string]interface{}{"title": "Hello"}), "content.items is missing")
+	assert.EqualError(t, sameShape(original, map[string]interface{}{"title": "Hello", "items": "Cake"}), "content.items must be a list")
+}
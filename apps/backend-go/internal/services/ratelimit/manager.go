@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend-go/internal/config"
+	"backend-go/internal/database"
+	"backend-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Route groups with their own quotas
+const (
+	GroupDefault = "default"
+	GroupAuth    = "auth"
+	GroupAI      = "ai"
+	GroupWSChat  = "ws_chat"
+)
+
+// TierAnonymous is the tier used for requests without an authenticated user
+const TierAnonymous = "anonymous"
+
+// TierResolver looks up the subscription tier of a user
+type TierResolver interface {
+	Tier(userID uuid.UUID) string
+}
+
+// Manager applies per-group, per-tier quotas on top of a Store
+type Manager struct {
+	store Store
+	cfg   *config.RateLimitConfig
+	tiers TierResolver
+}
+
+// NewManager creates a new rate limit manager
+func NewManager(store Store, cfg *config.RateLimitConfig, tiers TierResolver) *Manager {
+	return &Manager{
+		store: store,
+		cfg:   cfg,
+		tiers: tiers,
+	}
+}
+
+// Window returns the configured window length
+func (m *Manager) Window() time.Duration {
+	return m.cfg.Window
+}
+
+// TierFor returns the subscription tier of a user, or TierAnonymous
+func (m *Manager) TierFor(userID *uuid.UUID) string {
+	if userID == nil || m.tiers == nil {
+		return TierAnonymous
+	}
+	return m.tiers.Tier(*userID)
+}
+
+// Quota returns the request limit for a group and tier. Groups without an
+// entry for the tier fall back to the default group. ok is false when no
+// quota applies and the request is unlimited.
+func (m *Manager) Quota(group, tier string) (limit int, ok bool) {
+	if !m.cfg.Enabled {
+		return 0, false
+	}
+	if limit, ok := m.cfg.Groups[group][tier]; ok {
+		return limit, true
+	}
+	if limit, ok := m.cfg.Groups[GroupDefault][tier]; ok {
+		return limit, true
+	}
+	return 0, false
+}
+
+// Check records a hit for subject in group. ok is false when no quota applies.
+// Store errors fail open so an unavailable backend never blocks traffic.
+func (m *Manager) Check(ctx context.Context, group, tier, subject string) (result Result, ok bool) {
+	limit, ok := m.Quota(group, tier)
+	if !ok {
+		return Result{Allowed: true}, false
+	}
+
+	result, err := m.store.Allow(ctx, group+":"+subject, limit, m.cfg.Window)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group).Warn("Rate limit check failed, allowing request")
+		return Result{Allowed: true}, false
+	}
+
+	return result, true
+}
+
+// DBTierResolver resolves tiers from the users table with a short-lived cache
+type DBTierResolver struct {
+	db    *database.Database
+	ttl   time.Duration
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedTier
+}
+
+const maxCachedTiers = 10000
+
+type cachedTier struct {
+	tier      string
+	expiresAt time.Time
+}
+
+// NewDBTierResolver creates a tier resolver backed by the database
+func NewDBTierResolver(db *database.Database, ttl time.Duration) *DBTierResolver {
+	return &DBTierResolver{
+		db:    db,
+		ttl:   ttl,
+		cache: make(map[uuid.UUID]cachedTier),
+	}
+}
+
+// Tier returns the subscription tier of a user, defaulting to "free"
+func (r *DBTierResolver) Tier(userID uuid.UUID) string {
+	r.mu.Lock()
+	if cached, ok := r.cache[userID]; ok && time.Now().Before(cached.expiresAt) {
+		r.mu.Unlock()
+		return cached.tier
+	}
+	r.mu.Unlock()
+
+	tier := "free"
+	var user models.User
+	if err := r.db.DB.Select("subscription_tier").First(&user, "id = ?", userID).Error; err == nil && user.SubscriptionTier != "" {
+		tier = user.SubscriptionTier
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if len(r.cache) >= maxCachedTiers {
+		for id, cached := range r.cache {
+			if now.After(cached.expiresAt) {
+				delete(r.cache, id)
+			}
+		}
+	}
+	r.cache[userID] = cachedTier{tier: tier, expiresAt: now.Add(r.ttl)}
+	r.mu.Unlock()
+
+	return tier
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticTiers string
+
+func (s staticTiers) Tier(uuid.UUID) string { return string(s) }
+
+func testConfig() *config.RateLimitConfig {
+	return &config.RateLimitConfig{
+		Enabled: true,
+		Window:  time.Minute,
+		Groups: map[string]map[string]int{
+			GroupDefault: {TierAnonymous: 5, "free": 10},
+			GroupAI:      {"free": 2, "pro": 20},
+		},
+	}
+}
+
+func TestMemoryStore_Allow(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := store.Allow(ctx, "key", 3, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := store.Allow(ctx, "key", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.True(t, result.ResetAfter > 0 && result.ResetAfter <= time.Minute)
+
+	// Other keys are independent
+	result, err = store.Allow(ctx, "other", 3, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStore_WindowSlides(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_, err := store.Allow(ctx, "key", 1, 50*time.Millisecond)
+	require.NoError(t, err)
+
+	result, _ := store.Allow(ctx, "key", 1, 50*time.Millisecond)
+	assert.False(t, result.Allowed)
+
+	time.Sleep(60 * time.Millisecond)
+
+	result, _ = store.Allow(ctx, "key", 1, 50*time.Millisecond)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStore_EvictsIdleKeys(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	for _, key := range []string{"ip:1", "ip:2", "ip:3"} {
+		_, err := store.Allow(ctx, key, 1, 50*time.Millisecond)
+		require.NoError(t, err)
+	}
+	assert.Len(t, store.hits, 3)
+
+	time.Sleep(60 * time.Millisecond)
+
+	_, err := store.Allow(ctx, "ip:4", 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Len(t, store.hits, 1, "keys idle for a whole window are evicted")
+}
+
+func TestManager_Quota(t *testing.T) {
+	manager := NewManager(NewMemoryStore(), testConfig(), staticTiers("free"))
+
+	limit, ok := manager.Quota(GroupAI, "pro")
+	assert.True(t, ok)
+	assert.Equal(t, 20, limit)
+
+	// Falls back to the default group for tiers the group does not list
+	limit, ok = manager.Quota(GroupAI, TierAnonymous)
+	assert.True(t, ok)
+	assert.Equal(t, 5, limit)
+
+	// No quota anywhere means unlimited
+	_, ok = manager.Quota(GroupAI, "business")
+	assert.False(t, ok)
+}
+
+func TestManager_Check(t *testing.T) {
+	manager := NewManager(NewMemoryStore(), testConfig(), staticTiers("free"))
+	userID := uuid.New()
+	tier := manager.TierFor(&userID)
+	assert.Equal(t, "free", tier)
+	assert.Equal(t, TierAnonymous, manager.TierFor(nil))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		result, limited := manager.Check(ctx, GroupAI, tier, "user:"+userID.String())
+		assert.True(t, limited)
+		assert.True(t, result.Allowed)
+	}
+
+	result, limited := manager.Check(ctx, GroupAI, tier, "user:"+userID.String())
+	assert.True(t, limited)
+	assert.False(t, result.Allowed)
+
+	// Groups are counted separately
+	result, _ = manager.Check(ctx, GroupDefault, tier, "user:"+userID.String())
+	assert.True(t, result.Allowed)
+}
+
+func TestManager_Disabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Enabled = false
+	manager := NewManager(NewMemoryStore(), cfg, nil)
+
+	for i := 0; i < 10; i++ {
+		result, limited := manager.Check(context.Background(), GroupAI, "free", "ip:127.0.0.1")
+		assert.False(t, limited)
+		assert.True(t, result.Allowed)
+	}
+}
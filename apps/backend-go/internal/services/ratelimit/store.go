@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Result describes the outcome of a single rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+}
+
+// Store records hits for a key within a sliding window
+type Store interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// slidingWindowScript implements a sliding window log on a sorted set.
+// KEYS[1] = key, ARGV[1] = now (ms), ARGV[2] = window (ms), ARGV[3] = limit, ARGV[4] = member
+// Returns {allowed, count, oldest}.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local oldest = now
+local first = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if first[2] then
+	oldest = tonumber(first[2])
+end
+return {allowed, count, oldest}
+`)
+
+// RedisStore keeps rate limit state in Redis so limits hold across replicas
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: "ratelimit:",
+	}
+}
+
+// Allow records a hit for key and reports whether it fits within limit
+func (s *RedisStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + uuid.NewString()
+
+	values, err := slidingWindowScript.Run(ctx, s.client, []string{s.prefix + key},
+		now, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	return buildResult(values[0] == 1, limit, int(values[1]), time.UnixMilli(values[2]), window), nil
+}
+
+// MemoryStore keeps rate limit state in process memory.
+// It is used when Redis is unavailable and in tests.
+type MemoryStore struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+	// maxWindow is the longest window checked so far; keys without a hit in
+	// it are evicted once per maxWindow
+	maxWindow time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		hits: make(map[string][]time.Time),
+	}
+}
+
+// Allow records a hit for key and reports whether it fits within limit
+func (s *MemoryStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-window)
+	if window > s.maxWindow {
+		s.maxWindow = window
+	}
+	if now.Sub(s.lastSweep) >= s.maxWindow {
+		s.sweep(now)
+	}
+
+	hits := s.hits[key]
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+
+	allowed := len(hits) < limit
+	if allowed {
+		hits = append(hits, now)
+	}
+
+	if len(hits) == 0 {
+		delete(s.hits, key)
+	} else {
+		s.hits[key] = hits
+	}
+
+	oldest := now
+	if len(hits) > 0 {
+		oldest = hits[0]
+	}
+
+	return buildResult(allowed, limit, len(hits), oldest, window), nil
+}
+
+// sweep evicts the keys whose latest hit is older than the longest window,
+// so callers that never return do not pile up
+func (s *MemoryStore) sweep(now time.Time) {
+	cutoff := now.Add(-s.maxWindow)
+	for key, hits := range s.hits {
+		if !hits[len(hits)-1].After(cutoff) {
+			delete(s.hits, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func buildResult(allowed bool, limit, count int, oldest time.Time, window time.Duration) Result {
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	resetAfter := time.Until(oldest.Add(window))
+	if resetAfter < 0 {
+		resetAfter = 0
+	}
+
+	return Result{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  remaining,
+		ResetAfter: resetAfter,
+	}
+}
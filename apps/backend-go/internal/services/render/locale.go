@@ -0,0 +1,62 @@
+package render
+
+import "strings"
+
+// homeLabels name the home page in the navigation menu, by language
+var homeLabels = map[string]string{
+	"de": "Startseite",
+	"en": "Home",
+	"es": "Inicio",
+	"fr": "Accueil",
+	"id": "Beranda",
+	"it": "Home",
+	"ja": "ホーム",
+	"ko": "홈",
+	"ms": "Laman Utama",
+	"nl": "Home",
+	"pt": "Início",
+	"th": "หน้าแรก",
+	"vi": "Trang chủ",
+	"zh": "首页",
+}
+
+// languageNames name each language in itself for the language switcher
+var languageNames = map[string]string{
+	"de": "Deutsch",
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+	"id": "Bahasa Indonesia",
+	"it": "Italiano",
+	"ja": "日本語",
+	"ko": "한국어",
+	"ms": "Bahasa Melayu",
+	"nl": "Nederlands",
+	"pt": "Português",
+	"th": "ไทย",
+	"vi": "Tiếng Việt",
+	"zh": "中文",
+}
+
+// language is the language of a locale, e.g. pt for pt-br
+func language(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return language
+}
+
+// homeLabel names the home page in the locale's language, in English for
+// languages without a label
+func homeLabel(locale string) string {
+	if label, ok := homeLabels[language(locale)]; ok {
+		return label
+	}
+	return homeLabels["en"]
+}
+
+// languageName names a locale in its own language, or by its code
+func languageName(locale string) string {
+	if name, ok := languageNames[language(locale)]; ok {
+		return name
+	}
+	return strings.ToUpper(locale)
+}
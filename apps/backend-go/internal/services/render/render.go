@@ -0,0 +1,515 @@
+// Package render turns a website's content into its static HTML pages. The
+// preview and deployment render with the same templates, so a deployed page
+// matches its preview apart from preview-only markers: placeholders for
+// sections that cannot be shown, which the deployed page leaves out, and a
+// robots noindex tag.
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/design"
+	"backend-go/internal/services/section"
+	"backend-go/internal/services/seo"
+
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// sectionPrefix names the template of each section type, e.g. section/hero
+const sectionPrefix = "section/"
+
+// ErrPageNotFound is returned when previewing a page the site does not have
+var ErrPageNotFound = errors.New("page not found")
+
+// Renderer renders websites with the embedded layout and the template of
+// each registered section type
+type Renderer struct {
+	templates *template.Template
+	sections  *section.Registry
+}
+
+// funcs are available to every template
+var funcs = template.FuncMap{
+	"safeURL":  safeURL,
+	"richText": richText,
+	"join":     strings.Join,
+}
+
+// New parses the layout and the templates of the built-in section types
+func New() (*Renderer, error) {
+	return newRenderer(section.Builtin)
+}
+
+func newRenderer(sections *section.Registry) (*Renderer, error) {
+	templates, err := template.New("").Funcs(funcs).ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	for _, t := range sections.Types() {
+		if _, err := templates.New(sectionPrefix + t.Name).Parse(strings.TrimRight(t.Template, "\n")); err != nil {
+			return nil, fmt.Errorf("failed to parse template of section type %s: %w", t.Name, err)
+		}
+	}
+	return &Renderer{templates: templates, sections: sections}, nil
+}
+
+// Site is a rendered website: its home page and every other page, plus
+// the sitemap and robots.txt that tell search engines about them, which
+// need the site's base URL
+type Site struct {
+	Pages   []*Page
+	Sitemap []byte
+	Robots  []byte
+}
+
+// Page is a rendered website page
+type Page struct {
+	// Path is where the page is deployed, e.g. index.html or
+	// about/index.html
+	Path string
+	// Locale and Slug identify the page in the content; the home page has
+	// the empty slug
+	Locale string
+	Slug   string
+	HTML   []byte
+	// Policy is the page's Content-Security-Policy, also set in a meta tag
+	// so the page is protected wherever it is hosted
+	Policy string
+}
+
+// layout is the data of the layout template
+type layout struct {
+	Lang string
+	// Title is the document title; Heading names the page within the site
+	Title       string
+	Heading     string
+	SiteName    string
+	Description string
+	Keywords    []string
+	// Canonical and Image are absolute URLs, empty when unknown
+	Canonical string
+	Image     string
+	// StructuredData is the site's schema.org data, on the home page only
+	StructuredData map[string]interface{}
+	// NoIndex keeps previews out of search engines
+	NoIndex bool
+	Year    int
+	Policy  string
+	FontURL string
+	Styles  template.CSS
+	// Home is the relative link to the home page
+	Home       string
+	Nav        []navLink
+	Languages  []languageLink
+	Alternates []alternate
+	Sections   []template.HTML
+}
+
+// navLink is an entry of the navigation menu
+type navLink struct {
+	Label   string
+	URL     string
+	Current bool
+}
+
+// languageLink is an entry of the language switcher
+type languageLink struct {
+	Lang    string
+	Label   string
+	URL     string
+	Current bool
+}
+
+// alternate is the absolute URL of the page in another locale, for an
+// hreflang link
+type alternate struct {
+	Lang string
+	URL  string
+}
+
+// SectionTypes lists the section types that have a template
+func (r *Renderer) SectionTypes() []string {
+	var types []string
+	for _, t := range r.templates.Templates() {
+		if strings.HasPrefix(t.Name(), sectionPrefix) {
+			types = append(types, strings.TrimPrefix(t.Name(), sectionPrefix))
+		}
+	}
+	return types
+}
+
+// placeholder is the data of the placeholder template
+type placeholder struct {
+	Type   string
+	Reason string
+}
+
+// Render renders the website's pages as they are deployed. Every value from
+// the content is escaped for where it appears; links are limited to safe
+// schemes and rich text to allowlisted markup. Sections of unknown types,
+// or whose content does not fit their schema, are left out rather than
+// failing the whole page, as are pages with invalid or duplicate slugs.
+// baseURL is where the site is published, e.g. https://bakery.sitespark.id;
+// canonical links, share images and the sitemap are absolute to it. The
+// default locale's pages are at the root and each translation's below its
+// locale, e.g. en/about/index.html.
+func (r *Renderer) Render(site *models.Website, baseURL string) (*Site, error) {
+	locales, err := r.newWebsites(site, baseURL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &Site{}
+	var paths []string
+	for _, w := range locales {
+		pages := w.pages()
+		for _, page := range pages {
+			html, err := w.render(page, pages)
+			if err != nil {
+				return nil, err
+			}
+			rendered.Pages = append(rendered.Pages, html)
+			paths = append(paths, w.path(w.locale, page))
+		}
+	}
+	if baseURL != "" {
+		rendered.Sitemap = seo.Sitemap(baseURL, paths, site.UpdatedAt)
+		rendered.Robots = seo.Robots(baseURL)
+	}
+	return rendered, nil
+}
+
+// RenderPreview renders one page of the website like Render, found by its
+// path without index.html, e.g. about or en/about and the empty path for
+// the home page. It shows a visible placeholder for each section that would
+// be left out, so the owner can see what is missing. Previews ask search
+// engines not to index them.
+func (r *Renderer) RenderPreview(site *models.Website, baseURL, path string) (*Page, error) {
+	locales, err := r.newWebsites(site, baseURL, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range locales {
+		pages := w.pages()
+		for _, page := range pages {
+			if w.path(w.locale, page) == path {
+				return w.render(page, pages)
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrPageNotFound, path)
+}
+
+// website holds what every page of a site in one locale shares while it is
+// rendered
+type website struct {
+	*Renderer
+	// site carries the locale's title and description
+	site    *models.Website
+	content *models.SiteContent
+	// locale is the language of the pages, and locales every language
+	// the site is in, the default first
+	locale  string
+	locales []string
+	baseURL string
+	preview bool
+	name    string
+	styles  string
+	fontURL string
+}
+
+// newWebsites prepares the site in each of its locales, the default first
+func (r *Renderer) newWebsites(site *models.Website, baseURL string, preview bool) ([]*website, error) {
+	content, err := models.ParseContent(site.GeneratedContent)
+	if err != nil {
+		logrus.WithError(err).WithField("website_id", site.ID).Warn("Rendering website without its content")
+		content = &models.SiteContent{}
+	}
+
+	tokens, err := models.ParseDesignTokens(site.DesignTokens)
+	if err != nil {
+		logrus.WithError(err).WithField("website_id", site.ID).Warn("Rendering website with the default design")
+		tokens = nil
+	}
+	stylesheet := design.Compile(tokens)
+
+	// The tokens' custom properties come first; the template's rules use them
+	var styles bytes.Buffer
+	styles.WriteString(stylesheet.Variables)
+	if err := r.templates.ExecuteTemplate(&styles, "styles", nil); err != nil {
+		return nil, err
+	}
+
+	locales := content.Locales()
+	websites := make([]*website, 0, len(locales))
+	for _, locale := range locales {
+		localized := site
+		if locale != locales[0] {
+			// Translations name and describe the site in their own words
+			translated := content.Localized(locale)
+			copied := *site
+			copied.Title, copied.Description = translated.Title, translated.Description
+			if copied.Title == "" {
+				copied.Title = site.Title
+			}
+			localized = &copied
+		}
+		websites = append(websites, &website{
+			Renderer: r,
+			site:     localized,
+			content:  content.Localized(locale),
+			locale:   locale,
+			locales:  locales,
+			baseURL:  baseURL,
+			preview:  preview,
+			name:     seo.SiteName(localized),
+			styles:   styles.String(),
+			fontURL:  stylesheet.FontURL,
+		})
+	}
+	return websites, nil
+}
+
+// pages lists the home page, with the empty slug, followed by every other
+// page with a valid slug
+func (w *website) pages() []*models.Page {
+	home := &models.Page{Title: w.name, Sections: w.content.Sections, SEO: w.content.SEO}
+	pages := []*models.Page{home}
+
+	seen := map[string]bool{}
+	for i := range w.content.Pages {
+		page := &w.content.Pages[i]
+		if !models.ValidSlug(page.Slug) || seen[page.Slug] {
+			logrus.WithFields(logrus.Fields{
+				"website_id": w.site.ID,
+				"locale":     w.locale,
+				"slug":       page.Slug,
+			}).Warn("Skipping page with an invalid or duplicate slug")
+			continue
+		}
+		seen[page.Slug] = true
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// path is where a page is in the locale relative to the site's root,
+// without a trailing slash: the default locale's pages are at the root and
+// the others below their locale
+func (w *website) path(locale string, page *models.Page) string {
+	if locale == w.locales[0] {
+		return page.Slug
+	}
+	if page.Slug == "" {
+		return locale
+	}
+	return locale + "/" + page.Slug
+}
+
+// up is the relative link from a page to the directory n levels above it
+func up(n int) string {
+	if n == 0 {
+		return "./"
+	}
+	return strings.Repeat("../", n)
+}
+
+// relativeRoot is the relative link from a page to its locale's home page
+func relativeRoot(page *models.Page) string {
+	if page.Slug == "" {
+		return up(0)
+	}
+	return up(page.Depth())
+}
+
+// nav builds the navigation menu of a page: the home page and the other
+// top-level pages. Sites with a single page have no menu.
+func nav(current *models.Page, pages []*models.Page, home string) []navLink {
+	if len(pages) < 2 {
+		return nil
+	}
+	root := relativeRoot(current)
+
+	var links []navLink
+	for _, page := range pages {
+		if page.Slug != "" && page.Depth() > 1 {
+			continue
+		}
+		link := navLink{Label: page.Label(), URL: root, Current: page.Slug == current.Slug}
+		if page.Slug == "" {
+			link.Label = home
+		} else {
+			link.URL += page.Slug + "/"
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// languages builds the language switcher of a page and its hreflang
+// alternates: the same page in every locale of the site. Sites in a single
+// locale have neither; alternates also need the base URL.
+func (w *website) languages(page *models.Page) ([]languageLink, []alternate) {
+	if len(w.locales) < 2 {
+		return nil, nil
+	}
+	current := w.path(w.locale, page)
+	siteRoot := up(0)
+	if current != "" {
+		siteRoot = up(strings.Count(current, "/") + 1)
+	}
+
+	var links []languageLink
+	var alternates []alternate
+	for _, locale := range w.locales {
+		path := w.path(locale, page)
+		link := languageLink{Lang: locale, Label: languageName(locale), URL: siteRoot, Current: locale == w.locale}
+		if path != "" {
+			link.URL += path + "/"
+		}
+		links = append(links, link)
+		if w.baseURL != "" {
+			alternates = append(alternates, alternate{Lang: locale, URL: seo.PageURL(w.baseURL, path)})
+		}
+	}
+	if w.baseURL != "" {
+		alternates = append(alternates, alternate{Lang: "x-default", URL: seo.PageURL(w.baseURL, page.Slug)})
+	}
+	return links, alternates
+}
+
+// render renders one of the site's pages
+func (w *website) render(page *models.Page, pages []*models.Page) (*Page, error) {
+	localeURL := seo.PageURL(w.baseURL, w.path(w.locale, &models.Page{}))
+	meta := seo.PageMeta(w.site, w.content, page, localeURL)
+	data := layout{
+		Lang:        w.locale,
+		Title:       meta.Title,
+		Heading:     page.Title,
+		SiteName:    w.name,
+		Description: meta.Description,
+		Keywords:    meta.Keywords,
+		Canonical:   meta.Canonical,
+		Image:       meta.Image,
+		NoIndex:     w.preview,
+		Home:        relativeRoot(page),
+		Nav:         nav(page, pages, homeLabel(w.locale)),
+	}
+	data.Languages, data.Alternates = w.languages(page)
+	if page.Slug == "" {
+		data.StructuredData = seo.StructuredData(w.site, w.content, localeURL)
+	}
+	if !w.site.UpdatedAt.IsZero() {
+		data.Year = w.site.UpdatedAt.Year()
+	}
+
+	for i, s := range page.Sections {
+		html, err := w.section(s)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"website_id": w.site.ID,
+				"locale":     w.locale,
+				"page":       page.Slug,
+				"section":    i,
+				"type":       s.Type,
+			}).Warn("Skipping section that failed to render")
+			if !w.preview {
+				continue
+			}
+			if html, err = w.placeholder(s.Type, err); err != nil {
+				return nil, err
+			}
+		}
+		data.Sections = append(data.Sections, html)
+	}
+
+	data.FontURL = w.fontURL
+	data.Styles = template.CSS(w.styles)
+	data.Policy = policy(w.styles, w.fontURL != "")
+
+	var buf bytes.Buffer
+	if err := w.templates.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return nil, err
+	}
+	path := "index.html"
+	if current := w.path(w.locale, page); current != "" {
+		path = current + "/index.html"
+	}
+	return &Page{Path: path, Locale: w.locale, Slug: page.Slug, HTML: buf.Bytes(), Policy: data.Policy}, nil
+}
+
+// policy is a strict Content-Security-Policy for a page: no scripts, frames
+// or plugins, and only the page's own stylesheet, allowed by its hash, plus
+// the font stylesheet when the page loads hosted fonts
+func policy(styles string, hostedFonts bool) string {
+	sum := sha256.Sum256([]byte(styles))
+	styleSrc := "style-src 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	if hostedFonts {
+		styleSrc += " " + design.FontHost
+	}
+	return strings.Join([]string{
+		"default-src 'none'",
+		styleSrc,
+		"img-src https: data:",
+		"font-src https: data:",
+		"base-uri 'none'",
+		"form-action 'none'",
+	}, "; ")
+}
+
+// safeURL checks a link from the content for a template
+func safeURL(value interface{}) template.URL {
+	u, _ := value.(string)
+	return SafeURL(u)
+}
+
+// richText sanitizes rich text for a template
+func richText(value interface{}) template.HTML {
+	text, _ := value.(string)
+	return SanitizeHTML(text)
+}
+
+// section renders one section after checking its content against its
+// type's schema
+func (r *Renderer) section(s models.Section) (template.HTML, error) {
+	if err := r.sections.Validate(s.Type, s.Content); err != nil {
+		return "", err
+	}
+	t := r.templates.Lookup(sectionPrefix + s.Type)
+	if t == nil {
+		return "", fmt.Errorf("%w %q", section.ErrUnknownType, s.Type)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s.Content); err != nil {
+		return "", err
+	}
+	// The output of an html/template is already escaped
+	return template.HTML(buf.String()), nil
+}
+
+// placeholder renders the preview's stand-in for a section that cannot be
+// shown
+func (r *Renderer) placeholder(sectionType string, cause error) (template.HTML, error) {
+	reason := fmt.Sprintf("Its content does not fit the section type: %v.", cause)
+	if errors.Is(cause, section.ErrUnknownType) {
+		reason = "This section type is not supported yet, so it is left out of the published site."
+	}
+
+	var buf bytes.Buffer
+	if err := r.templates.ExecuteTemplate(&buf, "placeholder", placeholder{Type: sectionType, Reason: reason}); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,273 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services/section"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// testBaseURL is where the test sites are published
+const testBaseURL = "https://sweet-bites.sitespark.id"
+
+func testSite(t *testing.T, fixture string) *models.Website {
+	t.Helper()
+	site := &models.Website{
+		Title:     "Sweet Bites",
+		UpdatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if fixture != "" {
+		data, err := os.ReadFile(filepath.Join("testdata", fixture))
+		require.NoError(t, err)
+		site.GeneratedContent = datatypes.JSON(data)
+	}
+	return site
+}
+
+// assertGolden compares output with testdata/<name>.golden; go test -update
+// rewrites the file instead
+func assertGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.WriteFile(path, output, 0644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run go test -update to create the golden file")
+	assert.Equal(t, string(want), string(output))
+}
+
+func TestPageGolden(t *testing.T) {
+	renderer, err := New()
+	require.NoError(t, err)
+
+	hostile := testSite(t, "hostile.json")
+	hostile.Title = "</title><script>alert(1)</script>"
+	hostile.DesignTokens = datatypes.JSON(`{
+		"colors": {"primary": "red;}</style><script>alert(1)</script>"},
+		"typography": {"headingFont": "x');}@import url(//evil.test/x.css);"},
+		"borderRadius": "1px;background:url(//evil.test)"
+	}`)
+
+	site := testSite(t, "site.json")
+	site.DesignTokens = datatypes.JSON(`{
+		"colors": {"primary": "#B45309", "secondary": "#FDE68A", "accent": "#1E3A8A", "background": "#FFFBEB", "text": "#292524"},
+		"typography": {"headingFont": "Playfair Display", "bodyFont": "system-ui"},
+		"spacing": {"small": "0.75rem", "medium": "1.5rem", "large": "3rem"},
+		"borderRadius": "0"
+	}`)
+
+	tests := []struct {
+		name string
+		site *models.Website
+	}{
+		{"site", site},
+		{"empty", testSite(t, "")},
+		{"hostile", hostile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := renderer.Render(tt.site, testBaseURL)
+			require.NoError(t, err)
+			require.Len(t, rendered.Pages, 1)
+			assert.Equal(t, "index.html", rendered.Pages[0].Path)
+			assertGolden(t, tt.name, rendered.Pages[0].HTML)
+		})
+	}
+}
+
+func TestPreviewShowsPlaceholders(t *testing.T) {
+	renderer, err := New()
+	require.NoError(t, err)
+
+	page, err := renderer.RenderPreview(testSite(t, "site.json"), testBaseURL, "")
+	require.NoError(t, err)
+	assertGolden(t, "preview", page.HTML)
+
+	published, err := renderer.Render(testSite(t, "site.json"), testBaseURL)
+	require.NoError(t, err)
+	assert.NotContains(t, string(published.Pages[0].HTML), `class="placeholder unsupported"`)
+	assert.NotContains(t, string(published.Pages[0].HTML), `<meta name="robots"`)
+	assert.Contains(t, string(page.HTML), "<strong>carousel</strong> section")
+	assert.Contains(t, string(page.HTML), `<meta name="robots" content="noindex">`)
+}
+
+func TestRegisteredTypeRenders(t *testing.T) {
+	sections := section.NewRegistry()
+	require.NoError(t, sections.Register(section.Type{
+		Name:     "banner",
+		Schema:   `{"type":"object","required":["text"],"properties":{"text":{"type":"string"}}}`,
+		Defaults: map[string]interface{}{"text": "Hello"},
+		Template: `<section class="banner">{{.text}}</section>`,
+	}))
+	renderer, err := newRenderer(sections)
+	require.NoError(t, err)
+
+	site := &models.Website{GeneratedContent: datatypes.JSON(`{"sections":[
+		{"type":"banner","content":{"text":"<b>Open today</b>"}},
+		{"type":"banner","content":{}},
+		{"type":"hero","content":{"title":"Not registered here"}}
+	]}`)}
+	rendered, err := renderer.Render(site, testBaseURL)
+	require.NoError(t, err)
+
+	html := string(rendered.Pages[0].HTML)
+	assert.Contains(t, html, `<section class="banner">&lt;b&gt;Open today&lt;/b&gt;</section>`)
+	assert.Equal(t, 1, strings.Count(html, `class="banner"`), "content without the required text is left out")
+	assert.NotContains(t, html, "Not registered here")
+}
+
+func TestFixtureCoversEverySectionType(t *testing.T) {
+	renderer, err := New()
+	require.NoError(t, err)
+
+	content, err := models.ParseContent(testSite(t, "site.json").GeneratedContent)
+	require.NoError(t, err)
+	covered := map[string]bool{}
+	for _, section := range content.Sections {
+		covered[section.Type] = true
+	}
+
+	types := renderer.SectionTypes()
+	assert.NotEmpty(t, types)
+	for _, sectionType := range types {
+		assert.True(t, covered[sectionType], "testdata/site.json has no %s section", sectionType)
+	}
+}
+
+func TestPolicyAllowsOnlyThePageStyles(t *testing.T) {
+	renderer, err := New()
+	require.NoError(t, err)
+
+	rendered, err := renderer.Render(testSite(t, "site.json"), testBaseURL)
+	require.NoError(t, err)
+
+	page := rendered.Pages[0]
+	html := string(page.HTML)
+	start := strings.Index(html, "<style>") + len("<style>")
+	end := strings.Index(html, "</style>")
+	sum := sha256.Sum256([]byte(html[start:end]))
+
+	assert.Contains(t, page.Policy, "default-src 'none'")
+	assert.Contains(t, page.Policy, "style-src 'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+	assert.NotContains(t, page.Policy, "script-src")
+	assert.Contains(t, page.Policy, "https://fonts.googleapis.com", "the default fonts are hosted")
+	assert.Contains(t, html, `<link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Inter:wght@400;600;700&amp;display=swap">`)
+
+	site := testSite(t, "site.json")
+	site.DesignTokens = datatypes.JSON(`{"typography": {"headingFont": "Georgia", "bodyFont": "system-ui"}}`)
+	rendered, err = renderer.Render(site, testBaseURL)
+	require.NoError(t, err)
+	page = rendered.Pages[0]
+	assert.NotContains(t, page.Policy, "fonts.googleapis.com")
+	assert.NotContains(t, string(page.HTML), `<link rel="stylesheet"`)
+}
+
+func TestPagesLinkRelatively(t *testing.T) {
+	renderer, err := New()
+	require.NoError(t, err)
+
+	rendered, err := renderer.Render(testSite(t, "pages.json"), testBaseURL)
+	require.NoError(t, err)
+
+	pages := map[string]string{}
+	var paths []string
+	for _, page := range rendered.Pages {
+		pages[page.Path] = string(page.HTML)
+		paths = append(paths, page.Path)
+	}
+	assert.Equal(t, []string{"index.html", "about/index.html", "blog/index.html", "blog/opening-day/index.html"}, paths,
+		"pages with invalid or duplicate slugs are left out")
+
+	home := pages["index.html"]
+	assert.Contains(t, home, `<li><a href="./" aria-current="page">Home</a></li>`)
+	assert.Contains(t, home, `<li><a href="./about/">Our story</a></li>`)
+	assert.Contains(t, home, `<li><a href="./blog/">Blog</a></li>`)
+	assert.NotContains(t, home, "opening-day", "nested pages are not in the menu")
+
+	post := pages["blog/opening-day/index.html"]
+	assert.Contains(t, post, `<a class="brand" href="../../">Sweet Bites</a>`)
+	assert.Contains(t, post, `<li><a href="../../about/">Our story</a></li>`)
+	assert.Contains(t, post, `<title>Opening day | Sweet Bites</title>`)
+	assert.Contains(t, post, `<link rel="canonical" href="https://sweet-bites.sitespark.id/blog/opening-day/">`)
+	assert.NotContains(t, post, "application/ld+json", "structured data is on the home page only")
+
+	sitemap := string(rendered.Sitemap)
+	assert.Equal(t, 4, strings.Count(sitemap, "<url>"))
+	assert.Contains(t, sitemap, "<loc>https://sweet-bites.sitespark.id/blog/opening-day/</loc>")
+	assert.Contains(t, string(rendered.Robots), "Sitemap: https://sweet-bites.sitespark.id/sitemap.xml")
+
+	assertGolden(t, "about", []byte(pages["about/index.html"]))
+
+	page, err := renderer.RenderPreview(testSite(t, "pages.json"), testBaseURL, "blog/opening-day")
+	require.NoError(t, err)
+	assert.Equal(t, post, strings.Replace(string(page.HTML), "\n\t<meta name=\"robots\" content=\"noindex\">", "", 1),
+		"the preview only adds noindex")
+
+	_, err = renderer.RenderPreview(testSite(t, "pages.json"), testBaseURL, "../secret")
+	assert.True(t, errors.Is(err, ErrPageNotFound))
+}
+
+func TestLocalesRenderBelowTheirCode(t *testing.T) {
+	renderer, err := New()
+	require.NoError(t, err)
+
+	rendered, err := renderer.Render(testSite(t, "locales.json"), testBaseURL)
+	require.NoError(t, err)
+
+	pages := map[string]string{}
+	var paths []string
+	for _, page := range rendered.Pages {
+		pages[page.Path] = string(page.HTML)
+		paths = append(paths, page.Path)
+	}
+	assert.Equal(t, []string{
+		"index.html", "tentang/index.html", "menu/index.html",
+		"en/index.html", "en/tentang/index.html", "en/menu/index.html",
+	}, paths, "invalid and colliding locales are left out")
+
+	home := pages["index.html"]
+	assert.Contains(t, home, `<html lang="id">`)
+	assert.Contains(t, home, `<li><a href="./" aria-current="page">Beranda</a></li>`)
+	assert.Contains(t, home, `<link rel="alternate" hreflang="en" href="https://sweet-bites.sitespark.id/en/">`)
+	assert.Contains(t, home, `<link rel="alternate" hreflang="x-default" href="https://sweet-bites.sitespark.id/">`)
+	assert.Contains(t, home, `<li><a href="./en/" hreflang="en" lang="en">English</a></li>`)
+
+	about := pages["en/tentang/index.html"]
+	assert.Contains(t, about, `<html lang="en">`)
+	assert.Contains(t, about, `<title>About us | Sweet Bites</title>`)
+	assert.Contains(t, about, `<link rel="canonical" href="https://sweet-bites.sitespark.id/en/tentang/">`)
+	assert.Contains(t, about, `<a class="brand" href="../">Sweet Bites</a>`)
+	assert.Contains(t, about, `<li><a href="../">Home</a></li>`)
+	assert.Contains(t, about, `<li><a href="../../tentang/" hreflang="id" lang="id">Bahasa Indonesia</a></li>`)
+	assert.Contains(t, about, `<li><a href="../../en/tentang/" hreflang="en" lang="en" aria-current="true">English</a></li>`)
+
+	menu := pages["en/menu/index.html"]
+	assert.Contains(t, menu, "Menu kami", "untranslated pages keep the default locale's content")
+	assert.NotContains(t, strings.Join(paths, " "), "removed")
+
+	assert.Contains(t, string(rendered.Sitemap), "<loc>https://sweet-bites.sitespark.id/en/tentang/</loc>")
+	assert.Equal(t, 6, strings.Count(string(rendered.Sitemap), "<url>"))
+
+	page, err := renderer.RenderPreview(testSite(t, "locales.json"), testBaseURL, "en/tentang")
+	require.NoError(t, err)
+	assert.Contains(t, string(page.HTML), "Our story")
+	_, err = renderer.RenderPreview(testSite(t, "locales.json"), testBaseURL, "menu/tentang")
+	assert.True(t, errors.Is(err, ErrPageNotFound))
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// safeSchemes are the URL schemes content may link to
+var safeSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"tel":    true,
+}
+
+// SafeURL returns u if it is a relative URL or uses a safe scheme, and ""
+// otherwise, so javascript:, data: and similar URLs never reach a page
+func SafeURL(u string) template.URL {
+	u = strings.TrimSpace(u)
+	if u == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	if parsed.Scheme == "" {
+		// Relative; a colon before any slash would make browsers see a scheme
+		if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+			return ""
+		}
+		return template.URL(parsed.String())
+	}
+	if !safeSchemes[strings.ToLower(parsed.Scheme)] {
+		return ""
+	}
+	return template.URL(parsed.String())
+}
+
+// richTextTags are the elements rich text may use
+var richTextTags = map[string]bool{
+	"p": true, "br": true, "strong": true, "b": true, "em": true, "i": true, "u": true, "s": true,
+	"ul": true, "ol": true, "li": true, "blockquote": true, "h3": true, "h4": true, "a": true,
+}
+
+// droppedTags are removed together with everything inside them
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "template": true,
+	"noscript": true, "textarea": true, "title": true, "svg": true, "math": true, "select": true,
+}
+
+// SanitizeHTML keeps the allowlisted formatting elements of rich text and
+// drops everything else: other tags lose their markup but keep their text,
+// scripts and similar elements disappear with their content, and the only
+// attribute kept is a link's href when its URL is safe. Open elements are
+// closed at the end, so the result cannot break the surrounding page.
+func SanitizeHTML(input string) template.HTML {
+	var b strings.Builder
+	var open []string
+	dropping := 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			// io.EOF, or input the tokenizer gave up on; either way stop
+			break
+		}
+		token := tokenizer.Token()
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[token.Data] {
+				if tt == html.StartTagToken {
+					dropping++
+				}
+				continue
+			}
+			if dropping > 0 || !richTextTags[token.Data] {
+				continue
+			}
+			if token.Data == "br" {
+				b.WriteString("<br>")
+				continue
+			}
+			if tt == html.SelfClosingTagToken {
+				continue
+			}
+			b.WriteString("<" + token.Data)
+			if token.Data == "a" {
+				for _, attr := range token.Attr {
+					if attr.Key == "href" {
+						if href := SafeURL(attr.Val); href != "" {
+							b.WriteString(` href="` + html.EscapeString(string(href)) + `"`)
+						}
+					}
+				}
+				b.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			b.WriteString(">")
+			open = append(open, token.Data)
+
+		case html.EndTagToken:
+			if droppedTags[token.Data] {
+				if dropping > 0 {
+					dropping--
+				}
+				continue
+			}
+			if dropping > 0 {
+				continue
+			}
+			// Close up to the matching element; stray end tags are ignored
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == token.Data {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+
+		case html.TextToken:
+			if dropping == 0 {
+				b.WriteString(html.EscapeString(token.Data))
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return template.HTML(b.String())
+}
@@ -0,0 +1,57 @@
+package render
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeURL(t *testing.T) {
+	allowed := map[string]template.URL{
+		"https://example.test/menu": "https://example.test/menu",
+		" http://example.test ":     "http://example.test",
+		"mailto:hello@example.test": "mailto:hello@example.test",
+		"tel:+628123456":            "tel:+628123456",
+		"/menu":                     "/menu",
+		"#contact":                  "#contact",
+		"menu.html?day=1":           "menu.html?day=1",
+	}
+	for input, want := range allowed {
+		assert.Equal(t, want, SafeURL(input), input)
+	}
+
+	for _, input := range []string{
+		"javascript:alert(1)",
+		"JavaScript:alert(1)",
+		"data:text/html,<script>alert(1)</script>",
+		"vbscript:msgbox",
+		"file:///etc/passwd",
+		"",
+	} {
+		assert.Empty(t, SafeURL(input), input)
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  template.HTML
+	}{
+		{"<p>Fresh <em>daily</em></p>", "<p>Fresh <em>daily</em></p>"},
+		{`<p class="x" onclick="alert(1)">Hi</p>`, "<p>Hi</p>"},
+		{"<script>alert(1)</script>Safe", "Safe"},
+		{"<style>body{display:none}</style><b>Bold</b>", "<b>Bold</b>"},
+		{`<div><span style="color:red">Kept text</span></div>`, "Kept text"},
+		{`<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{`<a href="https://example.test/?a=1&b=2">x</a>`, `<a href="https://example.test/?a=1&amp;b=2" rel="nofollow noopener noreferrer">x</a>`},
+		{"<ul><li>One<li>Two", "<ul><li>One<li>Two</li></li></ul>"},
+		{"</p>Stray<br/>line", "Stray<br>line"},
+		{"5 < 6 & 7 > 3", "5 &lt; 6 &amp; 7 &gt; 3"},
+		{"<img src=x onerror=alert(1)>", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SanitizeHTML(tt.input), tt.input)
+	}
+}
@@ -0,0 +1,122 @@
+package revision
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"backend-go/internal/models"
+
+	"gorm.io/datatypes"
+)
+
+// Change operations
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Change is one difference between two revisions. Path is a JSON Pointer
+// into the revision document, e.g. /generatedContent/sections/0/content/title.
+type Change struct {
+	Op   string      `json:"op"`
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// document is the editable state of a revision as one JSON value
+func document(r *models.WebsiteRevision) map[string]interface{} {
+	return map[string]interface{}{
+		"title":            r.Title,
+		"description":      r.Description,
+		"config":           decode(r.Config),
+		"designTokens":     decode(r.DesignTokens),
+		"generatedContent": decode(r.GeneratedContent),
+	}
+}
+
+func decode(data datatypes.JSON) interface{} {
+	var value interface{}
+	if len(data) == 0 || json.Unmarshal(data, &value) != nil {
+		return nil
+	}
+	return value
+}
+
+// Diff lists the structural differences between two JSON values. Objects are
+// compared key by key and arrays position by position, so an edited section
+// shows up as changes to its fields rather than a new page.
+func Diff(from, to interface{}) []Change {
+	changes := []Change{}
+	diffValues("", from, to, &changes)
+	return changes
+}
+
+func diffValues(path string, from, to interface{}, changes *[]Change) {
+	switch a := from.(type) {
+	case map[string]interface{}:
+		if b, ok := to.(map[string]interface{}); ok {
+			diffObjects(path, a, b, changes)
+			return
+		}
+	case []interface{}:
+		if b, ok := to.([]interface{}); ok {
+			diffArrays(path, a, b, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, Change{Op: OpReplace, Path: path, From: from, To: to})
+	}
+}
+
+func diffObjects(path string, from, to map[string]interface{}, changes *[]Change) {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := path + "/" + escapePointer(key)
+		a, inFrom := from[key]
+		b, inTo := to[key]
+		switch {
+		case !inTo:
+			*changes = append(*changes, Change{Op: OpRemove, Path: child, From: a})
+		case !inFrom:
+			*changes = append(*changes, Change{Op: OpAdd, Path: child, To: b})
+		default:
+			diffValues(child, a, b, changes)
+		}
+	}
+}
+
+func diffArrays(path string, from, to []interface{}, changes *[]Change) {
+	for i := 0; i < len(from) || i < len(to); i++ {
+		child := fmt.Sprintf("%s/%d", path, i)
+		switch {
+		case i >= len(to):
+			*changes = append(*changes, Change{Op: OpRemove, Path: child, From: from[i]})
+		case i >= len(from):
+			*changes = append(*changes, Change{Op: OpAdd, Path: child, To: to[i]})
+		default:
+			diffValues(child, from[i], to[i], changes)
+		}
+	}
+}
+
+// escapePointer escapes a key for use in a JSON Pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
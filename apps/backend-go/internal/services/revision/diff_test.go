@@ -0,0 +1,45 @@
+package revision
+
+import (
+	"testing"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestDiff(t *testing.T) {
+	from := &models.WebsiteRevision{
+		Title:            "Sweet Bites",
+		DesignTokens:     datatypes.JSON(`{"colors":{"primary":"#3B82F6"},"borderRadius":"0.5rem"}`),
+		GeneratedContent: datatypes.JSON(`{"sections":[{"type":"hero","content":{"title":"Welcome","a/b":1}},{"type":"about","content":{}}]}`),
+	}
+	to := &models.WebsiteRevision{
+		Title:            "Sweet Bites",
+		Description:      "A family bakery",
+		DesignTokens:     datatypes.JSON(`{"colors":{"primary":"#1D4ED8"}}`),
+		GeneratedContent: datatypes.JSON(`{"sections":[{"type":"hero","content":{"title":"Fresh bread daily","a/b":1}}]}`),
+	}
+
+	assert.Equal(t, []Change{
+		{Op: OpReplace, Path: "/description", From: "", To: "A family bakery"},
+		{Op: OpRemove, Path: "/designTokens/borderRadius", From: "0.5rem"},
+		{Op: OpReplace, Path: "/designTokens/colors/primary", From: "#3B82F6", To: "#1D4ED8"},
+		{Op: OpReplace, Path: "/generatedContent/sections/0/content/title", From: "Welcome", To: "Fresh bread daily"},
+		{Op: OpRemove, Path: "/generatedContent/sections/1", From: map[string]interface{}{"type": "about", "content": map[string]interface{}{}}},
+	}, Diff(document(from), document(to)))
+}
+
+func TestDiffIdentical(t *testing.T) {
+	r := &models.WebsiteRevision{Title: "Same", Config: datatypes.JSON(`{"a":[1,2]}`)}
+	assert.Empty(t, Diff(document(r), document(r)))
+}
+
+func TestDiffKindChange(t *testing.T) {
+	changes := Diff(map[string]interface{}{"a": []interface{}{1.0}}, map[string]interface{}{"a": "one", "b~": true})
+	assert.Equal(t, []Change{
+		{Op: OpReplace, Path: "/a", From: []interface{}{1.0}, To: "one"},
+		{Op: OpAdd, Path: "/b~0", To: true},
+	}, changes)
+}
@@ -0,0 +1,260 @@
+// Package revision keeps the history of every website: each change writes an
+// immutable revision that can be listed, compared and restored.
+package revision
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"backend-go/internal/config"
+	"backend-go/internal/database"
+	"backend-go/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Authors of a revision
+const (
+	AuthorUser   = "user"   // an edit through the website API
+	AuthorAI     = "ai"     // generation or a section rewrite
+	AuthorTool   = "tool"   // a tool call of the chat assistant
+	AuthorSystem = "system" // the state found when history started
+)
+
+var (
+	// ErrWebsiteNotFound is returned when the website does not exist or is
+	// owned by someone else
+	ErrWebsiteNotFound = errors.New("website not found")
+	// ErrRevisionNotFound is returned for versions without a revision
+	ErrRevisionNotFound = errors.New("revision not found")
+)
+
+// Origin says who changed a website and why
+type Origin struct {
+	Author string
+	UserID *uuid.UUID
+	Reason string
+}
+
+// ByUser is the origin of a change made by a user
+func ByUser(userID uuid.UUID, reason string) Origin {
+	return Origin{Author: AuthorUser, UserID: &userID, Reason: reason}
+}
+
+type Service struct {
+	db     *database.Database
+	config *config.RevisionConfig
+}
+
+func NewService(db *database.Database, cfg *config.RevisionConfig) *Service {
+	return &Service{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// Record writes a revision of the website's state after a change, within
+// the change's transaction. before, when given, is the state the change
+// started from: it is recorded first if the website has no history yet, so
+// websites older than revisions can still be restored. Revisions beyond the
+// retention are pruned.
+func (s *Service) Record(tx *gorm.DB, before, after *models.Website, origin Origin) error {
+	if before != nil {
+		var count int64
+		if err := tx.Model(&models.WebsiteRevision{}).Where("website_id = ?", before.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check revisions: %w", err)
+		}
+		if count == 0 && before.ContentVersion < after.ContentVersion {
+			baseline := snapshot(before, Origin{Author: AuthorSystem, Reason: "State before revision history"})
+			if err := tx.Create(baseline).Error; err != nil {
+				return fmt.Errorf("failed to record revision: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Create(snapshot(after, origin)).Error; err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return s.prune(tx, after.ID, after.ContentVersion)
+}
+
+func snapshot(site *models.Website, origin Origin) *models.WebsiteRevision {
+	return &models.WebsiteRevision{
+		WebsiteID:        site.ID,
+		Version:          site.ContentVersion,
+		Author:           origin.Author,
+		UserID:           origin.UserID,
+		Reason:           origin.Reason,
+		Title:            site.Title,
+		Description:      site.Description,
+		Config:           site.Config,
+		DesignTokens:     site.DesignTokens,
+		GeneratedContent: site.GeneratedContent,
+	}
+}
+
+// prune deletes unnamed revisions beyond REVISION_KEEP or older than
+// REVISION_MAX_AGE, never the latest one
+func (s *Service) prune(tx *gorm.DB, websiteID uuid.UUID, latest int) error {
+	prunable := func() *gorm.DB {
+		return tx.Where("website_id = ? AND name = '' AND version < ?", websiteID, latest)
+	}
+
+	if s.config.Keep > 0 {
+		var cutoff []int
+		if err := tx.Model(&models.WebsiteRevision{}).
+			Where("website_id = ? AND name = ''", websiteID).
+			Order("version DESC").
+			Offset(s.config.Keep).
+			Limit(1).
+			Pluck("version", &cutoff).Error; err != nil {
+			return fmt.Errorf("failed to prune revisions: %w", err)
+		}
+		if len(cutoff) > 0 {
+			if err := prunable().Where("version <= ?", cutoff[0]).Delete(&models.WebsiteRevision{}).Error; err != nil {
+				return fmt.Errorf("failed to prune revisions: %w", err)
+			}
+		}
+	}
+
+	if s.config.MaxAge > 0 {
+		if err := prunable().Where("created_at < ?", time.Now().Add(-s.config.MaxAge)).Delete(&models.WebsiteRevision{}).Error; err != nil {
+			return fmt.Errorf("failed to prune revisions: %w", err)
+		}
+	}
+	return nil
+}
+
+// List returns a website's revisions, newest first, optionally only the
+// named snapshots, with the total count
+func (s *Service) List(userID, websiteID uuid.UUID, named bool, limit, offset int) ([]models.WebsiteRevision, int64, error) {
+	if err := s.checkOwner(s.db.DB, userID, websiteID); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.DB.Model(&models.WebsiteRevision{}).Where("website_id = ?", websiteID)
+	if named {
+		query = query.Where("name <> ''")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count revisions: %w", err)
+	}
+
+	var revisions []models.WebsiteRevision
+	if err := query.Order("version DESC").Limit(limit).Offset(offset).Find(&revisions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load revisions: %w", err)
+	}
+	return revisions, total, nil
+}
+
+// Get returns one revision of a website
+func (s *Service) Get(userID, websiteID uuid.UUID, version int) (*models.WebsiteRevision, error) {
+	if err := s.checkOwner(s.db.DB, userID, websiteID); err != nil {
+		return nil, err
+	}
+	return s.get(s.db.DB, websiteID, version)
+}
+
+func (s *Service) get(tx *gorm.DB, websiteID uuid.UUID, version int) (*models.WebsiteRevision, error) {
+	var revision models.WebsiteRevision
+	if err := tx.Where("website_id = ? AND version = ?", websiteID, version).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to load revision: %w", err)
+	}
+	return &revision, nil
+}
+
+// Compare returns the changes that turn revision from into revision to
+func (s *Service) Compare(userID, websiteID uuid.UUID, from, to int) ([]Change, error) {
+	a, err := s.Get(userID, websiteID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.get(s.db.DB, websiteID, to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(document(a), document(b)), nil
+}
+
+// Restore brings the website's content, config and design tokens back to a
+// revision. The restore is itself a change: it gets a new version and
+// revision, so it can be undone in turn.
+func (s *Service) Restore(userID, websiteID uuid.UUID, version int) (*models.Website, error) {
+	var site models.Website
+	err := s.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", websiteID, userID).
+			First(&site).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWebsiteNotFound
+			}
+			return fmt.Errorf("failed to load website: %w", err)
+		}
+
+		revision, err := s.get(tx, websiteID, version)
+		if err != nil {
+			return err
+		}
+
+		before := site
+		if err := tx.Model(&site).Updates(map[string]interface{}{
+			"title":             revision.Title,
+			"description":       revision.Description,
+			"config":            revision.Config,
+			"design_tokens":     revision.DesignTokens,
+			"generated_content": revision.GeneratedContent,
+			"content_version":   site.ContentVersion + 1,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to restore website: %w", err)
+		}
+		if err := tx.First(&site, "id = ?", websiteID).Error; err != nil {
+			return fmt.Errorf("failed to load website: %w", err)
+		}
+
+		return s.Record(tx, &before, &site, ByUser(userID, fmt.Sprintf("Restored revision %d", version)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// Name turns a revision into a named snapshot, or back into a regular
+// revision when name is empty
+func (s *Service) Name(userID, websiteID uuid.UUID, version int, name string) (*models.WebsiteRevision, error) {
+	revision, err := s.Get(userID, websiteID, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.DB.Model(revision).Update("name", name).Error; err != nil {
+		return nil, fmt.Errorf("failed to name revision: %w", err)
+	}
+	return revision, nil
+}
+
+// DeleteAll removes a website's history within the transaction deleting it
+func (s *Service) DeleteAll(tx *gorm.DB, websiteID uuid.UUID) error {
+	if err := tx.Where("website_id = ?", websiteID).Delete(&models.WebsiteRevision{}).Error; err != nil {
+		return fmt.Errorf("failed to delete revisions: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) checkOwner(tx *gorm.DB, userID, websiteID uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&models.Website{}).Where("id = ? AND user_id = ?", websiteID, userID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to load website: %w", err)
+	}
+	if count == 0 {
+		return ErrWebsiteNotFound
+	}
+	return nil
+}
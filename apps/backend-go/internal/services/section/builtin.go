@@ -0,0 +1,208 @@
+package section
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Builtin holds the section types the platform ships with. Further types
+// may be registered on it before the renderer is created.
+var Builtin = mustRegistry(builtinTypes())
+
+func mustRegistry(types []Type) *Registry {
+	registry := NewRegistry()
+	for _, t := range types {
+		if err := registry.Register(t); err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}
+
+// templateOf reads a built-in type's template
+func templateOf(name string) string {
+	data, err := templateFS.ReadFile("templates/" + name + ".html")
+	if err != nil {
+		panic(fmt.Sprintf("section type %s has no template: %v", name, err))
+	}
+	return string(data)
+}
+
+func builtinTypes() []Type {
+	return []Type{
+		{
+			Name:     "hero",
+			Template: templateOf("hero"),
+			Hint:     "The first section: a headline, a short subtitle and optionally a call-to-action link.",
+			Schema: `{"type":"object","required":["title"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"subtitle":{"type":"string","maxLength":300},
+				"ctaText":{"type":"string","maxLength":40},
+				"ctaUrl":{"type":"string","description":"A relative link such as #contact, or an http(s), mailto or tel URL"}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title":    "Welcome",
+				"subtitle": "Tell visitors what you do in one sentence.",
+			},
+		},
+		{
+			Name:     "about",
+			Template: templateOf("about"),
+			Hint:     "Who the business is. Plain text in text, or simple formatting (p, strong, em, ul, li, a) in html.",
+			Schema: `{"type":"object","required":["title"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"text":{"type":"string"},
+				"html":{"type":"string"}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title": "About us",
+				"text":  "Share your story and what makes you different.",
+			},
+		},
+		{
+			Name:     "services",
+			Template: templateOf("services"),
+			Hint:     "What the business offers, one item per product or service.",
+			Schema: `{"type":"object","required":["title","items"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"items":{"type":"array","maxItems":12,"items":{"type":"object","required":["title"],"properties":{
+					"title":{"type":"string","maxLength":120},
+					"description":{"type":"string","maxLength":500}
+				}}}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title": "What we offer",
+				"items": []interface{}{
+					map[string]interface{}{"title": "Service", "description": "Describe what you offer."},
+				},
+			},
+		},
+		{
+			Name:     "testimonials",
+			Template: templateOf("testimonials"),
+			Hint:     "Quotes from happy customers.",
+			Schema: `{"type":"object","required":["title","items"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"items":{"type":"array","maxItems":12,"items":{"type":"object","required":["quote"],"properties":{
+					"quote":{"type":"string","maxLength":600},
+					"author":{"type":"string","maxLength":80},
+					"role":{"type":"string","maxLength":80}
+				}}}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title": "What our customers say",
+				"items": []interface{}{
+					map[string]interface{}{"quote": "A wonderful experience.", "author": "A happy customer"},
+				},
+			},
+		},
+		{
+			Name:     "pricing",
+			Template: templateOf("pricing"),
+			Hint:     "Plans or packages with their price and what they include. Prices are text, e.g. \"Rp 150.000\".",
+			Schema: `{"type":"object","required":["title","plans"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"plans":{"type":"array","maxItems":6,"items":{"type":"object","required":["name","price"],"properties":{
+					"name":{"type":"string","maxLength":80},
+					"price":{"type":"string","maxLength":40},
+					"period":{"type":"string","maxLength":40},
+					"features":{"type":"array","maxItems":12,"items":{"type":"string","maxLength":120}},
+					"ctaText":{"type":"string","maxLength":40},
+					"ctaUrl":{"type":"string"}
+				}}}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title": "Pricing",
+				"plans": []interface{}{
+					map[string]interface{}{"name": "Basic", "price": "$10", "period": "month", "features": []interface{}{"Everything you need to start"}},
+				},
+			},
+		},
+		{
+			Name:     "faq",
+			Template: templateOf("faq"),
+			Hint:     "Frequently asked questions with short answers.",
+			Schema: `{"type":"object","required":["title","items"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"items":{"type":"array","maxItems":20,"items":{"type":"object","required":["question","answer"],"properties":{
+					"question":{"type":"string","maxLength":200},
+					"answer":{"type":"string","maxLength":1000}
+				}}}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title": "Frequently asked questions",
+				"items": []interface{}{
+					map[string]interface{}{"question": "How can I reach you?", "answer": "Use the contact details below."},
+				},
+			},
+		},
+		{
+			Name:     "gallery",
+			Template: templateOf("gallery"),
+			Hint:     "Photos with alt text describing each one. Only use image URLs given by the user.",
+			Schema: `{"type":"object","required":["title","images"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"images":{"type":"array","maxItems":24,"items":{"type":"object","required":["url","alt"],"properties":{
+					"url":{"type":"string"},
+					"alt":{"type":"string","maxLength":200},
+					"caption":{"type":"string","maxLength":200}
+				}}}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title":  "Gallery",
+				"images": []interface{}{},
+			},
+		},
+		{
+			Name:     "team",
+			Template: templateOf("team"),
+			Hint:     "The people behind the business.",
+			Schema: `{"type":"object","required":["title","members"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"members":{"type":"array","maxItems":24,"items":{"type":"object","required":["name"],"properties":{
+					"name":{"type":"string","maxLength":80},
+					"role":{"type":"string","maxLength":80},
+					"bio":{"type":"string","maxLength":500},
+					"photo":{"type":"string"}
+				}}}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title":   "Our team",
+				"members": []interface{}{},
+			},
+		},
+		{
+			Name:     "cta",
+			Template: templateOf("cta"),
+			Hint:     "A closing call to action: a short line and a button, usually before the contact section.",
+			Schema: `{"type":"object","required":["title"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"text":{"type":"string","maxLength":300},
+				"buttonText":{"type":"string","maxLength":40},
+				"buttonUrl":{"type":"string"}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title":      "Ready to get started?",
+				"buttonText": "Contact us",
+				"buttonUrl":  "#contact",
+			},
+		},
+		{
+			Name:     "contact",
+			Template: templateOf("contact"),
+			Hint:     "The last section: how to reach the business.",
+			Schema: `{"type":"object","required":["title"],"properties":{
+				"title":{"type":"string","maxLength":120},
+				"email":{"type":"string","maxLength":200},
+				"phone":{"type":"string","maxLength":40},
+				"address":{"type":"string","maxLength":300}
+			}}`,
+			Defaults: map[string]interface{}{
+				"title": "Contact us",
+			},
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package section
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Schema is the subset of JSON Schema section types declare their content
+// with: typed values, object properties with required keys, and lists with
+// an item schema. Keys a schema does not mention are allowed.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	MaxItems    int                `json:"maxItems,omitempty"`
+	MaxLength   int                `json:"maxLength,omitempty"`
+}
+
+// schemaTypes are the value types a schema may name
+var schemaTypes = map[string]bool{"": true, "object": true, "array": true, "string": true, "number": true, "boolean": true}
+
+// parseSchema decodes a schema and checks that it only uses the supported
+// subset
+func parseSchema(data string) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.check("content"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (s *Schema) check(path string) error {
+	if !schemaTypes[s.Type] {
+		return fmt.Errorf("%s: unsupported schema type %q", path, s.Type)
+	}
+	for _, key := range s.Required {
+		if _, ok := s.Properties[key]; !ok {
+			return fmt.Errorf("%s: required key %q has no property", path, key)
+		}
+	}
+	for key, property := range s.Properties {
+		if err := property.check(path + "." + key); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// Validate checks a decoded JSON value against the schema. A null value
+// counts as missing.
+func (s *Schema) Validate(path string, value interface{}) error {
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, key := range s.Required {
+			if object[key] == nil {
+				return fmt.Errorf("%s.%s is required", path, key)
+			}
+		}
+		for key, property := range s.Properties {
+			if item, ok := object[key]; ok && item != nil {
+				if err := property.Validate(path+"."+key, item); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be a list", path)
+		}
+		if s.MaxItems > 0 && len(list) > s.MaxItems {
+			return fmt.Errorf("%s must have at most %d items", path, s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range list {
+				if err := s.Items.Validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be text", path)
+		}
+		if s.MaxLength > 0 && utf8.RuneCountInString(text) > s.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters", path, s.MaxLength)
+		}
+	case "number":
+		switch value.(type) {
+		case float64, int:
+		default:
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package section is the registry of the section types a page is built
+// from. Each type declares the schema of its content, the content a new
+// section starts with, a hint for the model and its template, so
+// generation, editing, preview and deployment all agree on what a section
+// may contain.
+package section
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownType is returned for a section type that is not registered
+var ErrUnknownType = errors.New("unknown section type")
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,30}$`)
+
+// ValidName reports whether name is a well-formed section type name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Type is one kind of section, e.g. the hero or a list of FAQs
+type Type struct {
+	Name string
+	// Hint tells the model what the section is for and how to fill it
+	Hint string
+	// Schema is the JSON Schema of the section's content
+	Schema string
+	// Defaults is the content of a newly added section
+	Defaults map[string]interface{}
+	// Template is the html/template body that renders the content. It is
+	// executed with the content as its data and may use the renderer's
+	// functions, such as safeURL.
+	Template string
+
+	schema *Schema
+}
+
+// Validate checks content against the type's schema
+func (t *Type) Validate(content map[string]interface{}) error {
+	return t.schema.Validate("content", content)
+}
+
+// DefaultContent returns a copy of the type's default content, merged with
+// content when given: keys in content replace the defaults
+func (t *Type) DefaultContent(content map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(t.Defaults)+len(content))
+	for key, value := range t.Defaults {
+		merged[key] = clone(value)
+	}
+	for key, value := range content {
+		merged[key] = value
+	}
+	return merged
+}
+
+// clone deep-copies a decoded JSON value
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = clone(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = clone(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// Registry holds the known section types
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]*Type
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]*Type)}
+}
+
+// Register adds a section type. Its schema must parse and its defaults must
+// satisfy it.
+func (r *Registry) Register(t Type) error {
+	if !ValidName(t.Name) {
+		return fmt.Errorf("invalid section type name %q", t.Name)
+	}
+	if strings.TrimSpace(t.Template) == "" {
+		return fmt.Errorf("section type %s has no template", t.Name)
+	}
+
+	schema, err := parseSchema(t.Schema)
+	if err != nil {
+		return fmt.Errorf("section type %s: %w", t.Name, err)
+	}
+	if schema.Type != "object" {
+		return fmt.Errorf("section type %s: content schema must be an object", t.Name)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(t.Schema)); err != nil {
+		return fmt.Errorf("section type %s: %w", t.Name, err)
+	}
+	t.Schema = compact.String()
+	t.schema = schema
+
+	if t.Defaults == nil {
+		t.Defaults = map[string]interface{}{}
+	}
+	if err := t.Validate(t.Defaults); err != nil {
+		return fmt.Errorf("section type %s: defaults: %w", t.Name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[t.Name]; ok {
+		return fmt.Errorf("section type %s is already registered", t.Name)
+	}
+	r.types[t.Name] = &t
+	return nil
+}
+
+// Lookup returns the type with the given name
+func (r *Registry) Lookup(name string) (*Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Types lists the registered types by name
+func (r *Registry) Types() []*Type {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]*Type, 0, len(r.types))
+	for _, t := range r.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// Names lists the registered type names
+func (r *Registry) Names() []string {
+	types := r.Types()
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Validate checks a section's content against its type's schema
+func (r *Registry) Validate(name string, content map[string]interface{}) error {
+	t, ok := r.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%w %q, use one of %s", ErrUnknownType, name, strings.Join(r.Names(), ", "))
+	}
+	return t.Validate(content)
+}
+
+// Guide describes every type for the model: its name, hint and schema
+func (r *Registry) Guide() string {
+	var b strings.Builder
+	for _, t := range r.Types() {
+		fmt.Fprintf(&b, "- %s: %s Content schema: %s\n", t.Name, t.Hint, t.Schema)
+	}
+	return b.String()
+}
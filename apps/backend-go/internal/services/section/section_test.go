@@ -0,0 +1,98 @@
+package section
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinTypes(t *testing.T) {
+	names := Builtin.Names()
+	for _, name := range []string{"hero", "about", "services", "contact", "testimonials", "pricing", "faq", "gallery", "team", "cta"} {
+		assert.Contains(t, names, name)
+	}
+
+	for _, sectionType := range Builtin.Types() {
+		assert.NotEmpty(t, sectionType.Hint, sectionType.Name)
+		assert.NoError(t, sectionType.Validate(sectionType.DefaultContent(nil)), sectionType.Name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := map[string]interface{}{
+		"title": "Questions",
+		"items": []interface{}{
+			map[string]interface{}{"question": "Do you deliver?", "answer": "Yes", "extra": true},
+		},
+	}
+	assert.NoError(t, Builtin.Validate("faq", valid))
+
+	tests := []struct {
+		content map[string]interface{}
+		want    string
+	}{
+		{map[string]interface{}{"items": []interface{}{}}, "content.title is required"},
+		{map[string]interface{}{"title": nil, "items": []interface{}{}}, "content.title is required"},
+		{map[string]interface{}{"title": 3.0, "items": []interface{}{}}, "content.title must be text"},
+		{map[string]interface{}{"title": "Q", "items": "none"}, "content.items must be a list"},
+		{map[string]interface{}{"title": "Q", "items": []interface{}{"Do you deliver?"}}, "content.items[0] must be an object"},
+		{map[string]interface{}{"title": "Q", "items": []interface{}{map[string]interface{}{"question": "Why?"}}}, "content.items[0].answer is required"},
+		{map[string]interface{}{"title": strings.Repeat("a", 121), "items": []interface{}{}}, "content.title must be at most 120 characters"},
+	}
+	for _, tt := range tests {
+		err := Builtin.Validate("faq", tt.content)
+		if assert.Error(t, err) {
+			assert.Equal(t, tt.want, err.Error())
+		}
+	}
+
+	err := Builtin.Validate("carousel", map[string]interface{}{})
+	assert.True(t, errors.Is(err, ErrUnknownType))
+}
+
+func TestRegister(t *testing.T) {
+	registry := NewRegistry()
+	banner := Type{
+		Name:     "banner",
+		Hint:     "A one-line announcement.",
+		Schema:   `{"type": "object", "required": ["text"], "properties": {"text": {"type": "string"}}}`,
+		Defaults: map[string]interface{}{"text": "Now open"},
+		Template: `<section>{{.text}}</section>`,
+	}
+	require.NoError(t, registry.Register(banner))
+
+	registered, ok := registry.Lookup("banner")
+	require.True(t, ok)
+	assert.Equal(t, `{"type":"object","required":["text"],"properties":{"text":{"type":"string"}}}`, registered.Schema)
+	assert.Equal(t, "- banner: A one-line announcement. Content schema: "+registered.Schema+"\n", registry.Guide())
+
+	for name, broken := range map[string]func(*Type){
+		"duplicate":        func(*Type) {},
+		"bad name":         func(t *Type) { t.Name = "Banner!" },
+		"no template":      func(t *Type) { t.Name = "a"; t.Template = " " },
+		"invalid schema":   func(t *Type) { t.Name = "b"; t.Schema = `{"type":"object"` },
+		"unsupported type": func(t *Type) { t.Name = "c"; t.Schema = `{"type":"object","properties":{"n":{"type":"integer"}}}` },
+		"missing property": func(t *Type) { t.Name = "d"; t.Schema = `{"type":"object","required":["text"]}` },
+		"invalid defaults": func(t *Type) { t.Name = "e"; t.Defaults = map[string]interface{}{"text": 1.0} },
+	} {
+		sectionType := banner
+		broken(&sectionType)
+		assert.Error(t, registry.Register(sectionType), name)
+	}
+}
+
+func TestDefaultContent(t *testing.T) {
+	faq, ok := Builtin.Lookup("faq")
+	require.True(t, ok)
+
+	content := faq.DefaultContent(map[string]interface{}{"title": "Questions"})
+	assert.Equal(t, "Questions", content["title"])
+	require.Len(t, content["items"], 1)
+
+	// The copy is independent of the type's defaults
+	content["items"].([]interface{})[0].(map[string]interface{})["question"] = "Changed"
+	assert.Equal(t, "How can I reach you?", faq.DefaultContent(nil)["items"].([]interface{})[0].(map[string]interface{})["question"])
+}
@@ -0,0 +1,186 @@
+package seo
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+)
+
+// Severities of audit issues
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+const (
+	// maxTitleLength is about what search results show of a title
+	maxTitleLength = 60
+	// minDescriptionLength and maxDescriptionLength bound a useful meta
+	// description
+	minDescriptionLength = 50
+	maxDescriptionLength = 160
+	// errorPenalty and warningPenalty are taken off the score per issue
+	errorPenalty   = 10
+	warningPenalty = 4
+)
+
+// Issue is a problem the audit found
+type Issue struct {
+	// Page is the path of the page, e.g. / or /about/; empty for issues of
+	// the whole site
+	Page     string `json:"page,omitempty"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// Report is the result of an audit
+type Report struct {
+	// Score runs from 0 to 100
+	Score  int     `json:"score"`
+	Pages  int     `json:"pages"`
+	Issues []Issue `json:"issues"`
+}
+
+// auditor collects the issues of one audit
+type auditor struct {
+	report Report
+}
+
+func (a *auditor) add(page, severity, code, format string, args ...interface{}) {
+	a.report.Issues = append(a.report.Issues, Issue{
+		Page:     page,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// pagePath is where a page is served, / for the home page
+func pagePath(slug string) string {
+	if slug == "" {
+		return "/"
+	}
+	return "/" + slug + "/"
+}
+
+// Audit checks the search metadata of every page of the website and scores
+// it: each error costs 10 points and each warning 4
+func Audit(site *models.Website, content *models.SiteContent) *Report {
+	a := &auditor{report: Report{Issues: []Issue{}}}
+
+	if site.Title == "" {
+		a.add("", SeverityError, "missing_site_title", "The website has no title, so it is published as %q", defaultName)
+	}
+
+	pages := []*models.Page{{Sections: content.Sections, SEO: content.SEO}}
+	seen := map[string]bool{}
+	for i := range content.Pages {
+		page := &content.Pages[i]
+		if !models.ValidSlug(page.Slug) || seen[page.Slug] {
+			a.add("", SeverityError, "invalid_slug", "The page %q has an invalid or duplicate slug and is not published", page.Slug)
+			continue
+		}
+		seen[page.Slug] = true
+		pages = append(pages, page)
+	}
+	a.report.Pages = len(pages)
+
+	titles := map[string]string{}
+	descriptions := map[string]string{}
+	for _, page := range pages {
+		path := pagePath(page.Slug)
+		meta := PageMeta(site, content, page, "")
+		a.page(path, page, meta)
+
+		if other, ok := titles[meta.Title]; ok {
+			a.add(path, SeverityWarning, "duplicate_title", "The page has the same title as %s", other)
+		} else {
+			titles[meta.Title] = path
+		}
+		if meta.Description == "" {
+			continue
+		}
+		if other, ok := descriptions[meta.Description]; ok {
+			a.add(path, SeverityWarning, "duplicate_description", "The page has the same description as %s", other)
+		} else {
+			descriptions[meta.Description] = path
+		}
+	}
+
+	if !hasContactDetails(content) {
+		a.add("", SeverityWarning, "missing_contact", "No contact section has an email, phone number or address, so search engines cannot describe the business")
+	}
+
+	a.report.Score = score(a.report.Issues)
+	return &a.report
+}
+
+// page checks the metadata and headings of one page
+func (a *auditor) page(path string, page *models.Page, meta Meta) {
+	if n := utf8.RuneCountInString(meta.Title); n > maxTitleLength {
+		a.add(path, SeverityWarning, "long_title", "The title has %d characters; search results show about %d", n, maxTitleLength)
+	}
+
+	switch n := utf8.RuneCountInString(meta.Description); {
+	case n == 0 && page.Slug == "":
+		a.add(path, SeverityError, "missing_description", "The home page has no meta description")
+	case n == 0:
+		a.add(path, SeverityWarning, "missing_description", "The page has no meta description")
+	case n < minDescriptionLength:
+		a.add(path, SeverityWarning, "short_description", "The description has %d characters; aim for %d to %d", n, minDescriptionLength, maxDescriptionLength)
+	case n > maxDescriptionLength:
+		a.add(path, SeverityWarning, "long_description", "The description has %d characters; search results show about %d", n, maxDescriptionLength)
+	}
+
+	if len(page.Sections) == 0 {
+		a.add(path, SeverityError, "empty_page", "The page has no sections")
+	} else if !hasHeading(page.Sections) {
+		a.add(path, SeverityWarning, "missing_heading", "The page has no hero section with a title, so it has no main heading")
+	}
+
+	if page.Slug == "" && meta.Image == "" {
+		a.add(path, SeverityWarning, "missing_image", "The home page has no image to show when it is shared; set seo.image or add a gallery")
+	}
+}
+
+// hasHeading reports whether the sections include a hero with a title,
+// which renders as the page's h1
+func hasHeading(sections []models.Section) bool {
+	for _, s := range sections {
+		if title, _ := s.Content["title"].(string); s.Type == "hero" && strings.TrimSpace(title) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasContactDetails reports whether the site's contact section has any way
+// to reach the business
+func hasContactDetails(content *models.SiteContent) bool {
+	contact := contactDetails(content)
+	for _, key := range []string{"email", "phone", "address"} {
+		if value, _ := contact[key].(string); value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// score takes the penalty of each issue off 100
+func score(issues []Issue) int {
+	score := 100
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			score -= errorPenalty
+		} else {
+			score -= warningPenalty
+		}
+	}
+	if score < 0 {
+		return 0
+	}
+	return score
+}
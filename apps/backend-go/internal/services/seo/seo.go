@@ -0,0 +1,209 @@
+// Package seo derives the search and social metadata of a website's pages:
+// titles, descriptions, canonical URLs, share images and schema.org data,
+// plus the sitemap and robots.txt of a deployed site. It also audits a
+// site's metadata.
+package seo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// defaultName names websites without a title
+const defaultName = "My Website"
+
+// SiteName is the name a website goes by: its title, or a default
+func SiteName(site *models.Website) string {
+	if site.Title == "" {
+		return defaultName
+	}
+	return site.Title
+}
+
+// siteDescription is the description of the website's home page
+func siteDescription(site *models.Website, content *models.SiteContent) string {
+	if content.Description != "" {
+		return content.Description
+	}
+	return site.Description
+}
+
+// Meta is the metadata of one page
+type Meta struct {
+	Title       string
+	Description string
+	Keywords    []string
+	// Canonical is the page's absolute URL; empty without a base URL
+	Canonical string
+	// Image is the absolute URL of the page's share image, if any
+	Image string
+}
+
+// PageURL is the absolute URL of a page; the empty slug is the home page
+func PageURL(baseURL, slug string) string {
+	if baseURL == "" {
+		return ""
+	}
+	url := strings.TrimRight(baseURL, "/") + "/"
+	if slug != "" {
+		url += slug + "/"
+	}
+	return url
+}
+
+// PageMeta resolves the metadata of a page, the home page when its slug is
+// empty. The page's SEO fields win; otherwise the home page is titled after
+// the site and described by the site's description, and other pages are
+// titled "Page | Site" without a description.
+func PageMeta(site *models.Website, content *models.SiteContent, page *models.Page, baseURL string) Meta {
+	meta := Meta{Title: SiteName(site), Canonical: PageURL(baseURL, page.Slug)}
+	if page.Slug == "" {
+		meta.Description = siteDescription(site, content)
+	} else {
+		meta.Title = page.Title + " | " + meta.Title
+	}
+
+	if page.SEO != nil {
+		if page.SEO.Title != "" {
+			meta.Title = page.SEO.Title
+		}
+		if page.SEO.Description != "" {
+			meta.Description = page.SEO.Description
+		}
+		meta.Keywords = page.SEO.Keywords
+		if absoluteURL(page.SEO.Image) {
+			meta.Image = page.SEO.Image
+		}
+	}
+	if meta.Image == "" {
+		meta.Image = firstImage(page.Sections)
+	}
+	return meta
+}
+
+// absoluteURL reports whether url is an absolute http(s) URL, the only kind
+// social networks fetch
+func absoluteURL(url string) bool {
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")
+}
+
+// firstImage returns the first absolute image URL of the sections: a
+// gallery image or a team member's photo
+func firstImage(sections []models.Section) string {
+	for _, s := range sections {
+		var items []interface{}
+		key := ""
+		switch s.Type {
+		case "gallery":
+			items, _ = s.Content["images"].([]interface{})
+			key = "url"
+		case "team":
+			items, _ = s.Content["members"].([]interface{})
+			key = "photo"
+		}
+		for _, item := range items {
+			fields, _ := item.(map[string]interface{})
+			if url, _ := fields[key].(string); absoluteURL(url) {
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+// contactDetails returns the content of the site's first contact section,
+// looking at the home page before the other pages
+func contactDetails(content *models.SiteContent) map[string]interface{} {
+	sections := append([]models.Section(nil), content.Sections...)
+	for _, page := range content.Pages {
+		sections = append(sections, page.Sections...)
+	}
+	for _, s := range sections {
+		if s.Type == "contact" {
+			return s.Content
+		}
+	}
+	return nil
+}
+
+// StructuredData is the schema.org description of the business behind the
+// site, derived from its contact section: a LocalBusiness when the section
+// has an address or phone number, an Organization otherwise
+func StructuredData(site *models.Website, content *models.SiteContent, baseURL string) map[string]interface{} {
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "Organization",
+		"name":     SiteName(site),
+	}
+	if description := siteDescription(site, content); description != "" {
+		data["description"] = description
+	}
+	if url := PageURL(baseURL, ""); url != "" {
+		data["url"] = url
+	}
+	if image := firstImage(content.Sections); image != "" {
+		data["image"] = image
+	}
+
+	contact := contactDetails(content)
+	if email, _ := contact["email"].(string); email != "" {
+		data["email"] = email
+	}
+	phone, _ := contact["phone"].(string)
+	if phone != "" {
+		data["telephone"] = phone
+	}
+	address, _ := contact["address"].(string)
+	if address != "" {
+		data["address"] = map[string]interface{}{
+			"@type":         "PostalAddress",
+			"streetAddress": address,
+		}
+	}
+	if address != "" || phone != "" {
+		data["@type"] = "LocalBusiness"
+	}
+	return data
+}
+
+// sitemapURL is an entry of sitemap.xml
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap lists the pages with the given slugs in the sitemaps.org format
+func Sitemap(baseURL string, slugs []string, updated time.Time) []byte {
+	set := struct {
+		XMLName xml.Name     `xml:"urlset"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		URLs    []sitemapURL `xml:"url"`
+	}{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+	lastMod := ""
+	if !updated.IsZero() {
+		lastMod = updated.UTC().Format("2006-01-02")
+	}
+	for _, slug := range slugs {
+		set.URLs = append(set.URLs, sitemapURL{Loc: PageURL(baseURL, slug), LastMod: lastMod})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	// Encoding plain strings into a buffer cannot fail
+	_ = encoder.Encode(set)
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// Robots is the site's robots.txt: everything may be crawled, and the
+// sitemap is announced
+func Robots(baseURL string) []byte {
+	return []byte("User-agent: *\nAllow: /\n\nSitemap: " + strings.TrimRight(baseURL, "/") + "/sitemap.xml\n")
+}
@@ -0,0 +1,142 @@
+package seo
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"backend-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "https://sweet-bites.sitespark.id"
+
+func testContent() *models.SiteContent {
+	return &models.SiteContent{
+		Description: "Fresh bread and cakes from a family bakery in the heart of Bandung",
+		Sections: []models.Section{
+			{Type: "hero", Content: map[string]interface{}{"title": "Fresh bread daily"}},
+			{Type: "gallery", Content: map[string]interface{}{"images": []interface{}{
+				map[string]interface{}{"url": "/relative.jpg"},
+				map[string]interface{}{"url": "https://images.example.test/loaf.jpg", "alt": "A loaf"},
+			}}},
+		},
+		Pages: []models.Page{{
+			Slug:  "about",
+			Title: "About us",
+			Sections: []models.Section{
+				{Type: "hero", Content: map[string]interface{}{"title": "Our story"}},
+				{Type: "contact", Content: map[string]interface{}{"title": "Visit us", "email": "hello@sweetbites.test", "phone": "+62 812 3456"}},
+			},
+			SEO: &models.SEO{Description: "Three generations of bakers in one small shop, baking every day since 1990"},
+		}},
+	}
+}
+
+func TestPageMeta(t *testing.T) {
+	site := &models.Website{Title: "Sweet Bites"}
+	content := testContent()
+
+	home := PageMeta(site, content, &models.Page{Sections: content.Sections}, baseURL)
+	assert.Equal(t, Meta{
+		Title:       "Sweet Bites",
+		Description: content.Description,
+		Canonical:   "https://sweet-bites.sitespark.id/",
+		Image:       "https://images.example.test/loaf.jpg",
+	}, home, "relative images are skipped")
+
+	about := PageMeta(site, content, &content.Pages[0], baseURL+"/")
+	assert.Equal(t, "About us | Sweet Bites", about.Title)
+	assert.Equal(t, "https://sweet-bites.sitespark.id/about/", about.Canonical)
+	assert.Empty(t, about.Image)
+
+	content.Pages[0].SEO = &models.SEO{Title: "About the bakery", Image: "https://images.example.test/shop.jpg"}
+	about = PageMeta(&models.Website{}, content, &content.Pages[0], "")
+	assert.Equal(t, "About the bakery", about.Title)
+	assert.Empty(t, about.Description, "only the home page falls back to the site's description")
+	assert.Empty(t, about.Canonical)
+	assert.Equal(t, "https://images.example.test/shop.jpg", about.Image)
+}
+
+func TestStructuredData(t *testing.T) {
+	site := &models.Website{Title: "Sweet Bites"}
+	content := testContent()
+
+	data := StructuredData(site, content, baseURL)
+	assert.Equal(t, "LocalBusiness", data["@type"], "a phone number makes a local business")
+	assert.Equal(t, "hello@sweetbites.test", data["email"])
+	assert.Equal(t, "+62 812 3456", data["telephone"])
+	assert.Equal(t, "https://sweet-bites.sitespark.id/", data["url"])
+	assert.NotContains(t, data, "address")
+
+	content.Pages[0].Sections[1].Content = map[string]interface{}{"title": "Write to us", "email": "hello@sweetbites.test"}
+	data = StructuredData(site, content, baseURL)
+	assert.Equal(t, "Organization", data["@type"])
+	assert.NotContains(t, data, "telephone")
+
+	data = StructuredData(&models.Website{Description: "A bakery"}, &models.SiteContent{}, "")
+	assert.Equal(t, "My Website", data["name"])
+	assert.Equal(t, "A bakery", data["description"])
+	assert.NotContains(t, data, "url")
+}
+
+func TestSitemapAndRobots(t *testing.T) {
+	sitemap := Sitemap(baseURL, []string{"", "about", "blog/opening-day"}, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	var parsed struct {
+		URLs []sitemapURL `xml:"url"`
+	}
+	require.NoError(t, xml.Unmarshal(sitemap, &parsed))
+	assert.Equal(t, []sitemapURL{
+		{Loc: "https://sweet-bites.sitespark.id/", LastMod: "2026-03-01"},
+		{Loc: "https://sweet-bites.sitespark.id/about/", LastMod: "2026-03-01"},
+		{Loc: "https://sweet-bites.sitespark.id/blog/opening-day/", LastMod: "2026-03-01"},
+	}, parsed.URLs)
+	assert.Contains(t, string(sitemap), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+
+	assert.Equal(t, "User-agent: *\nAllow: /\n\nSitemap: https://sweet-bites.sitespark.id/sitemap.xml\n", string(Robots(baseURL+"/")))
+}
+
+func issueCodes(report *Report) map[string]string {
+	codes := map[string]string{}
+	for _, issue := range report.Issues {
+		codes[issue.Page+" "+issue.Code] = issue.Severity
+	}
+	return codes
+}
+
+func TestAudit(t *testing.T) {
+	site := &models.Website{Title: "Sweet Bites"}
+	report := Audit(site, testContent())
+	assert.Equal(t, 100, report.Score, "%v", report.Issues)
+	assert.Equal(t, 2, report.Pages)
+	assert.Empty(t, report.Issues)
+
+	content := testContent()
+	content.Description = ""
+	content.Sections = content.Sections[:1]
+	content.Pages[0].Sections = content.Pages[0].Sections[1:]
+	content.Pages[0].SEO.Description = "Too short"
+	content.Pages = append(content.Pages,
+		models.Page{Slug: "blog", Title: "About us"},
+		models.Page{Slug: "Blog Posts", Title: "Posts"},
+	)
+	site.Title = ""
+
+	report = Audit(site, content)
+	assert.Equal(t, map[string]string{
+		" missing_site_title":        SeverityError,
+		" invalid_slug":              SeverityError,
+		"/ missing_description":      SeverityError,
+		"/ missing_image":            SeverityWarning,
+		"/about/ short_description":  SeverityWarning,
+		"/about/ missing_heading":    SeverityWarning,
+		"/blog/ missing_description": SeverityWarning,
+		"/blog/ empty_page":          SeverityError,
+		"/blog/ duplicate_title":     SeverityWarning,
+	}, issueCodes(report))
+	assert.Equal(t, 100-4*10-5*4, report.Score)
+	assert.Equal(t, 3, report.Pages)
+}
@@ -24,6 +24,10 @@ const (
 	TypeSignupBonus      = "signup_bonus"
 	TypeDailyLogin       = "daily_login"
 	TypeWebsiteGen       = "website_generation"
+	TypeChatMessage      = "chat_message"
+	TypeSectionRegen     = "section_regeneration"
+	TypeTranslation      = "translation"
+	TypeAccessibilityFix = "accessibility_fix"
 	TypeReferral         = "referral"
 	TypePurchase         = "purchase"
 	TypeAdminGrant       = "admin_grant"
@@ -0,0 +1,87 @@
+package website
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"backend-go/internal/models"
+)
+
+// Brief is the structured description of a website collected by the guided
+// conversation
+type Brief struct {
+	BusinessType    string   `json:"businessType"`
+	BusinessName    string   `json:"businessName"`
+	TargetAudience  string   `json:"targetAudience"`
+	Features        []string `json:"features"`
+	Style           string   `json:"style"`
+	ColorPreference string   `json:"colorPreference,omitempty"`
+	ContactInfo     string   `json:"contactInfo"`
+}
+
+// Prompt renders the brief as the generation prompt
+func (b *Brief) Prompt() string {
+	var p strings.Builder
+	fmt.Fprintf(&p, "Business type: %s\n", b.BusinessType)
+	fmt.Fprintf(&p, "Business name: %s\n", b.BusinessName)
+	fmt.Fprintf(&p, "Target audience: %s\n", b.TargetAudience)
+	fmt.Fprintf(&p, "Features: %s\n", strings.Join(b.Features, ", "))
+	fmt.Fprintf(&p, "Style: %s\n", b.Style)
+	if b.ColorPreference != "" {
+		fmt.Fprintf(&p, "Color preference: %s\n", b.ColorPreference)
+	}
+	fmt.Fprintf(&p, "Contact information: %s\n", b.ContactInfo)
+	p.WriteString("\nCreate a complete website with a hero section, a section for each requested feature and a contact section. " +
+		"Write the content in the language the brief is written in.")
+	return p.String()
+}
+
+// TemplateFor picks the template that best matches a style description
+func TemplateFor(style string) string {
+	style = strings.ToLower(style)
+	switch {
+	case strings.Contains(style, "minimal"):
+		return "minimal"
+	case strings.Contains(style, "creative"), strings.Contains(style, "playful"),
+		strings.Contains(style, "kreatif"), strings.Contains(style, "ceria"):
+		return "creative"
+	default:
+		return "modern"
+	}
+}
+
+var nonSubdomainChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// subdomainSlug turns a business name into a subdomain label
+func subdomainSlug(name string) string {
+	slug := strings.Trim(nonSubdomainChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.Trim(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "site"
+	}
+	return slug
+}
+
+// AvailableSubdomain returns a free subdomain derived from a business name,
+// adding a numeric suffix when the plain one is taken
+func (g *Generator) AvailableSubdomain(name string) (string, error) {
+	base := subdomainSlug(name)
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		var count int64
+		if err := g.db.DB.Model(&models.Website{}).Where("subdomain = ?", candidate).Count(&count).Error; err != nil {
+			return "", fmt.Errorf("failed to check subdomain: %w", err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free subdomain for %q", name)
+}
@@ -0,0 +1,35 @@
+package website
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBriefPrompt(t *testing.T) {
+	brief := Brief{
+		BusinessType:   "Bakery",
+		BusinessName:   "Sweet Bites",
+		TargetAudience: "Families",
+		Features:       []string{"Menu", "Gallery"},
+		Style:          "Playful",
+		ContactInfo:    "hello@sweetbites.test",
+	}
+
+	prompt := brief.Prompt()
+	assert.Contains(t, prompt, "Business name: Sweet Bites\n")
+	assert.Contains(t, prompt, "Features: Menu, Gallery\n")
+	assert.NotContains(t, prompt, "Color preference")
+}
+
+func TestTemplateFor(t *testing.T) {
+	assert.Equal(t, "minimal", TemplateFor("Modern minimalis"))
+	assert.Equal(t, "creative", TemplateFor("Playful and bright"))
+	assert.Equal(t, "modern", TemplateFor("Elegant"))
+}
+
+func TestSubdomainSlug(t *testing.T) {
+	assert.Equal(t, "sweet-bites", subdomainSlug("  Sweet Bites! "))
+	assert.Equal(t, "site", subdomainSlug("☕"))
+	assert.LessOrEqual(t, len(subdomainSlug("a very long business name that keeps going and going")), 40)
+}
@@ -3,12 +3,16 @@ package website
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"backend-go/internal/database"
 	"backend-go/internal/models"
 	"backend-go/internal/services/ai"
+	"backend-go/internal/services/catalog"
+	"backend-go/internal/services/revision"
+	"backend-go/internal/services/section"
 	"backend-go/internal/services/token"
 
 	"github.com/google/uuid"
@@ -17,17 +21,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrInsufficientTokens is returned when the user cannot pay for a generation
+var ErrInsufficientTokens = errors.New("insufficient tokens")
+
 type Generator struct {
 	db          *database.Database
 	kimi        *ai.KimiClient
 	tokenMgr    *token.Manager
+	revisions   *revision.Service
+	templates   *catalog.Catalog
 }
 
-func NewGenerator(db *database.Database, kimi *ai.KimiClient, tokenMgr *token.Manager) *Generator {
+func NewGenerator(db *database.Database, kimi *ai.KimiClient, tokenMgr *token.Manager, revisions *revision.Service, templates *catalog.Catalog) *Generator {
 	return &Generator{
-		db:       db,
-		kimi:     kimi,
-		tokenMgr: tokenMgr,
+		db:        db,
+		kimi:      kimi,
+		tokenMgr:  tokenMgr,
+		revisions: revisions,
+		templates: templates,
 	}
 }
 
@@ -36,6 +47,8 @@ type GenerateRequest struct {
 	Prompt     string
 	TemplateID string
 	Subdomain  string
+	// Brief, when set, replaces Prompt with the guided conversation's answers
+	Brief *Brief
 }
 
 type GenerateResult struct {
@@ -43,20 +56,45 @@ type GenerateResult struct {
 	TokensUsed int
 }
 
+// Tier returns the subscription tier of a user, defaulting to "free"
+func (g *Generator) Tier(userID uuid.UUID) string {
+	var user models.User
+	if err := g.db.DB.Select("subscription_tier").First(&user, "id = ?", userID).Error; err != nil || user.SubscriptionTier == "" {
+		return "free"
+	}
+	return user.SubscriptionTier
+}
+
+// Template looks up a catalog template the user's subscription includes. It
+// returns catalog.ErrUnknownTemplate or catalog.ErrTemplateNotAllowed.
+func (g *Generator) Template(userID uuid.UUID, templateID string) (*catalog.Template, error) {
+	return g.templates.Check(templateID, g.Tier(userID))
+}
+
 func (g *Generator) Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error) {
 	const websiteGenCost = 50
 
+	template, err := g.Template(req.UserID, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check and deduct tokens
 	hasTokens, err := g.tokenMgr.HasEnoughTokens(req.UserID, websiteGenCost)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check token balance: %w", err)
 	}
 	if !hasTokens {
-		return nil, fmt.Errorf("insufficient tokens: need %d", websiteGenCost)
+		return nil, fmt.Errorf("%w: need %d", ErrInsufficientTokens, websiteGenCost)
+	}
+
+	prompt := req.Prompt
+	if req.Brief != nil {
+		prompt = req.Brief.Prompt()
 	}
 
 	// Generate website content via AI
-	content, err := g.kimi.GenerateWebsite(ctx, req.Prompt, req.TemplateID)
+	content, err := g.kimi.GenerateWebsite(ctx, prompt, template.Guide(), section.Builtin.Guide())
 	if err != nil {
 		return nil, fmt.Errorf("AI generation failed: %w", err)
 	}
@@ -65,7 +103,7 @@ func (g *Generator) Generate(ctx context.Context, req GenerateRequest) (*Generat
 	var generatedData map[string]interface{}
 	if err := json.Unmarshal([]byte(content), &generatedData); err != nil {
 		// Try to extract JSON from markdown code block
-		content = extractJSON(content)
+		content = ExtractJSON(content)
 		if err := json.Unmarshal([]byte(content), &generatedData); err != nil {
 			logrus.WithError(err).WithField("content", content).Warn("Failed to parse AI response as JSON")
 			// Use raw content as fallback
@@ -75,12 +113,18 @@ func (g *Generator) Generate(ctx context.Context, req GenerateRequest) (*Generat
 		}
 	}
 
+	// The sections are saved as generated; the preview marks any that do not
+	// fit their type and publishing leaves them out
+	checkSections(content)
+
 	// Extract title and description
 	title := getString(generatedData, "title", "My Website")
 	description := getString(generatedData, "description", "")
 
-	// Generate design tokens based on template
-	designTokens := g.generateDesignTokens(req.TemplateID)
+	designTokens, err := template.Tokens().JSON()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create website
 	website := &models.Website{
@@ -95,12 +139,17 @@ func (g *Generator) Generate(ctx context.Context, req GenerateRequest) (*Generat
 		GeneratedContent: datatypes.JSON(content),
 	}
 
-	// Transaction: create website and deduct tokens
+	// Transaction: create website, record its first revision and deduct tokens
 	err = g.db.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(website).Error; err != nil {
 			return err
 		}
 
+		origin := revision.Origin{Author: revision.AuthorAI, UserID: &req.UserID, Reason: "Generated website"}
+		if err := g.revisions.Record(tx, nil, website, origin); err != nil {
+			return err
+		}
+
 		_, err := g.tokenMgr.DeductTokensTx(tx, req.UserID, websiteGenCost, "website_generation", 
 			fmt.Sprintf("Generated website: %s", title), &website.ID)
 		if err != nil {
@@ -120,47 +169,36 @@ func (g *Generator) Generate(ctx context.Context, req GenerateRequest) (*Generat
 	}, nil
 }
 
-func (g *Generator) generateDesignTokens(templateID string) datatypes.JSON {
-	tokens := map[string]interface{}{
-		"colors": map[string]string{
-			"primary":   "#3B82F6",
-			"secondary": "#10B981",
-			"accent":    "#F59E0B",
-			"background": "#FFFFFF",
-			"text":      "#1F2937",
-		},
-		"typography": map[string]string{
-			"headingFont": "Inter",
-			"bodyFont":    "Inter",
-		},
-		"spacing": map[string]string{
-			"small":  "1rem",
-			"medium": "2rem",
-			"large":  "4rem",
-		},
-		"borderRadius": "0.5rem",
-	}
-
-	// Customize based on template
-	switch templateID {
-	case "modern":
-		tokens["colors"].(map[string]string)["primary"] = "#6366F1"
-		tokens["borderRadius"] = "1rem"
-	case "minimal":
-		tokens["colors"].(map[string]string)["primary"] = "#000000"
-		tokens["colors"].(map[string]string)["background"] = "#FAFAFA"
-		tokens["borderRadius"] = "0"
-	case "creative":
-		tokens["colors"].(map[string]string)["primary"] = "#EC4899"
-		tokens["colors"].(map[string]string)["secondary"] = "#8B5CF6"
-		tokens["typography"].(map[string]string)["headingFont"] = "Poppins"
-	}
-
-	jsonBytes, _ := json.Marshal(tokens)
-	return datatypes.JSON(jsonBytes)
+// checkSections logs generated sections the registry does not accept and
+// pages with invalid slugs
+func checkSections(content string) {
+	site, err := models.ParseContent(datatypes.JSON(content))
+	if err != nil {
+		return
+	}
+	check := func(slug string, sections []models.Section) {
+		for i, s := range sections {
+			if err := section.Builtin.Validate(s.Type, s.Content); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"page":    slug,
+					"section": i,
+					"type":    s.Type,
+				}).Warn("Generated section does not fit its type")
+			}
+		}
+	}
+	check("", site.Sections)
+	for _, page := range site.Pages {
+		if !models.ValidSlug(page.Slug) {
+			logrus.WithField("slug", page.Slug).Warn("Generated page has an invalid slug")
+			continue
+		}
+		check(page.Slug, page.Sections)
+	}
 }
 
-func extractJSON(content string) string {
+// ExtractJSON returns the JSON inside a markdown code block, or content as is
+func ExtractJSON(content string) string {
 	// Extract JSON from markdown code blocks
 	start := strings.Index(content, "```json")
 	if start != -1 {
@@ -78,6 +78,10 @@ func InternalError(c *gin.Context) {
 	JSONError(c, 500, ErrCodeInternal, "Internal server error")
 }
 
+func TooManyRequests(c *gin.Context, message string) {
+	JSONError(c, 429, ErrCodeTooManyRequests, message)
+}
+
 func InsufficientTokens(c *gin.Context) {
 	JSONError(c, 402, ErrCodeInsufficientTokens, "Insufficient tokens")
 }
\ No newline at end of file
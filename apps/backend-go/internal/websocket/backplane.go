@@ -0,0 +1,285 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Envelope is a message routed between nodes through a Backplane. Exactly
+// one of UserID or Room is set for targeted messages; neither means global.
+type Envelope struct {
+	NodeID  string  `json:"nodeId"`
+	UserID  string  `json:"userId,omitempty"`
+	Room    string  `json:"room,omitempty"`
+	Message Message `json:"message"`
+}
+
+// Backplane fans messages out across backend replicas and tracks which
+// users and rooms are live on which node. Presence entries expire after
+// their TTL unless refreshed, so a crashed node's entries disappear.
+type Backplane interface {
+	// Publish sends an envelope to every subscribed node, including the sender
+	Publish(ctx context.Context, env Envelope) error
+	// Subscribe delivers envelopes to handler until ctx is done or Close is called
+	Subscribe(ctx context.Context, handler func(Envelope)) error
+
+	// SetPresence marks member as present under key for ttl
+	SetPresence(ctx context.Context, key, member string, ttl time.Duration) error
+	// RemovePresence removes member from key
+	RemovePresence(ctx context.Context, key, member string) error
+	// Presence returns the unexpired members of key
+	Presence(ctx context.Context, key string) ([]string, error)
+
+	Close() error
+}
+
+// RedisBackplane implements Backplane with Redis pub/sub for messages and
+// sorted sets scored by expiry time for presence
+type RedisBackplane struct {
+	client  *redis.Client
+	channel string
+	prefix  string
+
+	mu   sync.Mutex
+	subs []*redis.PubSub
+}
+
+// NewRedisBackplane creates a Redis-backed backplane
+func NewRedisBackplane(client *redis.Client) *RedisBackplane {
+	return &RedisBackplane{
+		client:  client,
+		channel: "ws:backplane",
+		prefix:  "ws:presence:",
+	}
+}
+
+// Publish sends an envelope to all nodes
+func (b *RedisBackplane) Publish(ctx context.Context, env Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish envelope: %w", err)
+	}
+	return nil
+}
+
+// Subscribe starts delivering envelopes to handler in the background
+func (b *RedisBackplane) Subscribe(ctx context.Context, handler func(Envelope)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return fmt.Errorf("failed to subscribe to backplane: %w", err)
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	go func() {
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var env Envelope
+				if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+					logrus.WithError(err).Warn("Failed to unmarshal backplane envelope")
+					continue
+				}
+				handler(env)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// SetPresence marks member as present under key for ttl
+func (b *RedisBackplane) SetPresence(ctx context.Context, key, member string, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl).UnixMilli()
+
+	pipe := b.client.TxPipeline()
+	pipe.ZAdd(ctx, b.prefix+key, redis.Z{Score: float64(expiresAt), Member: member})
+	pipe.PExpire(ctx, b.prefix+key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set presence: %w", err)
+	}
+	return nil
+}
+
+// RemovePresence removes member from key
+func (b *RedisBackplane) RemovePresence(ctx context.Context, key, member string) error {
+	if err := b.client.ZRem(ctx, b.prefix+key, member).Err(); err != nil {
+		return fmt.Errorf("failed to remove presence: %w", err)
+	}
+	return nil
+}
+
+// Presence returns the unexpired members of key
+func (b *RedisBackplane) Presence(ctx context.Context, key string) ([]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	pipe := b.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, b.prefix+key, "-inf", now)
+	members := pipe.ZRange(ctx, b.prefix+key, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get presence: %w", err)
+	}
+	return members.Val(), nil
+}
+
+// Close stops all subscriptions
+func (b *RedisBackplane) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subs {
+		sub.Close()
+	}
+	b.subs = nil
+	return nil
+}
+
+// MemoryBackplane implements Backplane in process memory. Several managers
+// sharing one MemoryBackplane behave like separate nodes, which makes it
+// useful for tests and single-node deployments.
+type MemoryBackplane struct {
+	mu        sync.Mutex
+	subs      []chan Envelope
+	presence  map[string]map[string]time.Time
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemoryBackplane creates an in-memory backplane
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{
+		presence: make(map[string]map[string]time.Time),
+		done:     make(chan struct{}),
+	}
+}
+
+// Publish sends an envelope to all subscribers
+func (b *MemoryBackplane) Publish(ctx context.Context, env Envelope) error {
+	b.mu.Lock()
+	subs := append([]chan Envelope(nil), b.subs...)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub <- env:
+		case <-b.done:
+			return fmt.Errorf("backplane is closed")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe starts delivering envelopes to handler in the background, in
+// publish order
+func (b *MemoryBackplane) Subscribe(ctx context.Context, handler func(Envelope)) error {
+	sub := make(chan Envelope, 1024)
+
+	select {
+	case <-b.done:
+		return fmt.Errorf("backplane is closed")
+	default:
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	go func() {
+		defer b.unsubscribe(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.done:
+				return
+			case env := <-sub:
+				handler(env)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *MemoryBackplane) unsubscribe(sub chan Envelope) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, s := range b.subs {
+		if s == sub {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetPresence marks member as present under key for ttl
+func (b *MemoryBackplane) SetPresence(ctx context.Context, key, member string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.presence[key] == nil {
+		b.presence[key] = make(map[string]time.Time)
+	}
+	b.presence[key][member] = time.Now().Add(ttl)
+	return nil
+}
+
+// RemovePresence removes member from key
+func (b *MemoryBackplane) RemovePresence(ctx context.Context, key, member string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.presence[key], member)
+	if len(b.presence[key]) == 0 {
+		delete(b.presence, key)
+	}
+	return nil
+}
+
+// Presence returns the unexpired members of key
+func (b *MemoryBackplane) Presence(ctx context.Context, key string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	members := make([]string, 0, len(b.presence[key]))
+	for member, expiresAt := range b.presence[key] {
+		if now.After(expiresAt) {
+			delete(b.presence[key], member)
+			continue
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// Close stops delivering to all subscribers
+func (b *MemoryBackplane) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+	return nil
+}
@@ -0,0 +1,227 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"backend-go/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCluster starts two managers connected through one in-memory backplane
+func newTestCluster(t *testing.T) (*Manager, *Manager, *MemoryBackplane) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	backplane := NewMemoryBackplane()
+	t.Cleanup(func() { backplane.Close() })
+
+	nodeA := NewManager(&config.WebSocketConfig{NodeID: "node-a"})
+	nodeB := NewManager(&config.WebSocketConfig{NodeID: "node-b"})
+	require.NoError(t, nodeA.UseBackplane(ctx, backplane))
+	require.NoError(t, nodeB.UseBackplane(ctx, backplane))
+	go nodeA.Run()
+	go nodeB.Run()
+
+	return nodeA, nodeB, backplane
+}
+
+func receive(t *testing.T, client *Client) Message {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var msg Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("Timeout waiting for message on %s", client.ID)
+		return Message{}
+	}
+}
+
+func TestBackplane_SendToUserAcrossNodes(t *testing.T) {
+	nodeA, nodeB, _ := newTestCluster(t)
+
+	userID := uuid.New()
+	onA := &Client{ID: "on-a", UserID: userID, Send: make(chan []byte, 256)}
+	onB := &Client{ID: "on-b", UserID: userID, Send: make(chan []byte, 256)}
+	nodeA.register <- onA
+	nodeB.register <- onB
+	time.Sleep(50 * time.Millisecond)
+
+	nodeA.SendToUser(userID, Message{Type: MessageTypeChatMessage, Content: "hello"})
+
+	assert.Equal(t, "hello", receive(t, onA).Content)
+	assert.Equal(t, "hello", receive(t, onB).Content)
+
+	// Each node delivers the message exactly once
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, drain(onA))
+	assert.Empty(t, drain(onB))
+}
+
+func TestBackplane_RoomAndGlobalAcrossNodes(t *testing.T) {
+	nodeA, nodeB, _ := newTestCluster(t)
+
+	websiteID := uuid.New()
+	onA := &Client{ID: "on-a", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	onB := &Client{ID: "on-b", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	nodeA.register <- onA
+	nodeB.register <- onB
+	time.Sleep(50 * time.Millisecond)
+
+	nodeA.JoinRoom(onA, websiteID)
+	nodeB.JoinRoom(onB, websiteID)
+	time.Sleep(50 * time.Millisecond)
+
+	// Room membership is visible from both nodes
+	assert.ElementsMatch(t, []string{onA.UserID.String(), onB.UserID.String()}, nodeA.RoomUsers(websiteID))
+	assert.ElementsMatch(t, []string{onA.UserID.String(), onB.UserID.String()}, nodeB.RoomUsers(websiteID))
+
+	// The join on node B was announced to the member on node A
+	var announced []string
+	for _, msg := range drain(onA) {
+		announced = append(announced, msg.UserID)
+	}
+	assert.Contains(t, announced, onB.UserID.String())
+	drain(onB)
+
+	nodeB.BroadcastToRoom(websiteID, Message{Type: MessageTypeWebsiteContentChanged})
+	assert.Equal(t, MessageTypeWebsiteContentChanged, receive(t, onA).Type)
+	assert.Equal(t, MessageTypeWebsiteContentChanged, receive(t, onB).Type)
+
+	nodeA.Broadcast(Message{Type: MessageTypeChatTyping})
+	assert.Equal(t, MessageTypeChatTyping, receive(t, onA).Type)
+	assert.Equal(t, MessageTypeChatTyping, receive(t, onB).Type)
+}
+
+func TestBackplane_UserPresence(t *testing.T) {
+	nodeA, nodeB, _ := newTestCluster(t)
+
+	userID := uuid.New()
+	client := &Client{ID: "client", UserID: userID, Send: make(chan []byte, 256)}
+	nodeB.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, 0, nodeA.GetUserConnectionCount(userID))
+	assert.True(t, nodeA.IsUserConnected(userID), "presence is shared across nodes")
+
+	nodeB.unregister <- client
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, nodeA.IsUserConnected(userID))
+}
+
+func TestMemoryBackplane_PresenceTTL(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	ctx := context.Background()
+
+	require.NoError(t, backplane.SetPresence(ctx, "user:1", "node-a", 30*time.Millisecond))
+	require.NoError(t, backplane.SetPresence(ctx, "user:1", "node-b", time.Minute))
+
+	members, err := backplane.Presence(ctx, "user:1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"node-a", "node-b"}, members)
+
+	time.Sleep(50 * time.Millisecond)
+
+	members, err = backplane.Presence(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node-b"}, members)
+
+	require.NoError(t, backplane.RemovePresence(ctx, "user:1", "node-b"))
+	members, err = backplane.Presence(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+// stalledBackplane is a MemoryBackplane whose calls hang until their
+// context expires, like an unreachable Redis
+type stalledBackplane struct {
+	*MemoryBackplane
+}
+
+func (b stalledBackplane) Publish(ctx context.Context, env Envelope) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b stalledBackplane) SetPresence(ctx context.Context, key, member string, ttl time.Duration) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b stalledBackplane) RemovePresence(ctx context.Context, key, member string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b stalledBackplane) Presence(ctx context.Context, key string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBackplane_StalledBackplaneDoesNotBlockDelivery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	backplane := stalledBackplane{NewMemoryBackplane()}
+	t.Cleanup(func() { backplane.Close() })
+	manager := NewManager(&config.WebSocketConfig{NodeID: "node-a"})
+	require.NoError(t, manager.UseBackplane(ctx, backplane))
+	go manager.Run()
+
+	userID := uuid.New()
+	websiteID := uuid.New()
+	done := make(chan struct{})
+	var client *Client
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			c := &Client{ID: uuid.NewString(), UserID: userID, Send: make(chan []byte, 256)}
+			manager.register <- c
+			manager.JoinRoom(c, websiteID)
+			if i < 2 {
+				manager.unregister <- c
+			} else {
+				client = c
+			}
+		}
+		manager.SendToUser(userID, Message{Type: MessageTypeChatMessage, Content: "hello"})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(backplaneTimeout / 2):
+		t.Fatal("registering and sending waited on the backplane")
+	}
+	assert.Equal(t, "hello", receive(t, client).Content)
+}
+
+func TestBackplane_DeliversWhileRunIsBlocked(t *testing.T) {
+	// Run is not started, as if it were waiting on the backplane, which is
+	// waiting on this node's subscriber
+	manager := NewManager(&config.WebSocketConfig{NodeID: "node-a"})
+	userID := uuid.New()
+	client := &Client{ID: "client", UserID: userID, Send: make(chan []byte, 1024)}
+	manager.clients[userID.String()] = map[string]*Client{client.ID: client}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*cap(manager.broadcast); i++ {
+			manager.handleEnvelope(Envelope{NodeID: "node-b", UserID: userID.String(), Message: Message{Type: MessageTypeChatTyping}})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the subscriber waited on the Run loop")
+	}
+	assert.Len(t, drain(client), 2*cap(manager.broadcast))
+}
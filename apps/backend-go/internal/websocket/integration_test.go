@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"backend-go/internal/config"
 	"backend-go/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -27,10 +28,10 @@ func TestIntegration_WebSocketFullFlow(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// Setup
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
-	jwtUtil := utils.NewJWTUtil(&utils.JWTConfig{
+	jwtUtil := utils.NewJWTUtil(&config.JWTConfig{
 		Secret:    "test-secret-key-for-integration-tests",
 		ExpiresIn: time.Hour,
 	})
@@ -190,8 +191,8 @@ func TestIntegration_WebSocketFullFlow(t *testing.T) {
 		// Give time for server to process
 		time.Sleep(100 * time.Millisecond)
 
-		// Manager should only have 1 client (latest connection)
-		assert.Equal(t, 1, manager.GetClientCount())
+		// Both connections stay open for the same user
+		assert.Equal(t, 2, manager.GetUserConnectionCount(userID))
 	})
 
 	t.Run("Connection without token", func(t *testing.T) {
@@ -212,10 +213,10 @@ func TestIntegration_WebSocketFullFlow(t *testing.T) {
 func TestIntegration_WebSocketBroadcast(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
-	jwtUtil := utils.NewJWTUtil(&utils.JWTConfig{
+	jwtUtil := utils.NewJWTUtil(&config.JWTConfig{
 		Secret:    "test-secret-key-for-integration-tests",
 		ExpiresIn: time.Hour,
 	})
@@ -279,10 +280,10 @@ func TestIntegration_WebSocketBroadcast(t *testing.T) {
 func TestIntegration_WebSocketReconnection(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
-	jwtUtil := utils.NewJWTUtil(&utils.JWTConfig{
+	jwtUtil := utils.NewJWTUtil(&config.JWTConfig{
 		Secret:    "test-secret-key-for-integration-tests",
 		ExpiresIn: time.Hour,
 	})
@@ -1,11 +1,16 @@
 package websocket
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"backend-go/internal/config"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -16,32 +21,176 @@ import (
 var Upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins in development
-		// In production, this should be restricted
+	CheckOrigin:     checkOrigin,
+}
+
+var (
+	originsMu      sync.RWMutex
+	allowedOrigins = []string{"*"}
+)
+
+// SetAllowedOrigins configures which browser origins may open WebSocket
+// connections. A "*" entry allows any origin.
+func SetAllowedOrigins(origins []string) {
+	originsMu.Lock()
+	defer originsMu.Unlock()
+	allowedOrigins = append([]string(nil), origins...)
+}
+
+// checkOrigin accepts requests without an Origin header (non-browser
+// clients, which still need a valid ticket) and allowlisted origins.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
 		return true
-	},
+	}
+
+	originsMu.RLock()
+	defer originsMu.RUnlock()
+
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
 }
 
+// Client errors
+var (
+	ErrClientClosed   = errors.New("client is closed")
+	ErrSendBufferFull = errors.New("client send buffer is full")
+)
+
+// CloseSendBufferOverflow is the close code sent to clients that fall too
+// far behind. They should reconnect and resume.
+const CloseSendBufferOverflow = websocket.CloseTryAgainLater
+
 // Client represents a WebSocket client connection
 type Client struct {
-	ID     string
-	UserID uuid.UUID
-	Conn   *websocket.Conn
-	Send   chan []byte
-	mu     sync.Mutex
+	ID          string
+	UserID      uuid.UUID
+	Conn        *websocket.Conn
+	Send        chan []byte
+	ConnectedAt time.Time
+	mu          sync.Mutex
+	closed      bool
+	overflowed  bool
+	seq         uint64
+	protocol    int
+	replay      *replayBuffer
+	ctx         context.Context
+	cancel      context.CancelFunc
+	rooms       map[string]bool // guarded by Manager.mu
 }
 
-// SendMessage sends a message to the client safely
-func (c *Client) SendMessage(message []byte) {
+// Context returns a context that is cancelled when the connection closes,
+// so work started on behalf of the client stops when it goes away
+func (c *Client) Context() context.Context {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.ctx == nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+		if c.closed {
+			c.cancel()
+		}
+	}
+	return c.ctx
+}
+
+// SendMessage queues raw data for the client without a sequence number.
+// Prefer Deliver for protocol messages so they can be resumed.
+func (c *Client) SendMessage(message []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || c.overflowed {
+		return ErrClientClosed
+	}
+	return c.enqueue(message)
+}
+
+// Deliver stamps a message with the connection's next sequence number,
+// records it for replay and queues it for writing
+func (c *Client) Deliver(msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || c.overflowed {
+		return ErrClientClosed
+	}
+
+	c.seq++
+	msg.Seq = c.seq
+	if c.replay != nil {
+		c.replay.add(c.ID, msg.Seq, msg)
+	}
+
+	data, err := EncodeMessage(msg, c.protocol)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return c.enqueue(data)
+}
+
+// setProtocol switches the version used for messages sent from now on
+func (c *Client) setProtocol(version int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.protocol = version
+}
+
+// enqueue must be called with c.mu held. A client whose buffer is full is
+// not skipped silently: it is disconnected with CloseSendBufferOverflow so
+// it reconnects and resumes from the replay buffer.
+func (c *Client) enqueue(data []byte) error {
 	select {
-	case c.Send <- message:
+	case c.Send <- data:
+		return nil
 	default:
-		// Channel is full or closed
-		logrus.Warnf("Send channel blocked for client %s", c.ID)
+		c.overflowed = true
+		logrus.Warnf("Send buffer full for client %s, disconnecting", c.ID)
+		if c.Conn != nil {
+			go closeWithOverflow(c.Conn)
+		}
+		return ErrSendBufferFull
+	}
+}
+
+// closeWithOverflow tells a slow client why it is being disconnected. Both
+// calls are safe to make concurrently with the write pump.
+func closeWithOverflow(conn *websocket.Conn) {
+	reason := websocket.FormatCloseMessage(CloseSendBufferOverflow, "send buffer overflow")
+	conn.WriteControl(websocket.CloseMessage, reason, time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// attachReplay sets the buffer that delivered messages are recorded in
+func (c *Client) attachReplay(buffer *replayBuffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replay = buffer
+}
+
+// close closes the Send channel and the underlying connection once
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.Send != nil {
+		close(c.Send)
+	}
+	if c.Conn != nil {
+		c.Conn.Close()
 	}
 }
 
@@ -49,14 +198,20 @@ func (c *Client) SendMessage(message []byte) {
 type MessageType string
 
 const (
-	MessageTypeChatMessage   MessageType = "chat:message"
-	MessageTypeChatStream    MessageType = "chat:stream"
-	MessageTypeChatTyping    MessageType = "chat:typing"
-	MessageTypeWebsiteJoin   MessageType = "website:join"
-	MessageTypeWebsiteLeave  MessageType = "website:leave"
-	MessageTypeError         MessageType = "error"
-	MessageTypeConnected     MessageType = "connected"
-	MessageTypeDisconnected  MessageType = "disconnected"
+	MessageTypeChatMessage           MessageType = "chat:message"
+	MessageTypeChatStream            MessageType = "chat:stream"
+	MessageTypeChatTyping            MessageType = "chat:typing"
+	MessageTypeChatCancel            MessageType = "chat:cancel"
+	MessageTypeChatRegenerate        MessageType = "chat:regenerate"
+	MessageTypeWebsiteJoin           MessageType = "website:join"
+	MessageTypeWebsiteLeave          MessageType = "website:leave"
+	MessageTypeWebsitePresence       MessageType = "website:presence"
+	MessageTypeWebsiteContentChanged MessageType = "website:content_changed"
+	MessageTypeError                 MessageType = "error"
+	MessageTypeConnected             MessageType = "connected"
+	MessageTypeDisconnected          MessageType = "disconnected"
+	MessageTypeResume                MessageType = "resume"
+	MessageTypeResumed               MessageType = "resumed"
 )
 
 // Message represents a WebSocket message
@@ -69,55 +224,323 @@ type Message struct {
 	Chunk     string         `json:"chunk,omitempty"`
 	IsTyping  bool           `json:"isTyping,omitempty"`
 	WebsiteID string         `json:"websiteId,omitempty"`
+	ThreadID  string         `json:"threadId,omitempty"`
 	Timestamp time.Time      `json:"timestamp,omitempty"`
 	Error     string         `json:"error,omitempty"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
+
+	// Seq numbers the messages sent on one connection, starting at 1
+	Seq uint64 `json:"seq,omitempty"`
+	// SessionID and LastSeq are sent in a resume message: the ID from the
+	// previous connection's connected message and the last Seq received on it
+	SessionID string `json:"sessionId,omitempty"`
+	LastSeq   uint64 `json:"lastSeq,omitempty"`
+
+	// Code classifies error messages
+	Code ErrorCode `json:"code,omitempty"`
+	// Payload is the validated, typed payload of a decoded client message
+	Payload Payload `json:"-"`
+}
+
+// outbound is a message queued for delivery to a user, a room or everyone
+type outbound struct {
+	userID  string
+	room    string
+	except  *Client
+	message Message
 }
 
+// Presence events reported in website:presence messages
+const (
+	PresenceJoined = "joined"
+	PresenceLeft   = "left"
+)
+
 // Manager manages WebSocket connections
 type Manager struct {
-	clients    map[string]*Client // userID -> Client
-	register   chan *Client
-	unregister chan *Client
-	broadcast  chan Message
-	mu         sync.RWMutex
+	clients     map[string]map[string]*Client // userID -> clientID -> Client
+	rooms       map[string]map[string]*Client // websiteID -> clientID -> Client
+	register    chan *Client
+	unregister  chan *Client
+	broadcast   chan outbound
+	maxPerUser  int
+	nodeID      string
+	backplane   Backplane
+	presenceTTL time.Duration
+	// backplaneCalls queues the backplane I/O of the Run loop and the room
+	// methods for runBackplane, so a slow backplane never stalls delivery
+	backplaneCalls chan func()
+
+	replays         map[string]*replayBuffer // userID -> recent deliveries
+	replaySize      int
+	replayRetention time.Duration
+
+	mu sync.RWMutex
 }
 
 // NewManager creates a new WebSocket manager
-func NewManager() *Manager {
+func NewManager(cfg *config.WebSocketConfig) *Manager {
+	nodeID := cfg.NodeID
+	if nodeID == "" {
+		nodeID = uuid.New().String()
+	}
+
+	presenceTTL := cfg.PresenceTTL
+	if presenceTTL <= 0 {
+		presenceTTL = time.Minute
+	}
+
+	replaySize := cfg.ReplayBufferSize
+	if replaySize <= 0 {
+		replaySize = 256
+	}
+
+	replayRetention := cfg.ReplayRetention
+	if replayRetention <= 0 {
+		replayRetention = 5 * time.Minute
+	}
+
 	return &Manager{
-		clients:    make(map[string]*Client),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan Message, 256),
+		clients:     make(map[string]map[string]*Client),
+		rooms:       make(map[string]map[string]*Client),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		broadcast:   make(chan outbound, 256),
+		maxPerUser:  cfg.MaxConnectionsPerUser,
+		nodeID:      nodeID,
+		presenceTTL: presenceTTL,
+
+		backplaneCalls: make(chan func(), backplaneQueueSize),
+
+		replays:         make(map[string]*replayBuffer),
+		replaySize:      replaySize,
+		replayRetention: replayRetention,
+	}
+}
+
+// NodeID returns the identifier of this manager on the backplane
+func (m *Manager) NodeID() string {
+	return m.nodeID
+}
+
+// UseBackplane connects the manager to other replicas. It must be called
+// before Run. Messages for users and rooms are then also published to the
+// backplane, and messages from other nodes are delivered to local clients.
+func (m *Manager) UseBackplane(ctx context.Context, backplane Backplane) error {
+	if err := backplane.Subscribe(ctx, m.handleEnvelope); err != nil {
+		return err
+	}
+	m.backplane = backplane
+
+	go m.runBackplane(ctx)
+	go m.refreshPresence(ctx)
+	return nil
+}
+
+// handleEnvelope delivers a message published by another node. It delivers
+// directly rather than through the broadcast channel: the Run loop may be
+// waiting on the backplane, which may be waiting on this subscriber.
+func (m *Manager) handleEnvelope(env Envelope) {
+	if env.NodeID == m.nodeID {
+		return
+	}
+	m.deliver(outbound{userID: env.UserID, room: env.Room, message: env.Message})
+}
+
+// deliver sends a message to the local clients it is addressed to
+func (m *Manager) deliver(out outbound) {
+	var clients []*Client
+	if out.room != "" {
+		clients = m.roomSnapshot(out.room)
+	} else {
+		clients = m.snapshot(out.userID)
+	}
+
+	for _, client := range clients {
+		if client != out.except {
+			client.Deliver(out.message)
+		}
+	}
+}
+
+const (
+	// backplaneTimeout bounds each backplane call
+	backplaneTimeout = 2 * time.Second
+	// backplaneQueueSize bounds the backplane calls waiting to be made
+	backplaneQueueSize = 1024
+)
+
+// runBackplane makes the queued backplane calls one at a time, in the order
+// they were queued, until ctx is done
+func (m *Manager) runBackplane(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case call := <-m.backplaneCalls:
+			call()
+		}
+	}
+}
+
+// onBackplane queues a backplane call without blocking. When the backplane
+// has fallen this far behind the call is dropped: presence is refreshed
+// periodically, and stalling every local client would be worse than losing
+// a cross-node message.
+func (m *Manager) onBackplane(call func()) {
+	select {
+	case m.backplaneCalls <- call:
+	default:
+		logrus.Warn("Backplane queue is full, dropping a call")
+	}
+}
+
+// publish forwards a message to the other nodes, if a backplane is in use
+func (m *Manager) publish(env Envelope) {
+	if m.backplane == nil {
+		return
+	}
+	m.onBackplane(func() { m.publishNow(env) })
+}
+
+// publishNow forwards a message to the other nodes and waits for the
+// backplane; it is only called from runBackplane
+func (m *Manager) publishNow(env Envelope) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	env.NodeID = m.nodeID
+	if err := m.backplane.Publish(ctx, env); err != nil {
+		logrus.WithError(err).Warn("Failed to publish to backplane")
+	}
+}
+
+func userPresenceKey(userID string) string { return "user:" + userID }
+func roomPresenceKey(room string) string   { return "room:" + room }
+
+// setPresence records presence on the backplane, if one is in use
+func (m *Manager) setPresence(key, member string) {
+	if m.backplane == nil {
+		return
+	}
+	m.onBackplane(func() { m.setPresenceNow(key, member) })
+}
+
+// setPresenceNow records presence and waits for the backplane; it is only
+// called from runBackplane
+func (m *Manager) setPresenceNow(key, member string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	if err := m.backplane.SetPresence(ctx, key, member, m.presenceTTL); err != nil {
+		logrus.WithError(err).Warn("Failed to set presence")
+	}
+}
+
+// removePresence clears presence on the backplane, if one is in use
+func (m *Manager) removePresence(key, member string) {
+	if m.backplane == nil {
+		return
+	}
+	m.onBackplane(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		defer cancel()
+
+		if err := m.backplane.RemovePresence(ctx, key, member); err != nil {
+			logrus.WithError(err).Warn("Failed to remove presence")
+		}
+	})
+}
+
+// refreshPresence keeps this node's presence entries alive until ctx is done
+func (m *Manager) refreshPresence(ctx context.Context) {
+	ticker := time.NewTicker(m.presenceTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Queued behind pending removals, so a refresh never revives
+			// presence that was just removed
+			m.onBackplane(m.refreshPresenceNow)
+		}
+	}
+}
+
+// refreshPresenceNow records the presence of every local user and room
+// member; it is only called from runBackplane
+func (m *Manager) refreshPresenceNow() {
+	m.mu.RLock()
+	users := make([]string, 0, len(m.clients))
+	for userID := range m.clients {
+		users = append(users, userID)
+	}
+	roomMembers := make(map[string][]string)
+	for room, clients := range m.rooms {
+		for _, client := range clients {
+			roomMembers[room] = append(roomMembers[room], client.UserID.String())
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, userID := range users {
+		m.setPresenceNow(userPresenceKey(userID), m.nodeID)
+	}
+	for room, members := range roomMembers {
+		for _, userID := range members {
+			m.setPresenceNow(roomPresenceKey(room), userID+"|"+m.nodeID)
+		}
 	}
 }
 
 // Run starts the WebSocket manager event loop
 func (m *Manager) Run() {
+	cleanup := time.NewTicker(m.replayRetention / 2)
+	defer cleanup.Stop()
+
 	for {
 		select {
 		case client := <-m.register:
+			if client.ConnectedAt.IsZero() {
+				client.ConnectedAt = time.Now()
+			}
+
 			m.mu.Lock()
-			// Close existing connection for this user (only one connection per user)
-			if existing, ok := m.clients[client.UserID.String()]; ok {
-				logrus.Infof("Closing existing connection for user %s", client.UserID)
-				// Safely close the Send channel
-				if existing.Send != nil {
-					select {
-					case <-existing.Send:
-						// Channel already closed
-					default:
-						close(existing.Send)
-					}
-				}
-				if existing.Conn != nil {
-					existing.Conn.Close()
-				}
+			userID := client.UserID.String()
+			userClients, ok := m.clients[userID]
+			if !ok {
+				userClients = make(map[string]*Client)
+				m.clients[userID] = userClients
+			}
+
+			// Enforce the per-user limit by closing the oldest connections
+			var evicted []*Client
+			for m.maxPerUser > 0 && len(userClients) >= m.maxPerUser {
+				oldest := oldestClient(userClients)
+				delete(userClients, oldest.ID)
+				evicted = append(evicted, oldest)
+			}
+			userClients[client.ID] = client
+			connections := len(userClients)
+
+			replay, ok := m.replays[userID]
+			if !ok {
+				replay = newReplayBuffer(m.replaySize)
+				m.replays[userID] = replay
 			}
-			m.clients[client.UserID.String()] = client
 			m.mu.Unlock()
-			logrus.Infof("Client connected: %s (user: %s)", client.ID, client.UserID)
+
+			client.attachReplay(replay)
+
+			for _, old := range evicted {
+				logrus.Infof("Closing connection %s for user %s: connection limit reached", old.ID, old.UserID)
+				m.leaveAllRooms(old)
+				old.close()
+			}
+			m.setPresence(userPresenceKey(userID), m.nodeID)
+			logrus.Infof("Client connected: %s (user: %s, connections: %d)", client.ID, client.UserID, connections)
 
 			// Send connected confirmation (only if connection exists)
 			if client.Conn != nil {
@@ -127,86 +550,385 @@ func (m *Manager) Run() {
 					UserID:    client.UserID.String(),
 					Timestamp: time.Now(),
 				}
-				if data, err := json.Marshal(msg); err == nil {
-					select {
-					case client.Send <- data:
-					default:
-					}
-				}
+				client.Deliver(msg)
 			}
 
 		case client := <-m.unregister:
 			m.mu.Lock()
-			if _, ok := m.clients[client.UserID.String()]; ok {
-				delete(m.clients, client.UserID.String())
-				// Safely close the Send channel
-				if client.Send != nil {
-					select {
-					case <-client.Send:
-						// Channel already closed
-					default:
-						close(client.Send)
-					}
-				}
-				if client.Conn != nil {
-					client.Conn.Close()
+			userID := client.UserID.String()
+			lastConnection := false
+			if existing, ok := m.clients[userID][client.ID]; ok && existing == client {
+				delete(m.clients[userID], client.ID)
+				if len(m.clients[userID]) == 0 {
+					delete(m.clients, userID)
+					lastConnection = true
 				}
 				logrus.Infof("Client disconnected: %s (user: %s)", client.ID, client.UserID)
 			}
 			m.mu.Unlock()
-
-		case message := <-m.broadcast:
-			m.mu.RLock()
-			clients := make(map[string]*Client)
-			for k, v := range m.clients {
-				clients[k] = v
+			if lastConnection {
+				m.removePresence(userPresenceKey(userID), m.nodeID)
 			}
-			m.mu.RUnlock()
+			m.leaveAllRooms(client)
+			client.close()
 
-			data, err := json.Marshal(message)
-			if err != nil {
-				logrus.WithError(err).Error("Failed to marshal broadcast message")
-				continue
-			}
+		case out := <-m.broadcast:
+			m.deliver(out)
 
-			// Send to specific user if specified
-			if message.UserID != "" {
-				if client, ok := clients[message.UserID]; ok {
-					client.SendMessage(data)
-				}
-			} else {
-				// Broadcast to all clients
-				for _, client := range clients {
-					client.SendMessage(data)
-				}
-			}
+		case <-cleanup.C:
+			m.pruneReplays()
 		}
 	}
 }
 
-// SendToUser sends a message to a specific user
+// pruneReplays drops the replay buffers of users who have had no
+// connection on this node for longer than the retention period
+func (m *Manager) pruneReplays() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := time.Now().Add(-m.replayRetention)
+	for userID, replay := range m.replays {
+		if len(m.clients[userID]) == 0 && replay.idleSince().Before(cutoff) {
+			delete(m.replays, userID)
+		}
+	}
+}
+
+// Resume replays the messages a previous connection of the same user missed
+// after lastSeq, then sends a resumed message. Replayed messages get new
+// sequence numbers on the current connection. complete is false when the
+// gap could not be filled (the session is unknown to this node or its
+// messages were evicted) and the client should reload its state instead.
+func (m *Manager) Resume(client *Client, sessionID string, lastSeq uint64) {
+	m.mu.RLock()
+	replay := m.replays[client.UserID.String()]
+	m.mu.RUnlock()
+
+	var missed []Message
+	complete := false
+	if replay != nil && sessionID != client.ID {
+		missed, complete = replay.since(sessionID, lastSeq)
+	}
+
+	for _, msg := range missed {
+		if err := client.Deliver(msg); err != nil {
+			return
+		}
+	}
+
+	client.Deliver(Message{
+		Type:      MessageTypeResumed,
+		SessionID: sessionID,
+		LastSeq:   lastSeq,
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"replayed": len(missed),
+			"complete": complete,
+		},
+	})
+}
+
+// snapshot copies the clients of one user, or of all users when userID is empty
+func (m *Manager) snapshot(userID string) []*Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var clients []*Client
+	if userID != "" {
+		for _, client := range m.clients[userID] {
+			clients = append(clients, client)
+		}
+		return clients
+	}
+
+	for _, userClients := range m.clients {
+		for _, client := range userClients {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// roomSnapshot copies the clients that joined a room
+func (m *Manager) roomSnapshot(room string) []*Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(m.rooms[room]))
+	for _, client := range m.rooms[room] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func oldestClient(clients map[string]*Client) *Client {
+	var oldest *Client
+	for _, client := range clients {
+		if oldest == nil || client.ConnectedAt.Before(oldest.ConnectedAt) {
+			oldest = client
+		}
+	}
+	return oldest
+}
+
+// SendToUser sends a message to every connection of a specific user,
+// on whichever node they are connected
 func (m *Manager) SendToUser(userID uuid.UUID, message Message) {
-	m.broadcast <- message
+	m.broadcast <- outbound{userID: userID.String(), message: message}
+	m.publish(Envelope{UserID: userID.String(), Message: message})
 }
 
-// Broadcast sends a message to all connected clients
+// Broadcast sends a message to all connected clients on all nodes
 func (m *Manager) Broadcast(message Message) {
-	m.broadcast <- message
+	m.broadcast <- outbound{message: message}
+	m.publish(Envelope{Message: message})
+}
+
+// JoinRoom adds a client to a website room and announces it to the room
+func (m *Manager) JoinRoom(client *Client, websiteID uuid.UUID) {
+	room := websiteID.String()
+
+	m.mu.Lock()
+	if m.rooms[room] == nil {
+		m.rooms[room] = make(map[string]*Client)
+	}
+	m.rooms[room][client.ID] = client
+	if client.rooms == nil {
+		client.rooms = make(map[string]bool)
+	}
+	client.rooms[room] = true
+	m.mu.Unlock()
+
+	m.setPresence(roomPresenceKey(room), client.UserID.String()+"|"+m.nodeID)
+	m.announcePresence(room, client, PresenceJoined)
+}
+
+// LeaveRoom removes a client from a website room and announces it to the room
+func (m *Manager) LeaveRoom(client *Client, websiteID uuid.UUID) {
+	room := websiteID.String()
+	if m.removeFromRoom(client, room) {
+		m.announcePresence(room, client, PresenceLeft)
+	}
+}
+
+// leaveAllRooms removes a client from every room it joined
+func (m *Manager) leaveAllRooms(client *Client) {
+	m.mu.RLock()
+	rooms := make([]string, 0, len(client.rooms))
+	for room := range client.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mu.RUnlock()
+
+	for _, room := range rooms {
+		if m.removeFromRoom(client, room) {
+			m.announcePresence(room, client, PresenceLeft)
+		}
+	}
+}
+
+func (m *Manager) removeFromRoom(client *Client, room string) bool {
+	m.mu.Lock()
+	if _, ok := m.rooms[room][client.ID]; !ok {
+		m.mu.Unlock()
+		return false
+	}
+	delete(m.rooms[room], client.ID)
+	if len(m.rooms[room]) == 0 {
+		delete(m.rooms, room)
+	}
+	delete(client.rooms, room)
+
+	// Keep the user's room presence while another local connection is in the room
+	userStillInRoom := false
+	for _, other := range m.rooms[room] {
+		if other.UserID == client.UserID {
+			userStillInRoom = true
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if !userStillInRoom {
+		m.removePresence(roomPresenceKey(room), client.UserID.String()+"|"+m.nodeID)
+	}
+	return true
+}
+
+// announcePresence tells everyone in a room who is there now. It delivers
+// directly rather than through the broadcast channel because it is also
+// called from the Run loop. With a backplane, listing the room's users
+// waits on it, so the announcement is made from runBackplane, after the
+// presence change queued before it.
+func (m *Manager) announcePresence(room string, client *Client, event string) {
+	userID, clientID, timestamp := client.UserID.String(), client.ID, time.Now()
+	announce := func() {
+		msg := Message{
+			Type:      MessageTypeWebsitePresence,
+			UserID:    userID,
+			WebsiteID: room,
+			Timestamp: timestamp,
+			Metadata: map[string]any{
+				"event":    event,
+				"clientId": clientID,
+				"users":    m.roomUsers(room),
+			},
+		}
+
+		for _, member := range m.roomSnapshot(room) {
+			member.Deliver(msg)
+		}
+		if m.backplane != nil {
+			m.publishNow(Envelope{Room: room, Message: msg})
+		}
+	}
+
+	if m.backplane == nil {
+		announce()
+		return
+	}
+	m.onBackplane(announce)
 }
 
-// GetClientCount returns the number of connected clients
+// roomUsers returns the distinct users in a room across all nodes
+func (m *Manager) roomUsers(room string) []string {
+	seen := make(map[string]bool)
+	var users []string
+	add := func(userID string) {
+		if !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+
+	m.mu.RLock()
+	for _, client := range m.rooms[room] {
+		add(client.UserID.String())
+	}
+	m.mu.RUnlock()
+
+	if m.backplane != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		defer cancel()
+
+		members, err := m.backplane.Presence(ctx, roomPresenceKey(room))
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to get room presence")
+		}
+		for _, member := range members {
+			if userID, _, ok := strings.Cut(member, "|"); ok {
+				add(userID)
+			}
+		}
+	}
+
+	if users == nil {
+		users = []string{}
+	}
+	return users
+}
+
+// RoomUsers returns the distinct users currently in a website room
+func (m *Manager) RoomUsers(websiteID uuid.UUID) []string {
+	return m.roomUsers(websiteID.String())
+}
+
+// ClientRooms returns the website rooms a client has joined
+func (m *Manager) ClientRooms(client *Client) []uuid.UUID {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rooms := make([]uuid.UUID, 0, len(client.rooms))
+	for room := range client.rooms {
+		if id, err := uuid.Parse(room); err == nil {
+			rooms = append(rooms, id)
+		}
+	}
+	return rooms
+}
+
+// IsInRoom checks if a client has joined a website room
+func (m *Manager) IsInRoom(client *Client, websiteID uuid.UUID) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return client.rooms[websiteID.String()]
+}
+
+// BroadcastToRoom sends a message to every client in a website room
+func (m *Manager) BroadcastToRoom(websiteID uuid.UUID, message Message) {
+	m.broadcast <- outbound{room: websiteID.String(), message: message}
+	m.publish(Envelope{Room: websiteID.String(), Message: message})
+}
+
+// BroadcastToRoomExcept sends a message to every client in a website room
+// except the given one, typically the sender
+func (m *Manager) BroadcastToRoomExcept(websiteID uuid.UUID, except *Client, message Message) {
+	m.broadcast <- outbound{room: websiteID.String(), except: except, message: message}
+	m.publish(Envelope{Room: websiteID.String(), Message: message})
+}
+
+// NotifyContentChanged tells everyone watching a website that some of its
+// fields were modified, so they can refresh the preview. version is the
+// website's content version after the change.
+func (m *Manager) NotifyContentChanged(websiteID, actorID uuid.UUID, fields []string, version int) {
+	m.BroadcastToRoom(websiteID, Message{
+		Type:      MessageTypeWebsiteContentChanged,
+		UserID:    actorID.String(),
+		WebsiteID: websiteID.String(),
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"fields":  fields,
+			"version": version,
+		},
+	})
+}
+
+// GetClientCount returns the number of open connections across all users
 func (m *Manager) GetClientCount() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+
+	count := 0
+	for _, userClients := range m.clients {
+		count += len(userClients)
+	}
+	return count
+}
+
+// GetUserCount returns the number of distinct connected users
+func (m *Manager) GetUserCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.clients)
 }
 
-// IsUserConnected checks if a user is currently connected
-func (m *Manager) IsUserConnected(userID uuid.UUID) bool {
+// GetUserConnectionCount returns the number of open connections for a user
+func (m *Manager) GetUserConnectionCount(userID uuid.UUID) int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	_, ok := m.clients[userID.String()]
-	return ok
+	return len(m.clients[userID.String()])
+}
+
+// IsUserConnected checks if a user has at least one open connection on
+// this node or, with a backplane, on any node
+func (m *Manager) IsUserConnected(userID uuid.UUID) bool {
+	if m.GetUserConnectionCount(userID) > 0 {
+		return true
+	}
+	if m.backplane == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	nodes, err := m.backplane.Presence(ctx, userPresenceKey(userID.String()))
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get user presence")
+		return false
+	}
+	return len(nodes) > 0
 }
 
 // ReadPump pumps messages from the WebSocket connection to the manager
@@ -231,18 +953,24 @@ func (m *Manager) ReadPump(client *Client, handler MessageHandler) {
 			break
 		}
 
-		var msg Message
-		if err := json.Unmarshal(data, &msg); err != nil {
-			logrus.WithError(err).Warn("Failed to unmarshal WebSocket message")
-			// Send error back to client
-			errorMsg := Message{
-				Type:      MessageTypeError,
-				Error:     "Invalid message format",
-				Timestamp: time.Now(),
-			}
-			if data, err := json.Marshal(errorMsg); err == nil {
-				client.Send <- data
-			}
+		msg, perr := DecodeMessage(data)
+		if perr != nil {
+			logrus.WithField("code", perr.Code).Warn("Rejected WebSocket message: " + perr.Message)
+			client.Deliver(ErrorMessage(msg.ID, perr.Code, perr.Message))
+			continue
+		}
+
+		// Handshake, resume and keepalive are part of the transport, not of
+		// the application protocol
+		switch msg.Type {
+		case MessageTypeHello:
+			m.handshake(client, msg)
+			continue
+		case MessageTypeResume:
+			resume := msg.Payload.(*ResumePayload)
+			m.Resume(client, resume.SessionID, resume.LastSeq)
+			continue
+		case MessageTypePing:
 			continue
 		}
 
@@ -253,6 +981,29 @@ func (m *Manager) ReadPump(client *Client, handler MessageHandler) {
 	}
 }
 
+// handshake selects the protocol version for the rest of the connection and
+// answers with a welcome message in that version
+func (m *Manager) handshake(client *Client, msg Message) {
+	hello := msg.Payload.(*HelloPayload)
+	version, ok := negotiateVersion(hello.Versions)
+	if !ok {
+		client.Deliver(ErrorMessage(msg.ID, ErrorCodeUnsupportedVersion, fmt.Sprintf("No common protocol version, server supports %v", SupportedVersions)))
+		return
+	}
+
+	client.setProtocol(version)
+	client.Deliver(Message{
+		Type:      MessageTypeWelcome,
+		ID:        msg.ID,
+		SessionID: client.ID,
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"version":  version,
+			"versions": SupportedVersions,
+		},
+	})
+}
+
 // WritePump pumps messages from the manager to the WebSocket connection
 func (m *Manager) WritePump(client *Client) {
 	ticker := time.NewTicker(54 * time.Second)
@@ -307,10 +1058,11 @@ func (m *Manager) HandleWebSocket(c *gin.Context, userID uuid.UUID, handler Mess
 	}
 
 	client := &Client{
-		ID:     uuid.New().String(),
-		UserID: userID,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Conn:        conn,
+		Send:        make(chan []byte, 256),
+		ConnectedAt: time.Now(),
 	}
 
 	m.register <- client
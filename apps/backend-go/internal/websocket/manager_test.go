@@ -6,13 +6,15 @@ import (
 	"testing"
 	"time"
 
+	"backend-go/internal/config"
+
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestNewManager(t *testing.T) {
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	assert.NotNil(t, manager)
 	assert.NotNil(t, manager.clients)
 	assert.NotNil(t, manager.register)
@@ -21,7 +23,7 @@ func TestNewManager(t *testing.T) {
 }
 
 func TestManager_Run(t *testing.T) {
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
 	// Test client registration
@@ -46,7 +48,7 @@ func TestManager_Run(t *testing.T) {
 }
 
 func TestManager_SendToUser(t *testing.T) {
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
 	userID := uuid.New()
@@ -82,7 +84,7 @@ func TestManager_SendToUser(t *testing.T) {
 }
 
 func TestManager_Broadcast(t *testing.T) {
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
 	// Create multiple clients
@@ -172,11 +174,28 @@ func TestUpgrader(t *testing.T) {
 	assert.Equal(t, 1024, Upgrader.WriteBufferSize)
 	assert.NotNil(t, Upgrader.CheckOrigin)
 
-	// Test CheckOrigin allows all origins in development
+	// Test CheckOrigin allows requests without an Origin header
 	req := httptest.NewRequest("GET", "/ws", nil)
 	assert.True(t, Upgrader.CheckOrigin(req))
 }
 
+func TestCheckOrigin(t *testing.T) {
+	defer SetAllowedOrigins([]string{"*"})
+	SetAllowedOrigins([]string{"https://app.sitespark.id"})
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	assert.True(t, Upgrader.CheckOrigin(req), "requests without Origin are allowed")
+
+	req.Header.Set("Origin", "https://app.sitespark.id")
+	assert.True(t, Upgrader.CheckOrigin(req))
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	assert.False(t, Upgrader.CheckOrigin(req))
+
+	SetAllowedOrigins([]string{"*"})
+	assert.True(t, Upgrader.CheckOrigin(req))
+}
+
 func TestMessage_MarshalUnmarshal(t *testing.T) {
 	msg := Message{
 		Type:      MessageTypeChatMessage,
@@ -211,7 +230,7 @@ func TestMessage_MarshalUnmarshal(t *testing.T) {
 }
 
 func TestManager_ConcurrentAccess(t *testing.T) {
-	manager := NewManager()
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
 	// Concurrent registrations
@@ -244,38 +263,215 @@ func TestManager_ConcurrentAccess(t *testing.T) {
 	assert.Equal(t, 0, manager.GetClientCount())
 }
 
-func TestManager_SingleConnectionPerUser(t *testing.T) {
-	manager := NewManager()
+func TestManager_MultipleConnectionsPerUser(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{})
 	go manager.Run()
 
 	userID := uuid.New()
 
-	// Register first client
+	// Register two connections for the same user (e.g. two tabs)
 	client1 := &Client{
 		ID:     "client-1",
 		UserID: userID,
 		Send:   make(chan []byte, 256),
 	}
-	manager.register <- client1
-	time.Sleep(50 * time.Millisecond)
-
-	// Register second client with same user ID
 	client2 := &Client{
 		ID:     "client-2",
 		UserID: userID,
 		Send:   make(chan []byte, 256),
 	}
+	manager.register <- client1
 	manager.register <- client2
 	time.Sleep(50 * time.Millisecond)
 
-	// Should still only have 1 client (latest one)
-	assert.Equal(t, 1, manager.GetClientCount())
+	assert.Equal(t, 2, manager.GetClientCount())
+	assert.Equal(t, 1, manager.GetUserCount())
+	assert.Equal(t, 2, manager.GetUserConnectionCount(userID))
+
+	// SendToUser fans out to every connection
+	manager.SendToUser(userID, Message{Type: MessageTypeChatMessage, Content: "hello"})
+	for _, client := range []*Client{client1, client2} {
+		select {
+		case data := <-client.Send:
+			var received Message
+			require.NoError(t, json.Unmarshal(data, &received))
+			assert.Equal(t, "hello", received.Content)
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for message on %s", client.ID)
+		}
+	}
+
+	// Closing one connection keeps the user connected
+	manager.unregister <- client1
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, manager.IsUserConnected(userID))
+	assert.Equal(t, 1, manager.GetUserConnectionCount(userID))
+
+	manager.unregister <- client2
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, manager.IsUserConnected(userID))
+	assert.Equal(t, 0, manager.GetUserCount())
+}
 
-	// Verify client1's channel was closed
+func TestManager_ConnectionLimitPerUser(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{MaxConnectionsPerUser: 2})
+	go manager.Run()
+
+	userID := uuid.New()
+	clients := make([]*Client, 3)
+	for i := range clients {
+		clients[i] = &Client{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			Send:        make(chan []byte, 256),
+			ConnectedAt: time.Now().Add(time.Duration(i) * time.Second),
+		}
+		manager.register <- clients[i]
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, 2, manager.GetUserConnectionCount(userID))
+
+	// The oldest connection is closed to make room
 	select {
-	case _, ok := <-client1.Send:
-		assert.False(t, ok, "First client's channel should be closed")
+	case _, ok := <-clients[0].Send:
+		assert.False(t, ok, "Oldest client's channel should be closed")
 	case <-time.After(100 * time.Millisecond):
-		// Channel might already be drained
+		t.Fatal("Oldest client's channel was not closed")
+	}
+
+	// Unregistering an evicted client doesn't affect the others
+	manager.unregister <- clients[0]
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, manager.GetUserConnectionCount(userID))
+}
+
+func TestClient_SendMessage_AfterClose(t *testing.T) {
+	client := &Client{
+		ID:     uuid.New().String(),
+		UserID: uuid.New(),
+		Send:   make(chan []byte, 1),
 	}
+
+	client.close()
+	client.close()
+
+	// Sending to a closed client must not panic
+	assert.NotPanics(t, func() {
+		client.SendMessage([]byte("late message"))
+	})
+}
+
+// drain reads all queued messages from a client's Send channel
+func drain(client *Client) []Message {
+	var messages []Message
+	for {
+		select {
+		case data := <-client.Send:
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err == nil {
+				messages = append(messages, msg)
+			}
+		default:
+			return messages
+		}
+	}
+}
+
+func TestManager_Rooms(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{})
+	go manager.Run()
+
+	websiteID := uuid.New()
+	owner := &Client{ID: "owner", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	watcher := &Client{ID: "watcher", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	outsider := &Client{ID: "outsider", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	for _, client := range []*Client{owner, watcher, outsider} {
+		manager.register <- client
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	manager.JoinRoom(owner, websiteID)
+	manager.JoinRoom(watcher, websiteID)
+	assert.True(t, manager.IsInRoom(owner, websiteID))
+	assert.False(t, manager.IsInRoom(outsider, websiteID))
+	assert.ElementsMatch(t, []string{owner.UserID.String(), watcher.UserID.String()}, manager.RoomUsers(websiteID))
+
+	// Both members saw the watcher join
+	ownerMsgs := drain(owner)
+	require.NotEmpty(t, ownerMsgs)
+	last := ownerMsgs[len(ownerMsgs)-1]
+	assert.Equal(t, MessageTypeWebsitePresence, last.Type)
+	assert.Equal(t, PresenceJoined, last.Metadata["event"])
+	assert.Len(t, last.Metadata["users"], 2)
+	drain(watcher)
+
+	// Room broadcasts only reach members, optionally skipping the sender
+	manager.BroadcastToRoomExcept(websiteID, owner, Message{Type: MessageTypeChatTyping, IsTyping: true})
+	manager.BroadcastToRoom(websiteID, Message{Type: MessageTypeWebsiteContentChanged})
+	time.Sleep(50 * time.Millisecond)
+
+	ownerMsgs = drain(owner)
+	require.Len(t, ownerMsgs, 1)
+	assert.Equal(t, MessageTypeWebsiteContentChanged, ownerMsgs[0].Type)
+
+	watcherMsgs := drain(watcher)
+	require.Len(t, watcherMsgs, 2)
+	assert.Equal(t, MessageTypeChatTyping, watcherMsgs[0].Type)
+	assert.Equal(t, MessageTypeWebsiteContentChanged, watcherMsgs[1].Type)
+
+	assert.Empty(t, drain(outsider))
+
+	// Leaving announces presence to the remaining members
+	manager.LeaveRoom(watcher, websiteID)
+	assert.False(t, manager.IsInRoom(watcher, websiteID))
+	ownerMsgs = drain(owner)
+	require.Len(t, ownerMsgs, 1)
+	assert.Equal(t, PresenceLeft, ownerMsgs[0].Metadata["event"])
+}
+
+func TestManager_RoomAutoLeaveOnDisconnect(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{})
+	go manager.Run()
+
+	websiteID := uuid.New()
+	stays := &Client{ID: "stays", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	leaves := &Client{ID: "leaves", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	manager.register <- stays
+	manager.register <- leaves
+	time.Sleep(50 * time.Millisecond)
+
+	manager.JoinRoom(stays, websiteID)
+	manager.JoinRoom(leaves, websiteID)
+	drain(stays)
+
+	manager.unregister <- leaves
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, []string{stays.UserID.String()}, manager.RoomUsers(websiteID))
+	msgs := drain(stays)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, MessageTypeWebsitePresence, msgs[0].Type)
+	assert.Equal(t, PresenceLeft, msgs[0].Metadata["event"])
+	assert.Equal(t, leaves.UserID.String(), msgs[0].UserID)
+
+	// The last member leaving removes the room
+	manager.unregister <- stays
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, manager.RoomUsers(websiteID))
+}
+
+func TestClient_ContextCancelledOnClose(t *testing.T) {
+	client := &Client{ID: "client", UserID: uuid.New(), Send: make(chan []byte, 1)}
+
+	ctx := client.Context()
+	assert.NoError(t, ctx.Err())
+
+	client.close()
+	assert.Error(t, ctx.Err())
+
+	// Work started after the connection closed is cancelled immediately
+	late := &Client{ID: "late", UserID: uuid.New(), Send: make(chan []byte, 1)}
+	late.close()
+	assert.Error(t, late.Context().Err())
 }
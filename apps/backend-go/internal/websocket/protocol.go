@@ -0,0 +1,360 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Protocol versions. Version 1 is the original flat Message; version 2 wraps
+// a typed payload in a Frame. A connection speaks version 1 until a hello
+// handshake selects another one, so older clients keep working unchanged.
+const (
+	ProtocolV1 = 1
+	ProtocolV2 = 2
+)
+
+// SupportedVersions lists the protocol versions this server speaks
+var SupportedVersions = []int{ProtocolV1, ProtocolV2}
+
+// Handshake and keepalive message types
+const (
+	MessageTypeHello   MessageType = "hello"
+	MessageTypeWelcome MessageType = "welcome"
+	MessageTypePing    MessageType = "ping"
+)
+
+// ErrorCode classifies an error reply so clients can react without parsing
+// the human-readable message
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidJSON        ErrorCode = "invalid_json"
+	ErrorCodeUnsupportedVersion ErrorCode = "unsupported_version"
+	ErrorCodeUnknownType        ErrorCode = "unknown_type"
+	ErrorCodeInvalidPayload     ErrorCode = "invalid_payload"
+	ErrorCodeRateLimited        ErrorCode = "rate_limited"
+	ErrorCodeInsufficientTokens ErrorCode = "insufficient_tokens"
+	ErrorCodeNotFound           ErrorCode = "not_found"
+	ErrorCodeConflict           ErrorCode = "conflict"
+	ErrorCodeInternal           ErrorCode = "internal"
+)
+
+// ProtocolError is a message that could not be accepted
+type ProtocolError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// ErrorMessage builds an error reply correlated with the request's ID
+func ErrorMessage(requestID string, code ErrorCode, message string) Message {
+	return Message{
+		Type:      MessageTypeError,
+		ID:        requestID,
+		Code:      code,
+		Error:     message,
+		Timestamp: time.Now(),
+	}
+}
+
+// Frame is the version 2 wire format: an envelope around a typed payload
+type Frame struct {
+	V         int             `json:"v"`
+	Type      MessageType     `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Seq       uint64          `json:"seq,omitempty"`
+	Timestamp time.Time       `json:"timestamp,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// Payload is the body of a client message. Validate runs on decode, so
+// handlers only ever see well-formed payloads.
+type Payload interface {
+	Validate() error
+}
+
+// maxChatContentLength bounds a single chat message, in characters
+const maxChatContentLength = 4000
+
+// ChatMessagePayload is a chat message from the user, or the assistant's
+// reply from the server
+type ChatMessagePayload struct {
+	Content   string         `json:"content"`
+	Role      string         `json:"role,omitempty"`
+	WebsiteID string         `json:"websiteId,omitempty"`
+	ThreadID  string         `json:"threadId,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+func (p *ChatMessagePayload) Validate() error {
+	if p.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	if utf8.RuneCountInString(p.Content) > maxChatContentLength {
+		return fmt.Errorf("content must be at most %d characters", maxChatContentLength)
+	}
+	if err := validateOptionalUUID("threadId", p.ThreadID); err != nil {
+		return err
+	}
+	return validateOptionalUUID("websiteId", p.WebsiteID)
+}
+
+// ChatCancelPayload stops a response. ResponseID is the ID of its
+// chat:stream messages; empty cancels whatever is being generated.
+type ChatCancelPayload struct {
+	ResponseID string `json:"responseId,omitempty"`
+}
+
+func (p *ChatCancelPayload) Validate() error {
+	return nil
+}
+
+// ChatRegeneratePayload asks for a new answer to the last prompt of a thread
+type ChatRegeneratePayload struct {
+	WebsiteID string `json:"websiteId,omitempty"`
+	ThreadID  string `json:"threadId,omitempty"`
+}
+
+func (p *ChatRegeneratePayload) Validate() error {
+	if err := validateOptionalUUID("threadId", p.ThreadID); err != nil {
+		return err
+	}
+	return validateOptionalUUID("websiteId", p.WebsiteID)
+}
+
+// ChatTypingPayload is a typing indicator
+type ChatTypingPayload struct {
+	UserID    string `json:"userId,omitempty"`
+	WebsiteID string `json:"websiteId,omitempty"`
+	IsTyping  bool   `json:"isTyping"`
+}
+
+func (p *ChatTypingPayload) Validate() error {
+	return validateOptionalUUID("websiteId", p.WebsiteID)
+}
+
+// ChatStreamPayload is one chunk of a streamed response
+type ChatStreamPayload struct {
+	Chunk string `json:"chunk"`
+}
+
+// WebsitePayload names the website room to join or leave
+type WebsitePayload struct {
+	WebsiteID string `json:"websiteId"`
+}
+
+func (p *WebsitePayload) Validate() error {
+	if p.WebsiteID == "" {
+		return fmt.Errorf("websiteId is required")
+	}
+	return validateOptionalUUID("websiteId", p.WebsiteID)
+}
+
+// ResumePayload asks for the messages missed since a previous connection
+type ResumePayload struct {
+	SessionID string `json:"sessionId"`
+	LastSeq   uint64 `json:"lastSeq"`
+}
+
+func (p *ResumePayload) Validate() error {
+	if p.SessionID == "" {
+		return fmt.Errorf("sessionId is required")
+	}
+	return nil
+}
+
+// HelloPayload lists the protocol versions a client speaks
+type HelloPayload struct {
+	Versions []int `json:"versions"`
+}
+
+func (p *HelloPayload) Validate() error {
+	if len(p.Versions) == 0 {
+		return fmt.Errorf("versions is required")
+	}
+	return nil
+}
+
+// EmptyPayload is the payload of messages that carry no data
+type EmptyPayload struct{}
+
+func (p *EmptyPayload) Validate() error {
+	return nil
+}
+
+// WelcomePayload answers a hello with the selected version
+type WelcomePayload struct {
+	Version   int    `json:"version"`
+	Versions  []int  `json:"versions"`
+	SessionID string `json:"sessionId"`
+}
+
+// ResumedPayload reports the outcome of a resume
+type ResumedPayload struct {
+	SessionID string `json:"sessionId"`
+	LastSeq   uint64 `json:"lastSeq"`
+	Replayed  int    `json:"replayed"`
+	Complete  bool   `json:"complete"`
+}
+
+// ErrorPayload is an error reply
+type ErrorPayload struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// EventPayload carries server events such as connected, website:presence
+// and website:content_changed
+type EventPayload struct {
+	UserID    string         `json:"userId,omitempty"`
+	WebsiteID string         `json:"websiteId,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// clientPayloads maps each type a client may send to its payload
+var clientPayloads = map[MessageType]func() Payload{
+	MessageTypeChatMessage:    func() Payload { return &ChatMessagePayload{} },
+	MessageTypeChatCancel:     func() Payload { return &ChatCancelPayload{} },
+	MessageTypeChatRegenerate: func() Payload { return &ChatRegeneratePayload{} },
+	MessageTypeChatTyping:     func() Payload { return &ChatTypingPayload{} },
+	MessageTypeWebsiteJoin:    func() Payload { return &WebsitePayload{} },
+	MessageTypeWebsiteLeave:   func() Payload { return &WebsitePayload{} },
+	MessageTypeResume:         func() Payload { return &ResumePayload{} },
+	MessageTypeHello:          func() Payload { return &HelloPayload{} },
+	MessageTypePing:           func() Payload { return &EmptyPayload{} },
+}
+
+func validateOptionalUUID(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := uuid.Parse(value); err != nil {
+		return fmt.Errorf("%s must be a UUID", field)
+	}
+	return nil
+}
+
+// DecodeMessage parses a client message in either protocol version and
+// validates its payload. The returned Message has the flat fields filled in
+// as well as Payload. On error the Message still carries the request ID
+// when it could be read, so the reply can be correlated.
+func DecodeMessage(data []byte) (Message, *ProtocolError) {
+	var head struct {
+		V    int         `json:"v"`
+		Type MessageType `json:"type"`
+		ID   string      `json:"id"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return Message{}, &ProtocolError{Code: ErrorCodeInvalidJSON, Message: "Invalid message format"}
+	}
+
+	msg := Message{Type: head.Type, ID: head.ID}
+	if head.V != 0 && head.V != ProtocolV1 && head.V != ProtocolV2 {
+		return msg, &ProtocolError{Code: ErrorCodeUnsupportedVersion, Message: fmt.Sprintf("Unsupported protocol version %d", head.V)}
+	}
+
+	newPayload, ok := clientPayloads[head.Type]
+	if !ok {
+		return msg, &ProtocolError{Code: ErrorCodeUnknownType, Message: fmt.Sprintf("Unknown message type %q", head.Type)}
+	}
+	payload := newPayload()
+
+	// Version 1 keeps payload fields at the top level, version 2 nests them
+	body := data
+	if head.V == ProtocolV2 {
+		var frame Frame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			return msg, &ProtocolError{Code: ErrorCodeInvalidJSON, Message: "Invalid message format"}
+		}
+		body = frame.Payload
+		if len(body) == 0 {
+			body = []byte("{}")
+		}
+	}
+
+	if err := json.Unmarshal(body, payload); err != nil {
+		return msg, &ProtocolError{Code: ErrorCodeInvalidPayload, Message: "Invalid payload for " + string(head.Type)}
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return msg, &ProtocolError{Code: ErrorCodeInvalidPayload, Message: "Invalid payload for " + string(head.Type)}
+	}
+	msg.Type, msg.ID = head.Type, head.ID
+
+	// In version 1 a cancel names the response in its own id
+	if cancel, ok := payload.(*ChatCancelPayload); ok && head.V != ProtocolV2 && cancel.ResponseID == "" {
+		cancel.ResponseID = head.ID
+	}
+
+	if err := payload.Validate(); err != nil {
+		return msg, &ProtocolError{Code: ErrorCodeInvalidPayload, Message: err.Error()}
+	}
+
+	msg.Payload = payload
+	return msg, nil
+}
+
+// EncodeMessage renders a server message in the given protocol version
+func EncodeMessage(msg Message, version int) ([]byte, error) {
+	if version != ProtocolV2 {
+		return json.Marshal(msg)
+	}
+
+	payload, err := json.Marshal(serverPayload(msg))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Frame{
+		V:         ProtocolV2,
+		Type:      msg.Type,
+		ID:        msg.ID,
+		Seq:       msg.Seq,
+		Timestamp: msg.Timestamp,
+		Payload:   payload,
+	})
+}
+
+// serverPayload picks the version 2 payload of a server message
+func serverPayload(msg Message) any {
+	switch msg.Type {
+	case MessageTypeChatMessage:
+		return ChatMessagePayload{Content: msg.Content, Role: msg.Role, WebsiteID: msg.WebsiteID, ThreadID: msg.ThreadID, Metadata: msg.Metadata}
+	case MessageTypeChatStream:
+		return ChatStreamPayload{Chunk: msg.Chunk}
+	case MessageTypeChatTyping:
+		return ChatTypingPayload{UserID: msg.UserID, WebsiteID: msg.WebsiteID, IsTyping: msg.IsTyping}
+	case MessageTypeWebsiteJoin, MessageTypeWebsiteLeave:
+		return WebsitePayload{WebsiteID: msg.WebsiteID}
+	case MessageTypeError:
+		return ErrorPayload{Code: msg.Code, Message: msg.Error}
+	case MessageTypeWelcome:
+		version, _ := msg.Metadata["version"].(int)
+		versions, _ := msg.Metadata["versions"].([]int)
+		return WelcomePayload{Version: version, Versions: versions, SessionID: msg.SessionID}
+	case MessageTypeResumed:
+		replayed, _ := msg.Metadata["replayed"].(int)
+		complete, _ := msg.Metadata["complete"].(bool)
+		return ResumedPayload{SessionID: msg.SessionID, LastSeq: msg.LastSeq, Replayed: replayed, Complete: complete}
+	default:
+		return EventPayload{UserID: msg.UserID, WebsiteID: msg.WebsiteID, Metadata: msg.Metadata}
+	}
+}
+
+// negotiateVersion picks the highest version both sides speak
+func negotiateVersion(offered []int) (int, bool) {
+	best := 0
+	for _, v := range offered {
+		for _, supported := range SupportedVersions {
+			if v == supported && v > best {
+				best = v
+			}
+		}
+	}
+	return best, best != 0
+}
@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"backend-go/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMessage_V1(t *testing.T) {
+	websiteID := uuid.New().String()
+	msg, perr := DecodeMessage([]byte(`{"type":"chat:message","id":"req-1","content":"Hi","websiteId":"` + websiteID + `"}`))
+	require.Nil(t, perr)
+
+	assert.Equal(t, MessageTypeChatMessage, msg.Type)
+	assert.Equal(t, "req-1", msg.ID)
+	assert.Equal(t, "Hi", msg.Content)
+	assert.Equal(t, websiteID, msg.WebsiteID)
+
+	payload, ok := msg.Payload.(*ChatMessagePayload)
+	require.True(t, ok)
+	assert.Equal(t, "Hi", payload.Content)
+
+	// A v1 cancel names the response in its id
+	msg, perr = DecodeMessage([]byte(`{"type":"chat:cancel","id":"resp-1"}`))
+	require.Nil(t, perr)
+	assert.Equal(t, "resp-1", msg.Payload.(*ChatCancelPayload).ResponseID)
+}
+
+func TestDecodeMessage_V2(t *testing.T) {
+	msg, perr := DecodeMessage([]byte(`{"v":2,"type":"chat:cancel","id":"req-2","payload":{"responseId":"resp-1"}}`))
+	require.Nil(t, perr)
+	assert.Equal(t, "req-2", msg.ID)
+	assert.Equal(t, "resp-1", msg.Payload.(*ChatCancelPayload).ResponseID)
+
+	msg, perr = DecodeMessage([]byte(`{"v":2,"type":"website:join","id":"req-3","payload":{"websiteId":"` + uuid.New().String() + `"}}`))
+	require.Nil(t, perr)
+	assert.NotEmpty(t, msg.WebsiteID)
+
+	// Messages without data may omit the payload
+	_, perr = DecodeMessage([]byte(`{"v":2,"type":"ping"}`))
+	assert.Nil(t, perr)
+}
+
+func TestDecodeMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		code ErrorCode
+		id   string
+	}{
+		{"invalid json", `not json`, ErrorCodeInvalidJSON, ""},
+		{"unknown version", `{"v":9,"type":"chat:message","id":"a"}`, ErrorCodeUnsupportedVersion, "a"},
+		{"unknown type", `{"type":"chat:shout","id":"b"}`, ErrorCodeUnknownType, "b"},
+		{"missing content", `{"v":2,"type":"chat:message","id":"c","payload":{}}`, ErrorCodeInvalidPayload, "c"},
+		{"bad website id", `{"type":"website:join","id":"d","websiteId":"nope"}`, ErrorCodeInvalidPayload, "d"},
+		{"wrong field type", `{"v":2,"type":"chat:typing","id":"e","payload":{"isTyping":"yes"}}`, ErrorCodeInvalidPayload, "e"},
+		{"missing session", `{"type":"resume","id":"f"}`, ErrorCodeInvalidPayload, "f"},
+		{"bad thread id", `{"v":2,"type":"chat:regenerate","id":"g","payload":{"threadId":"nope"}}`, ErrorCodeInvalidPayload, "g"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, perr := DecodeMessage([]byte(tt.data))
+			require.NotNil(t, perr)
+			assert.Equal(t, tt.code, perr.Code)
+			assert.Equal(t, tt.id, msg.ID)
+		})
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	msg := Message{Type: MessageTypeChatStream, ID: "resp-1", Chunk: "Hel", Seq: 3}
+
+	v1, err := EncodeMessage(msg, ProtocolV1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"chat:stream","id":"resp-1","chunk":"Hel","seq":3,"timestamp":"0001-01-01T00:00:00Z"}`, string(v1))
+
+	v2, err := EncodeMessage(msg, ProtocolV2)
+	require.NoError(t, err)
+
+	var frame Frame
+	require.NoError(t, json.Unmarshal(v2, &frame))
+	assert.Equal(t, ProtocolV2, frame.V)
+	assert.Equal(t, MessageTypeChatStream, frame.Type)
+	assert.Equal(t, uint64(3), frame.Seq)
+	assert.JSONEq(t, `{"chunk":"Hel"}`, string(frame.Payload))
+
+	errMsg, err := EncodeMessage(ErrorMessage("req-1", ErrorCodeRateLimited, "Slow down"), ProtocolV2)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(errMsg, &frame))
+	assert.Equal(t, "req-1", frame.ID)
+	assert.JSONEq(t, `{"code":"rate_limited","message":"Slow down"}`, string(frame.Payload))
+}
+
+func TestManager_Handshake(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{})
+	go manager.Run()
+
+	client := &Client{ID: "client", UserID: uuid.New(), Send: make(chan []byte, 8)}
+	manager.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	hello, perr := DecodeMessage([]byte(`{"v":2,"type":"hello","id":"h1","payload":{"versions":[1,2,3]}}`))
+	require.Nil(t, perr)
+	manager.handshake(client, hello)
+
+	var frame Frame
+	require.NoError(t, json.Unmarshal(<-client.Send, &frame))
+	assert.Equal(t, MessageTypeWelcome, frame.Type)
+	assert.Equal(t, "h1", frame.ID)
+
+	var welcome WelcomePayload
+	require.NoError(t, json.Unmarshal(frame.Payload, &welcome))
+	assert.Equal(t, ProtocolV2, welcome.Version)
+	assert.Equal(t, client.ID, welcome.SessionID)
+
+	// Later messages use the negotiated version
+	manager.SendToUser(client.UserID, Message{Type: MessageTypeChatStream, Chunk: "x"})
+	select {
+	case data := <-client.Send:
+		require.NoError(t, json.Unmarshal(data, &frame))
+		assert.Equal(t, ProtocolV2, frame.V)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+
+	// No common version leaves the connection on its current version
+	hello, _ = DecodeMessage([]byte(`{"type":"hello","id":"h2","versions":[7]}`))
+	manager.handshake(client, hello)
+	require.NoError(t, json.Unmarshal(<-client.Send, &frame))
+	assert.Equal(t, MessageTypeError, frame.Type)
+	assert.Equal(t, "h2", frame.ID)
+}
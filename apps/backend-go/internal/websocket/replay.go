@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// replayEntry is a message as it was delivered on one connection
+type replayEntry struct {
+	clientID string
+	seq      uint64
+	message  Message
+}
+
+// replayBuffer keeps the most recent messages delivered to one user's
+// connections so that a reconnecting client can resume where it left off.
+// It is shared by all of the user's connections on this node and bounded
+// to size entries; the oldest entries are evicted first.
+type replayBuffer struct {
+	mu       sync.Mutex
+	size     int
+	entries  []replayEntry
+	evicted  map[string]uint64 // clientID -> highest evicted seq
+	lastUsed time.Time
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{
+		size:     size,
+		entries:  make([]replayEntry, 0, size),
+		evicted:  make(map[string]uint64),
+		lastUsed: time.Now(),
+	}
+}
+
+// add records a delivered message
+func (b *replayBuffer) add(clientID string, seq uint64, msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastUsed = time.Now()
+	if b.size <= 0 {
+		return
+	}
+
+	if len(b.entries) >= b.size {
+		oldest := b.entries[0]
+		b.evicted[oldest.clientID] = oldest.seq
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+		b.pruneEvicted()
+	}
+
+	b.entries = append(b.entries, replayEntry{clientID: clientID, seq: seq, message: msg})
+}
+
+// pruneEvicted forgets eviction marks of connections that have no entries
+// left once there are more marks than entries, so the map stays bounded
+func (b *replayBuffer) pruneEvicted() {
+	if len(b.evicted) <= b.size {
+		return
+	}
+
+	live := make(map[string]bool)
+	for _, entry := range b.entries {
+		live[entry.clientID] = true
+	}
+	for clientID := range b.evicted {
+		if !live[clientID] {
+			delete(b.evicted, clientID)
+		}
+	}
+}
+
+// since returns the messages delivered on clientID after seq, oldest first.
+// complete is false when the connection is unknown to this buffer or some
+// of the missed messages were already evicted.
+func (b *replayBuffer) since(clientID string, seq uint64) ([]Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastUsed = time.Now()
+
+	evictedSeq, known := b.evicted[clientID]
+	var messages []Message
+	for _, entry := range b.entries {
+		if entry.clientID != clientID {
+			continue
+		}
+		known = true
+		if entry.seq > seq {
+			messages = append(messages, entry.message)
+		}
+	}
+
+	return messages, known && evictedSeq <= seq
+}
+
+// idleSince reports when the buffer was last written or read
+func (b *replayBuffer) idleSince() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastUsed
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"backend-go/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBuffer_Since(t *testing.T) {
+	buffer := newReplayBuffer(3)
+	for seq := uint64(1); seq <= 2; seq++ {
+		buffer.add("a", seq, Message{Type: MessageTypeChatStream, Seq: seq})
+	}
+	buffer.add("b", 1, Message{Type: MessageTypeChatTyping, Seq: 1})
+
+	missed, complete := buffer.since("a", 1)
+	assert.True(t, complete)
+	require.Len(t, missed, 1)
+	assert.Equal(t, uint64(2), missed[0].Seq)
+
+	// Fully caught up
+	missed, complete = buffer.since("a", 2)
+	assert.True(t, complete)
+	assert.Empty(t, missed)
+
+	// Unknown sessions cannot be resumed
+	_, complete = buffer.since("unknown", 0)
+	assert.False(t, complete)
+}
+
+func TestReplayBuffer_Eviction(t *testing.T) {
+	buffer := newReplayBuffer(2)
+	for seq := uint64(1); seq <= 4; seq++ {
+		buffer.add("a", seq, Message{Seq: seq})
+	}
+
+	// Seq 1 and 2 were evicted, so resuming from 1 leaves a gap
+	missed, complete := buffer.since("a", 1)
+	assert.False(t, complete)
+	assert.Len(t, missed, 2)
+
+	missed, complete = buffer.since("a", 2)
+	assert.True(t, complete)
+	assert.Len(t, missed, 2)
+}
+
+func TestClient_Deliver_Sequence(t *testing.T) {
+	client := &Client{ID: "client", UserID: uuid.New(), Send: make(chan []byte, 8)}
+
+	require.NoError(t, client.Deliver(Message{Type: MessageTypeChatStream}))
+	require.NoError(t, client.Deliver(Message{Type: MessageTypeChatStream}))
+
+	msgs := drain(client)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, uint64(1), msgs[0].Seq)
+	assert.Equal(t, uint64(2), msgs[1].Seq)
+}
+
+func TestClient_Deliver_Overflow(t *testing.T) {
+	client := &Client{ID: "client", UserID: uuid.New(), Send: make(chan []byte, 1)}
+
+	require.NoError(t, client.Deliver(Message{Type: MessageTypeChatStream}))
+	assert.ErrorIs(t, client.Deliver(Message{Type: MessageTypeChatStream}), ErrSendBufferFull)
+
+	// Once overflowed the client gets nothing more until it reconnects
+	<-client.Send
+	assert.ErrorIs(t, client.Deliver(Message{Type: MessageTypeChatStream}), ErrClientClosed)
+}
+
+func TestManager_Resume(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{})
+	go manager.Run()
+
+	userID := uuid.New()
+	old := &Client{ID: "old", UserID: userID, Send: make(chan []byte, 256)}
+	manager.register <- old
+	time.Sleep(50 * time.Millisecond)
+
+	for _, chunk := range []string{"Hel", "lo", " world"} {
+		manager.SendToUser(userID, Message{Type: MessageTypeChatStream, Chunk: chunk})
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// The old connection only read the first chunk before dropping
+	received := drain(old)
+	require.Len(t, received, 3)
+	lastSeq := received[0].Seq
+	manager.unregister <- old
+
+	fresh := &Client{ID: "fresh", UserID: userID, Send: make(chan []byte, 256)}
+	manager.register <- fresh
+	time.Sleep(50 * time.Millisecond)
+
+	manager.Resume(fresh, old.ID, lastSeq)
+
+	msgs := drain(fresh)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "lo", msgs[0].Chunk)
+	assert.Equal(t, " world", msgs[1].Chunk)
+	assert.Equal(t, uint64(1), msgs[0].Seq)
+	assert.Equal(t, uint64(2), msgs[1].Seq)
+
+	assert.Equal(t, MessageTypeResumed, msgs[2].Type)
+	assert.Equal(t, old.ID, msgs[2].SessionID)
+	assert.EqualValues(t, 2, msgs[2].Metadata["replayed"])
+	assert.Equal(t, true, msgs[2].Metadata["complete"])
+}
+
+func TestManager_Resume_OtherUser(t *testing.T) {
+	manager := NewManager(&config.WebSocketConfig{})
+	go manager.Run()
+
+	victim := &Client{ID: "victim", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	attacker := &Client{ID: "attacker", UserID: uuid.New(), Send: make(chan []byte, 256)}
+	manager.register <- victim
+	manager.register <- attacker
+	time.Sleep(50 * time.Millisecond)
+
+	manager.SendToUser(victim.UserID, Message{Type: MessageTypeChatStream, Chunk: "secret"})
+	time.Sleep(50 * time.Millisecond)
+
+	// Sessions are only resumable by the user they belong to
+	manager.Resume(attacker, victim.ID, 0)
+
+	msgs := drain(attacker)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, MessageTypeResumed, msgs[0].Type)
+	assert.EqualValues(t, 0, msgs[0].Metadata["replayed"])
+	assert.Equal(t, false, msgs[0].Metadata["complete"])
+}
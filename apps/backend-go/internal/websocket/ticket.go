@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// TicketTTL is how long an issued ticket can be redeemed
+const TicketTTL = 30 * time.Second
+
+// Ticket errors
+var (
+	ErrTicketInvalid        = errors.New("ticket is invalid or expired")
+	ErrTicketOriginMismatch = errors.New("ticket was issued for a different origin")
+)
+
+// TicketStore issues and redeems single-use WebSocket tickets. A ticket is
+// bound to the user it was issued for and the Origin of the issuing request.
+type TicketStore interface {
+	Issue(ctx context.Context, userID uuid.UUID, origin string) (string, time.Time, error)
+	Redeem(ctx context.Context, ticket, origin string) (uuid.UUID, error)
+}
+
+type ticketData struct {
+	UserID    uuid.UUID `json:"userId"`
+	Origin    string    `json:"origin"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (t ticketData) verify(origin string) (uuid.UUID, error) {
+	if time.Now().After(t.ExpiresAt) {
+		return uuid.Nil, ErrTicketInvalid
+	}
+	if t.Origin != origin {
+		return uuid.Nil, ErrTicketOriginMismatch
+	}
+	return t.UserID, nil
+}
+
+func newTicketID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// MemoryTicketStore keeps tickets in process memory. Tickets only work on
+// the node that issued them, so it is meant for single-node setups and tests.
+type MemoryTicketStore struct {
+	mu      sync.Mutex
+	tickets map[string]ticketData
+}
+
+// NewMemoryTicketStore creates an in-memory ticket store
+func NewMemoryTicketStore() *MemoryTicketStore {
+	return &MemoryTicketStore{
+		tickets: make(map[string]ticketData),
+	}
+}
+
+// Issue creates a new ticket for a user and origin
+func (s *MemoryTicketStore) Issue(ctx context.Context, userID uuid.UUID, origin string) (string, time.Time, error) {
+	ticket, err := newTicketID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(TicketTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired tickets so abandoned ones don't accumulate
+	now := time.Now()
+	for id, t := range s.tickets {
+		if now.After(t.ExpiresAt) {
+			delete(s.tickets, id)
+		}
+	}
+
+	s.tickets[ticket] = ticketData{UserID: userID, Origin: origin, ExpiresAt: expiresAt}
+	return ticket, expiresAt, nil
+}
+
+// Redeem consumes a ticket and returns the user it was issued for
+func (s *MemoryTicketStore) Redeem(ctx context.Context, ticket, origin string) (uuid.UUID, error) {
+	s.mu.Lock()
+	data, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	s.mu.Unlock()
+
+	if !ok {
+		return uuid.Nil, ErrTicketInvalid
+	}
+	return data.verify(origin)
+}
+
+// RedisTicketStore keeps tickets in Redis so any replica can redeem them
+type RedisTicketStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisTicketStore creates a Redis-backed ticket store
+func NewRedisTicketStore(client *redis.Client) *RedisTicketStore {
+	return &RedisTicketStore{
+		client: client,
+		prefix: "ws:ticket:",
+	}
+}
+
+// Issue creates a new ticket for a user and origin
+func (s *RedisTicketStore) Issue(ctx context.Context, userID uuid.UUID, origin string) (string, time.Time, error) {
+	ticket, err := newTicketID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(TicketTTL)
+	data, err := json.Marshal(ticketData{UserID: userID, Origin: origin, ExpiresAt: expiresAt})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	if err := s.client.Set(ctx, s.prefix+ticket, data, TicketTTL).Err(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store ticket: %w", err)
+	}
+
+	return ticket, expiresAt, nil
+}
+
+// Redeem atomically consumes a ticket and returns the user it was issued for
+func (s *RedisTicketStore) Redeem(ctx context.Context, ticket, origin string) (uuid.UUID, error) {
+	raw, err := s.client.GetDel(ctx, s.prefix+ticket).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return uuid.Nil, ErrTicketInvalid
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to redeem ticket: %w", err)
+	}
+
+	var data ticketData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return uuid.Nil, ErrTicketInvalid
+	}
+	return data.verify(origin)
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTicketStore_IssueAndRedeem(t *testing.T) {
+	store := NewMemoryTicketStore()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	ticket, expiresAt, err := store.Issue(ctx, userID, "https://app.sitespark.id")
+	require.NoError(t, err)
+	assert.NotEmpty(t, ticket)
+	assert.WithinDuration(t, time.Now().Add(TicketTTL), expiresAt, time.Second)
+
+	redeemed, err := store.Redeem(ctx, ticket, "https://app.sitespark.id")
+	require.NoError(t, err)
+	assert.Equal(t, userID, redeemed)
+
+	// Tickets are single-use
+	_, err = store.Redeem(ctx, ticket, "https://app.sitespark.id")
+	assert.ErrorIs(t, err, ErrTicketInvalid)
+}
+
+func TestMemoryTicketStore_OriginMismatch(t *testing.T) {
+	store := NewMemoryTicketStore()
+	ctx := context.Background()
+
+	ticket, _, err := store.Issue(ctx, uuid.New(), "https://app.sitespark.id")
+	require.NoError(t, err)
+
+	_, err = store.Redeem(ctx, ticket, "https://evil.example.com")
+	assert.ErrorIs(t, err, ErrTicketOriginMismatch)
+
+	// A rejected ticket is still consumed
+	_, err = store.Redeem(ctx, ticket, "https://app.sitespark.id")
+	assert.ErrorIs(t, err, ErrTicketInvalid)
+}
+
+func TestMemoryTicketStore_Expired(t *testing.T) {
+	store := NewMemoryTicketStore()
+	userID := uuid.New()
+
+	store.tickets["expired"] = ticketData{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(-time.Second),
+	}
+
+	_, err := store.Redeem(context.Background(), "expired", "")
+	assert.ErrorIs(t, err, ErrTicketInvalid)
+}
+
+func TestMemoryTicketStore_UnknownTicket(t *testing.T) {
+	store := NewMemoryTicketStore()
+	_, err := store.Redeem(context.Background(), "does-not-exist", "")
+	assert.ErrorIs(t, err, ErrTicketInvalid)
+}
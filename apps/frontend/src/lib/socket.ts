@@ -1,13 +1,32 @@
 import type { ChatMessage } from '@/types'
+import { api } from '@/lib/api'
 
 type MessageCallback = (message: ChatMessage) => void
  type TypingCallback = (data: { userId: string; isTyping: boolean }) => void
  type ConnectCallback = () => void
  type DisconnectCallback = () => void
  type StreamCallback = (chunk: string, messageId: string) => void
+ type PresenceCallback = (data: { websiteId: string; userId: string; event: string; users: string[] }) => void
+ type ContentChangedCallback = (data: { websiteId: string; userId?: string; fields: string[] }) => void
+ type ResyncCallback = () => void
+
+ // Protocol versions this client speaks; the server picks the highest common one
+ const PROTOCOL_VERSIONS = [1, 2]
+
+ // Version 2 wire format: a typed payload inside an envelope
+ interface Frame {
+  v: number
+  type: string
+  id?: string
+  seq?: number
+  timestamp?: string
+  payload?: Record<string, unknown>
+}
 
  interface WebSocketMessage {
   type: string
+  code?: string
+  message?: string
   id?: string
   userId?: string
   content?: string
@@ -15,9 +34,16 @@ type MessageCallback = (message: ChatMessage) => void
   chunk?: string
   isTyping?: boolean
   websiteId?: string
+  threadId?: string
   timestamp?: string
   error?: string
   metadata?: Record<string, unknown>
+  seq?: number
+  sessionId?: string
+  lastSeq?: number
+  responseId?: string
+  version?: number
+  complete?: boolean
 }
 
  class SocketClient {
@@ -33,9 +59,19 @@ type MessageCallback = (message: ChatMessage) => void
   private connectCallbacks: ConnectCallback[] = []
   private disconnectCallbacks: DisconnectCallback[] = []
   private streamCallbacks: StreamCallback[] = []
+  private presenceCallbacks: PresenceCallback[] = []
+  private contentChangedCallbacks: ContentChangedCallback[] = []
+  private resyncCallbacks: ResyncCallback[] = []
 
   private pendingMessages: WebSocketMessage[] = []
 
+  private connecting = false
+
+  // Identify the current connection and the last message received on it,
+  // so that after a reconnect the server can replay what we missed
+  private sessionId: string | null = null
+  private lastSeq = 0
+
   connect(): this {
     const token = localStorage.getItem('token')
     if (!token) {
@@ -44,13 +80,34 @@ type MessageCallback = (message: ChatMessage) => void
     }
 
     // Prevent multiple connections
-    if (this.ws?.readyState === WebSocket.OPEN || this.ws?.readyState === WebSocket.CONNECTING) {
+    if (this.connecting || this.ws?.readyState === WebSocket.OPEN || this.ws?.readyState === WebSocket.CONNECTING) {
       console.log('[Socket] Already connected or connecting, skipping')
       return this
     }
 
-    const wsUrl = `${import.meta.env.VITE_SOCKET_URL || 'ws://localhost:3001'}/ws?token=${token}`
-    console.log('[Socket] Connecting to:', wsUrl)
+    this.connecting = true
+    this.openWithTicket().finally(() => {
+      this.connecting = false
+    })
+
+    return this
+  }
+
+  // The access token never goes in the URL: we exchange it for a
+  // single-use ticket that is only valid for a few seconds.
+  private async openWithTicket(): Promise<void> {
+    let ticket: string
+    try {
+      const response = await api.post<{ ticket: string; expiresIn: number }>('/ws/ticket')
+      ticket = response.data.data!.ticket
+    } catch (err) {
+      console.error('[Socket] Failed to obtain WebSocket ticket:', err)
+      this.attemptReconnect()
+      return
+    }
+
+    const wsUrl = `${import.meta.env.VITE_SOCKET_URL || 'ws://localhost:3001'}/ws?ticket=${encodeURIComponent(ticket)}`
+    console.log('[Socket] Connecting with ticket')
 
     try {
       this.ws = new WebSocket(wsUrl)
@@ -58,6 +115,7 @@ type MessageCallback = (message: ChatMessage) => void
       this.ws.onopen = () => {
         console.log('[Socket] WebSocket connected successfully')
         this.reconnectAttempts = 0
+        this.sendFrame({ type: 'hello', payload: { versions: PROTOCOL_VERSIONS } })
         this.startPingInterval()
         this.connectCallbacks.forEach(cb => cb())
 
@@ -71,8 +129,7 @@ type MessageCallback = (message: ChatMessage) => void
       this.ws.onmessage = (event) => {
         console.log('[Socket] Received message:', event.data)
         try {
-          const data: WebSocketMessage = JSON.parse(event.data)
-          this.handleMessage(data)
+          this.handleMessage(this.normalize(JSON.parse(event.data)))
         } catch (err) {
           console.error('[Socket] Failed to parse WebSocket message:', err)
         }
@@ -91,8 +148,6 @@ type MessageCallback = (message: ChatMessage) => void
     } catch (err) {
       console.error('[Socket] Failed to create WebSocket connection:', err)
     }
-
-    return this
   }
 
   disconnect(): void {
@@ -101,6 +156,8 @@ type MessageCallback = (message: ChatMessage) => void
       this.reconnectTimer = null
     }
     this.stopPingInterval()
+    this.sessionId = null
+    this.lastSeq = 0
 
     if (this.ws) {
       this.ws.close()
@@ -128,7 +185,7 @@ type MessageCallback = (message: ChatMessage) => void
     this.pingInterval = setInterval(() => {
       if (this.ws?.readyState === WebSocket.OPEN) {
         // Send ping to keep connection alive
-        this.ws.send(JSON.stringify({ type: 'ping' }))
+        this.sendFrame({ type: 'ping' })
       }
     }, 30000) // Ping every 30 seconds
   }
@@ -140,7 +197,31 @@ type MessageCallback = (message: ChatMessage) => void
     }
   }
 
+  // Flatten a version 2 frame so both versions are handled alike. Until the
+  // handshake completes the server still speaks version 1.
+  private normalize(raw: Frame | WebSocketMessage): WebSocketMessage {
+    if (!('v' in raw) || raw.v < 2) {
+      return raw as WebSocketMessage
+    }
+    const frame = raw as Frame
+    const data = {
+      ...frame.payload,
+      type: frame.type,
+      id: frame.id,
+      seq: frame.seq,
+      timestamp: frame.timestamp,
+    } as WebSocketMessage
+    if (data.type === 'error' && data.message) {
+      data.error = data.message
+    }
+    return data
+  }
+
   private handleMessage(data: WebSocketMessage): void {
+    if (data.type !== 'connected' && data.seq) {
+      this.lastSeq = data.seq
+    }
+
     switch (data.type) {
       case 'chat:message':
         if (data.id && data.role && data.content) {
@@ -167,12 +248,54 @@ type MessageCallback = (message: ChatMessage) => void
         }
         break
 
-      case 'connected':
+      case 'website:presence':
+        if (data.websiteId && data.userId) {
+          const presence = {
+            websiteId: data.websiteId,
+            userId: data.userId,
+            event: String(data.metadata?.event ?? ''),
+            users: (data.metadata?.users as string[] | undefined) ?? [],
+          }
+          this.presenceCallbacks.forEach(cb => cb(presence))
+        }
+        break
+
+      case 'website:content_changed':
+        if (data.websiteId) {
+          const change = {
+            websiteId: data.websiteId,
+            userId: data.userId,
+            fields: (data.metadata?.fields as string[] | undefined) ?? [],
+          }
+          this.contentChangedCallbacks.forEach(cb => cb(change))
+        }
+        break
+
+      case 'connected': {
         console.log('WebSocket connection confirmed by server')
+        const previousSession = this.sessionId
+        const previousSeq = this.lastSeq
+        this.sessionId = data.id ?? null
+        this.lastSeq = data.seq ?? 0
+        if (previousSession) {
+          this.sendFrame({ type: 'resume', payload: { sessionId: previousSession, lastSeq: previousSeq } })
+        }
+        break
+      }
+
+      case 'resumed':
+        if ((data.complete ?? data.metadata?.complete) === false) {
+          // Some messages could not be replayed, so local state may be stale
+          this.resyncCallbacks.forEach(cb => cb())
+        }
+        break
+
+      case 'welcome':
+        console.log('[Socket] Using protocol version', data.version)
         break
 
       case 'error':
-        console.error('WebSocket error from server:', data.error)
+        console.error('WebSocket error from server:', data.code, data.error, data.id ? `(request ${data.id})` : '')
         break
 
       default:
@@ -181,20 +304,48 @@ type MessageCallback = (message: ChatMessage) => void
     }
   }
 
+  // Send a version 2 frame on the open connection. The id lets the server
+  // correlate error replies with the request.
+  private sendFrame(frame: Omit<Frame, 'v' | 'id'>): void {
+    if (this.ws?.readyState !== WebSocket.OPEN) return
+    this.ws.send(JSON.stringify({ v: 2, id: crypto.randomUUID(), ...frame }))
+  }
+
   private send(data: WebSocketMessage): void {
     if (this.ws?.readyState === WebSocket.OPEN) {
-      this.ws.send(JSON.stringify(data))
+      const { type, ...payload } = data
+      this.sendFrame({ type, payload })
     } else {
       // Queue message for when connection is established
       this.pendingMessages.push(data)
     }
   }
 
-  sendMessage(content: string, websiteId?: string): void {
+  // Without a threadId the latest thread about the website continues; the
+  // acknowledgment names the thread that was used.
+  sendMessage(content: string, websiteId?: string, threadId?: string): void {
     this.send({
       type: 'chat:message',
       content,
       websiteId,
+      threadId,
+    })
+  }
+
+  // Stop the response being generated. The final chat:message then
+  // carries metadata.truncated with the partial content.
+  cancelResponse(messageId?: string): void {
+    this.send({
+      type: 'chat:cancel',
+      responseId: messageId,
+    })
+  }
+
+  regenerateResponse(websiteId?: string, threadId?: string): void {
+    this.send({
+      type: 'chat:regenerate',
+      websiteId,
+      threadId,
     })
   }
 
@@ -258,6 +409,27 @@ type MessageCallback = (message: ChatMessage) => void
     }
   }
 
+  onPresence(callback: PresenceCallback): () => void {
+    this.presenceCallbacks.push(callback)
+    return () => {
+      this.presenceCallbacks = this.presenceCallbacks.filter(cb => cb !== callback)
+    }
+  }
+
+  onContentChanged(callback: ContentChangedCallback): () => void {
+    this.contentChangedCallbacks.push(callback)
+    return () => {
+      this.contentChangedCallbacks = this.contentChangedCallbacks.filter(cb => cb !== callback)
+    }
+  }
+
+  onResync(callback: ResyncCallback): () => void {
+    this.resyncCallbacks.push(callback)
+    return () => {
+      this.resyncCallbacks = this.resyncCallbacks.filter(cb => cb !== callback)
+    }
+  }
+
   isConnected(): boolean {
     return this.ws?.readyState === WebSocket.OPEN
   }